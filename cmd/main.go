@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/mmartinjoo/explainer/internal/explainer"
+	"github.com/mmartinjoo/explainer/internal/platform"
 	"github.com/mmartinjoo/explainer/internal/tableanalyzer"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 )
 
 const (
@@ -22,6 +27,8 @@ var (
 	port     *int
 	user     *string
 	pass     *string
+	// queryTimeout is also sent to MySQL as max_execution_time so the server stops long running SELECTs too
+	queryTimeout *time.Duration
 )
 
 func main() {
@@ -30,9 +37,26 @@ func main() {
 	port = flag.Int("port", 3306, "Host port")
 	user = flag.String("user", "root", "Username")
 	pass = flag.String("pass", "root", "Password")
+	queryTimeout = flag.Duration("query-timeout", 0, "Maximum duration of a single database query such as 5s. 0 means no timeout")
 	help := flag.Bool("help", false, "Show help message")
 	ver := flag.Bool("version", false, "Show version")
 
+	// Subcommand flags come after the subcommand: 'myexplainer logs --follow ./queries.log'
+	logsFlags := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := logsFlags.Bool("follow", false, "Keep reading the log file as it grows and analyze new queries as they arrive")
+	format := logsFlags.String("format", explainer.FormatText, "Log format: 'text', 'jsonl' (one JSON object per line) or a framework preset: 'rails', 'django', 'hibernate', 'gorm' or 'ecto'")
+	parameterize := logsFlags.Bool("parameterize", false, "Extract the literals of queries logged without bindings into placeholders so they are deduplicated and their values are left out of the report")
+	includeWrites := logsFlags.Bool("include-writes", false, "Also explain UPDATE, DELETE and INSERT ... SELECT statements. They are never executed")
+	concurrency := logsFlags.Int("concurrency", 4, "Number of EXPLAIN queries that run at the same time. It's also the maximum number of open database connections")
+	limit := logsFlags.Int("limit", 0, "Analyze only the first N unique queries of the logs. 0 means no limit")
+	retries := logsFlags.Int("retries", 3, "Number of times an EXPLAIN query is run again after a deadlock, a lock wait timeout, a lost connection or 'Too many connections'")
+	retryBackoff := logsFlags.Duration("retry-backoff", 200*time.Millisecond, "Wait before the first retry. It doubles after every retry")
+	jsonSQL := logsFlags.String("json-sql", strings.Join(explainer.DefaultJSONPaths.SQL, ","), "Comma-separated JSON paths of the SQL query with --format jsonl")
+	jsonBindings := logsFlags.String("json-bindings", strings.Join(explainer.DefaultJSONPaths.Bindings, ","), "Comma-separated JSON paths of the bindings array with --format jsonl")
+	jsonTime := logsFlags.String("json-time", strings.Join(explainer.DefaultJSONPaths.Time, ","), "Comma-separated JSON paths of the execution time in milliseconds with --format jsonl")
+	jsonConnection := logsFlags.String("json-connection", strings.Join(explainer.DefaultJSONPaths.Connection, ","), "Comma-separated JSON paths of the connection or database name with --format jsonl")
+	jsonTimestamp := logsFlags.String("json-timestamp", strings.Join(explainer.DefaultJSONPaths.Timestamp, ","), "Comma-separated JSON paths of the time the query was logged with --format jsonl")
+
 	flag.Parse()
 
 	flag.Usage = func() {
@@ -41,13 +65,28 @@ func main() {
 		fmt.Fprintf(os.Stderr, "A CLI tool for analyzing queries and DB tables. It is meant to be used in local environment not in production.\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
 		fmt.Fprintf(os.Stderr, "'myexplainer table <tablename>' analyzes the table structure and gives you performance-related warnings, if any\n")
-		fmt.Fprintf(os.Stderr, "'myexplainer logs <path>' reads a log file in which every line contains a SQL query and analyzes them using EXPLAIN and gives you detailed information and tips\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer logs <path...>' reads log files (or stdin with '-') in which every line contains a SQL query and analyzes them using EXPLAIN and gives you detailed information and tips\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer logs --follow <path>' keeps reading the log file as it grows (like 'tail -F') and prints a query the first time it's seen or when its grade changes\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer ddl <path>' reads CREATE TABLE statements (for example from 'mysqldump --no-data') and analyzes the tables without a database\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer migrations <dir>' reads Laravel and SQL migrations and analyzes the resulting tables without a database\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer alter <path|statement>' tells how MySQL executes ALTER TABLE statements, whether they block writes and how long they take based on the size of the table\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer rules' prints the grade of every access type of EXPLAIN, the starting point of the grade of a query\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "'myexplainer --database analytics table page_views' will analyze the 'page_views' table in the 'analytics' database on 'localhost' (default) with user 'root' (default) and password 'root' (default)\n\n")
 		fmt.Fprintf(os.Stderr, "'myexplainer --database analytics logs ./queries.log' will read the 'queries.log' file, parse the queries that it contains and then run EXPLAIN queries in the 'analytics' database on 'localhost' (default) with user 'root' (default) and password 'root' (default)\n\n")
+		fmt.Fprintf(os.Stderr, "'kubectl logs deploy/app | myexplainer --database analytics logs -' will analyze the queries piped into it. Directories, glob patterns such as 'storage/logs/laravel-*.log*' and .gz/.zst files are also accepted\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer --database analytics logs --format jsonl ./storage/logs/queries.json' will read a JSON log where every line looks like {\"message\":\"select ...\",\"context\":{\"bindings\":[...],\"time\":1.2}}\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer --database analytics logs --follow ./storage/logs/laravel.log' will analyze every new query written to 'laravel.log' while you click through your app\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer ddl ./schema.sql' will parse the tables in 'schema.sql' and run every check that doesn't need data or index statistics\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer migrations ./database/migrations' will apply the migrations in file name order and run the same checks on the tables they create or change\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer --database analytics --query-timeout 5s logs ./queries.log' will give up on queries that take longer than 5 seconds. Press Ctrl+C to stop and print the report of the queries analyzed so far\n\n")
+		fmt.Fprintf(os.Stderr, "'myexplainer --database analytics alter \"ALTER TABLE page_views ADD INDEX idx_uri (uri)\"' will look up the size of the 'page_views' table in the 'analytics' database and assess the risk of adding the index\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nFlags of 'logs':\n")
+		logsFlags.PrintDefaults()
 	}
+	logsFlags.Usage = flag.Usage
 
 	if *help {
 		flag.Usage()
@@ -57,6 +96,20 @@ func main() {
 		fmt.Printf("%s %s\n", name, version)
 		return
 	}
+	// rules doesn't need a database or a parameter
+	if flag.Arg(0) == "rules" {
+		fmt.Print(explainer.Rules())
+		return
+	}
+
+	// The first Ctrl+C cancels ctx so the partial report is printed, the second one kills the process
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := platform.WithQueryTimeout(sigCtx, *queryTimeout)
 
 	db, err := sql.Open("mysql", connectionString())
 	if err != nil {
@@ -64,21 +117,64 @@ func main() {
 	}
 	defer db.Close()
 
-	if len(os.Args) < 2 {
+	args := flag.Args()
+	if len(args) < 2 {
 		flag.Usage()
 		return
 	}
 
-	param := os.Args[len(os.Args)-1]
-	cmd := os.Args[len(os.Args)-2]
+	cmd := args[0]
+	param := args[1]
 
 	switch cmd {
 	case "logs":
-		if err = explainer.Explain(db, param); err != nil {
+		logsFlags.Parse(args[1:])
+		if logsFlags.NArg() == 0 {
+			flag.Usage()
+			return
+		}
+		opts := explainer.Options{
+			Format:        *format,
+			Parameterize:  *parameterize,
+			IncludeWrites: *includeWrites,
+			Concurrency:   *concurrency,
+			Limit:         *limit,
+			Retries:       *retries,
+			RetryBackoff:  *retryBackoff,
+			JSONPaths: explainer.JSONPaths{
+				SQL:        explainer.ParseJSONPath(*jsonSQL),
+				Bindings:   explainer.ParseJSONPath(*jsonBindings),
+				Time:       explainer.ParseJSONPath(*jsonTime),
+				Connection: explainer.ParseJSONPath(*jsonConnection),
+				Timestamp:  explainer.ParseJSONPath(*jsonTimestamp),
+			},
+		}
+		db.SetMaxOpenConns(max(*concurrency, 1))
+		if *follow {
+			if logsFlags.NArg() != 1 {
+				log.Fatal("--follow reads exactly one log file or '-' for stdin")
+			}
+			err = explainer.Follow(ctx, db, opts, logsFlags.Arg(0))
+		} else {
+			err = explainer.Explain(ctx, db, opts, logsFlags.Args()...)
+		}
+		if err != nil {
 			log.Fatal(err)
 		}
 	case "table":
-		if err = tableanalyzer.Analyze(db, param); err != nil {
+		if err = tableanalyzer.Analyze(ctx, db, param); err != nil {
+			log.Fatal(err)
+		}
+	case "ddl":
+		if err = tableanalyzer.AnalyzeDDL(param); err != nil {
+			log.Fatal(err)
+		}
+	case "migrations":
+		if err = tableanalyzer.AnalyzeMigrations(param); err != nil {
+			log.Fatal(err)
+		}
+	case "alter":
+		if err = tableanalyzer.AnalyzeAlter(ctx, db, param); err != nil {
 			log.Fatal(err)
 		}
 	default:
@@ -89,5 +185,10 @@ func main() {
 
 func connectionString() string {
 	// "root:root@tcp(127.0.0.1:3306)/analytics"
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", *user, *pass, *host, *port, *database)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", *user, *pass, *host, *port, *database)
+	if *queryTimeout > 0 {
+		// The driver runs SET max_execution_time on every new connection
+		dsn += fmt.Sprintf("?max_execution_time=%d", queryTimeout.Milliseconds())
+	}
+	return dsn
 }
@@ -6,6 +6,7 @@ require (
 	github.com/agiledragon/gomonkey/v2 v2.11.0
 	github.com/fatih/color v1.18.0
 	github.com/go-sql-driver/mysql v1.8.1
+	github.com/klauspost/compress v1.17.11
 	github.com/stretchr/testify v1.10.0
 )
 
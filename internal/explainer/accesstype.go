@@ -0,0 +1,131 @@
+package explainer
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+)
+
+// accessType is the grade and the explanation of a join type, the type column of EXPLAIN
+type accessType struct {
+	name string
+	// description is the summary of the access type in the grading table
+	description string
+	grade       float32
+	// warning is printed in the report. It's empty for the access types that need no attention
+	warning string
+	// uncoveredGrade and uncoveredWarning replace grade and warning if the Extra column doesn't contain "Using index"
+	// uncoveredGrade is 0 if it makes no difference whether the index covers the query
+	uncoveredGrade   float32
+	uncoveredWarning string
+}
+
+// accessTypes are the join types of EXPLAIN from the best to the worst in the order of the MySQL documentation
+var accessTypes = []accessType{
+	{
+		name:        "system",
+		description: "The table has exactly one row.",
+		grade:       5,
+	},
+	{
+		name:        "const",
+		description: "At most one row matches, it's looked up by the primary key or a unique index compared with constants.",
+		grade:       5,
+	},
+	{
+		name:        "eq_ref",
+		description: "One row is looked up by the primary key or a unique NOT NULL index for every row of the tables joined before it.",
+		grade:       5,
+	},
+	{
+		name:        "ref",
+		description: "The rows are looked up by a non-unique index or a prefix of an index compared with =.",
+		grade:       5,
+	},
+	{
+		name:        "fulltext",
+		description: "The rows are found by a FULLTEXT index. It can't be combined with the other indexes of the table.",
+		grade:       4,
+		warning:     `The query uses the "fulltext" access type. MySQL finds the rows in a FULLTEXT index. It can't be combined with the other indexes of the table so the other conditions are checked on every row the search matches.`,
+	},
+	{
+		name:        "ref_or_null",
+		description: `Like "ref" with an extra pass for the rows with NULL: col = ? OR col IS NULL.`,
+		grade:       4,
+		warning:     `The query uses the "ref_or_null" access type. It's like "ref" but MySQL looks up the rows with NULL in an extra pass, it's the plan of col = ? OR col IS NULL. If NULL only means "no value", a NOT NULL column with a default value saves the extra pass.`,
+	},
+	{
+		name:        "index_merge",
+		description: "More than one index of the table is read and the rows they find are merged.",
+		grade:       4,
+		warning:     `The query uses the "index_merge" access type. MySQL reads more than one index of the table and merges the rows they find. See "Index merge" for whether it's a good plan for this query.`,
+	},
+	{
+		name:        "unique_subquery",
+		description: "An IN (SELECT ...) subquery runs as a lookup in a unique index for every row of the outer query.",
+		grade:       4,
+		warning:     `The query uses the "unique_subquery" access type. The IN (SELECT ...) subquery runs as a lookup in the primary key or a unique index for every row of the outer query. Every lookup is fast but it still runs once for every row.`,
+	},
+	{
+		name:        "index_subquery",
+		description: "An IN (SELECT ...) subquery runs as a lookup in a non-unique index for every row of the outer query.",
+		grade:       3,
+		warning:     `The query uses the "index_subquery" access type. The IN (SELECT ...) subquery runs as a lookup in a non-unique index for every row of the outer query. Rewriting it as a JOIN lets MySQL choose the join order.`,
+	},
+	{
+		name:             "range",
+		description:      "A range of an index is read such as BETWEEN, >, < or IN.",
+		grade:            4,
+		uncoveredGrade:   3,
+		uncoveredWarning: `Altough your query uses the "range" access type, the "Extra" column does not contain "Using index". It means you run unnecessary I/O operations. First, the DB scans the BTREE index for matching rows and then it runs I/O operations for each node. It can be slower if you have a large number of records.`,
+	},
+	{
+		name:             "index",
+		description:      "The whole index is read from the beginning to the end.",
+		grade:            2,
+		warning:          `The query uses the "index" access type. It scans every node in the index BTREE which is pretty inefficient. It will cause you trouble if you have a large number of records. Fortunately, the "Extra" column contains "Using index" which means the query does not run a large number of extra I/O operations.`,
+		uncoveredGrade:   1,
+		uncoveredWarning: `Altough your query uses the "index" access type, the "Extra" column does not contain "Using index". It means you effectively do a FULL TABLE SCAN. First, the DB scans the whole BTREE index and then runs I/O operations for each node to satisfy the SELECT statement. It often happens when "SELECT *" is used. It will cause you trouble if you have a large number of records.`,
+	},
+	{
+		name:        "ALL",
+		description: "Every row of the table is read without an index.",
+		grade:       1,
+		warning:     `The query uses the "ALL" access type. It scans ALL rows from the disk without using an index. It will cause you trouble if you have a large number of records.`,
+	},
+}
+
+// findAccessType returns the access type with the name as EXPLAIN shows it
+func findAccessType(name string) (accessType, bool) {
+	for _, t := range accessTypes {
+		if strings.EqualFold(t.name, name) {
+			return t, true
+		}
+	}
+	return accessType{}, false
+}
+
+// Rules returns the grading table of the access types
+//
+// Every row of the plan is graded and a query starts with the grade of its worst row. The other checks take points off
+// so the table is the best grade a query with that access type in its plan can get.
+func Rules() string {
+	var str strings.Builder
+	str.WriteString("Every row of the plan is graded by its access type (the type column of EXPLAIN): the tables of a join, the subqueries and the derived tables.\n")
+	str.WriteString("A query starts with the grade of its worst row.\n")
+	str.WriteString(fmt.Sprintf("The other checks take points off down to %0.2f and the report explains every one of them.\n\n", grade.MinGrade))
+
+	w := tabwriter.NewWriter(&str, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Access type\tGrade\tWithout \"Using index\"\tDescription\n")
+	for _, t := range accessTypes {
+		uncovered := "-"
+		if t.uncoveredGrade != 0 {
+			uncovered = fmt.Sprintf("%0.2f", t.uncoveredGrade)
+		}
+		fmt.Fprintf(w, "%s\t%0.2f\t%s\t%s\n", t.name, t.grade, uncovered, t.description)
+	}
+	w.Flush()
+	return str.String()
+}
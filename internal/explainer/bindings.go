@@ -0,0 +1,287 @@
+package explainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bindingsParser parses the bindings block at the end of a log entry such as [10, "Doe, John", null, [1,2]]
+// or {"id": 10, "name": "Doe, John"} for named placeholders
+//
+// Values are converted to Go types:
+//   - Quoted strings with escapes: string
+//   - Integers: int64, other numbers: float64
+//   - true and false: bool
+//   - null: nil, and so are nil (Ruby, Elixir) and None (Python)
+//   - Nested arrays and objects: their JSON representation as a string because they are usually stored in JSON columns
+//   - Anything else is an unquoted string such as 2024-12-13 20:05:44
+type bindingsParser struct {
+	s   string
+	pos int
+}
+
+// parseBindings parses a whole bindings block
+// An array results in positional bindings, an object in named bindings.
+func parseBindings(block string) ([]any, map[string]any, error) {
+	p := bindingsParser{s: block}
+	p.skipSpace()
+
+	var positional []any
+	var named map[string]any
+	var err error
+	switch {
+	case p.accept('['):
+		positional, err = p.array()
+		for i, v := range positional {
+			positional[i] = flatBinding(v)
+		}
+	case p.accept('{'):
+		named, err = p.named()
+	default:
+		return nil, nil, fmt.Errorf("explainer.parseBindings: bindings must start with \"[\" or \"{\": %s", block)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("explainer.parseBindings: %w: %s", err, block)
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, nil, fmt.Errorf("explainer.parseBindings: unexpected %q after the bindings: %s", p.s[p.pos:], block)
+	}
+	return positional, named, nil
+}
+
+// flatBinding turns a nested array into its JSON representation
+func flatBinding(v any) any {
+	if nested, ok := v.([]any); ok {
+		j, _ := json.Marshal(nested)
+		return string(j)
+	}
+	return v
+}
+
+// named parses the keys and values of an object after its opening brace
+func (p *bindingsParser) named() (map[string]any, error) {
+	values := make(map[string]any)
+	p.skipSpace()
+	if p.accept('}') {
+		return values, nil
+	}
+	for {
+		var key string
+		if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+			k, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			key = k
+		} else {
+			start := p.pos
+			for p.pos < len(p.s) && p.s[p.pos] != ':' && p.s[p.pos] != ',' && p.s[p.pos] != '}' {
+				p.pos++
+			}
+			key = strings.TrimSpace(p.s[start:p.pos])
+		}
+		p.skipSpace()
+		if len(key) == 0 || !p.accept(':') {
+			return nil, fmt.Errorf("expected a key and \":\" at position %d", p.pos)
+		}
+		p.skipSpace()
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values[key] = flatBinding(v)
+
+		p.skipSpace()
+		switch {
+		case p.accept(','):
+			p.skipSpace()
+		case p.accept('}'):
+			return values, nil
+		default:
+			return nil, fmt.Errorf("expected \",\" or \"}\" at position %d", p.pos)
+		}
+	}
+}
+
+// array parses the values of an array after its opening bracket
+func (p *bindingsParser) array() ([]any, error) {
+	values := make([]any, 0)
+	p.skipSpace()
+	if p.accept(']') {
+		return values, nil
+	}
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		p.skipSpace()
+		switch {
+		case p.accept(','):
+			p.skipSpace()
+		case p.accept(']'):
+			return values, nil
+		default:
+			return nil, fmt.Errorf("expected \",\" or \"]\" at position %d", p.pos)
+		}
+	}
+}
+
+func (p *bindingsParser) value() (any, error) {
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("unexpected end of bindings")
+	}
+
+	switch c := p.s[p.pos]; c {
+	case '"', '\'':
+		return p.quoted()
+	case '[':
+		p.pos++
+		return p.array()
+	case '{':
+		return p.object()
+	default:
+		return p.bare()
+	}
+}
+
+// quoted parses a string literal. Backslash escapes and doubled quotes are resolved.
+func (p *bindingsParser) quoted() (string, error) {
+	quote := p.s[p.pos]
+	var str strings.Builder
+	for i := p.pos + 1; i < len(p.s); i++ {
+		c := p.s[i]
+		switch {
+		case c == '\\' && i+1 < len(p.s):
+			i++
+			switch p.s[i] {
+			case 'n':
+				str.WriteByte('\n')
+			case 't':
+				str.WriteByte('\t')
+			case 'r':
+				str.WriteByte('\r')
+			case 'u':
+				if r, err := strconv.ParseUint(p.s[i+1:min(i+5, len(p.s))], 16, 32); err == nil && i+5 <= len(p.s) {
+					str.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+				str.WriteByte('u')
+			default:
+				str.WriteByte(p.s[i])
+			}
+		case c == quote && i+1 < len(p.s) && p.s[i+1] == quote:
+			str.WriteByte(c)
+			i++
+		case c == quote:
+			p.pos = i + 1
+			return str.String(), nil
+		default:
+			str.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string at position %d", p.pos)
+}
+
+// object returns a JSON object as it's written
+func (p *bindingsParser) object() (string, error) {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '"', '\'':
+			if _, err := p.quoted(); err != nil {
+				return "", err
+			}
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.s[start:p.pos], nil
+			}
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated object at position %d", start)
+}
+
+// bare parses an unquoted value up to the next comma, closing bracket or closing brace
+func (p *bindingsParser) bare() (any, error) {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != ',' && p.s[p.pos] != ']' && p.s[p.pos] != '}' {
+		p.pos++
+	}
+	v := strings.TrimSpace(p.s[start:p.pos])
+	// Quotes and brackets mean the value is part of the query, not an unquoted binding
+	if len(v) == 0 || strings.ContainsAny(v, "[](){}'\"`") {
+		return nil, fmt.Errorf("unexpected %q at position %d", v, start)
+	}
+
+	switch strings.ToLower(v) {
+	case "null", "nil", "none":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f, nil
+	}
+	return v, nil
+}
+
+func (p *bindingsParser) accept(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *bindingsParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// bindingsIndex returns the position of the bindings block at the end of a log entry or -1 if there's none
+//
+// The block is the leftmost "[" or "{" from which the rest of the entry parses as bindings,
+// so brackets in the query such as JSON paths ('$[0]') and in nested arrays don't confuse it.
+func bindingsIndex(entry string) int {
+	if !hasBindings(entry) {
+		return -1
+	}
+	return blockIndex(entry, "[{")
+}
+
+// blockIndex returns the leftmost position of one of the opening characters from which the rest of the entry
+// parses as bindings or -1 if there's none
+func blockIndex(entry, open string) int {
+	for i := strings.IndexAny(entry, open); i != -1; {
+		if _, _, err := parseBindings(entry[i:]); err == nil {
+			return i
+		}
+		next := strings.IndexAny(entry[i+1:], open)
+		if next == -1 {
+			break
+		}
+		i += next + 1
+	}
+	return -1
+}
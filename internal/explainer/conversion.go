@@ -0,0 +1,216 @@
+package explainer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+var (
+	// conversionNote is the warning of EXPLAIN when a comparison can't use an index because of a conversion
+	conversionNote = regexp.MustCompile(`Cannot use (\w+) access on index '([^']*)' due to type or collation conversion on field '([^']*)'`)
+
+	// stringTypes and numericTypes are the column types that are compared as strings and as numbers
+	stringTypes  = []string{"char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set"}
+	numericTypes = []string{"tinyint", "smallint", "mediumint", "int", "integer", "bigint", "decimal", "numeric", "float", "double", "real", "bit"}
+
+	// conversionOperators are the comparisons that convert the two sides to the same type
+	conversionOperators = []string{"=", "<", ">", "<=", ">=", "<>", "!=", "<=>", "in", "between"}
+)
+
+// comparedValue is a literal or a binding a column is compared with
+type comparedValue struct {
+	value any
+	// text is how the value is referred to in the report such as '123' or binding #2 (123)
+	text string
+}
+
+// checkTypeConversion checks for columns that are compared with a value of a different type
+//
+// For example:
+//
+// select * from users where phone = 123
+//
+// phone is a varchar column so MySQL converts the phone of every row to a number to compare them and the index
+// on phone can't be used. The other way around, an int column compared with a string, the string is converted once.
+// That can still use the index but a value that is not a number is converted to 0.
+//
+// The conversions of the rewritten query and the ones MySQL reports in the warnings of the EXPLAIN are reported too,
+// they include collation conversions such as a utf8mb4_unicode_ci column joined with a utf8mb4_0900_ai_ci column.
+func (r *Result) checkTypeConversion(ctx context.Context, s *schema) {
+	tokens := sqllexer.Tokenize(r.explain.Query.SQL)
+	tables := queryTables(tokens)
+	positions := make(map[int]int)
+	for i, p := range findPlaceholders(r.explain.Query.SQL) {
+		positions[p.start] = i
+	}
+
+	warnings := make([]string, 0)
+	// fields are the columns that already have a warning
+	fields := make([]string, 0)
+	blocksIndex := false
+	for _, c := range comparisons(tokens) {
+		if !slices.Contains(conversionOperators, c.op) {
+			continue
+		}
+		colSide, valueSide := c.left, c.right
+		if !isColumn(colSide) {
+			colSide, valueSide = c.right, c.left
+		}
+		if !isColumn(colSide) {
+			continue
+		}
+		ref := columnRefs(colSide)[0]
+		tableRef, ok := resolveTable(tables, ref)
+		if !ok {
+			continue
+		}
+		table, ok := s.table(ctx, tableRef.name)
+		if !ok {
+			continue
+		}
+		dataType, ok := table.ColumnType(ref.column)
+		if !ok {
+			continue
+		}
+		indexes := table.IndexesOf(ref.column)
+
+		for _, v := range r.comparedValues(valueSide, positions) {
+			switch {
+			case isTypeOf(dataType, stringTypes) && isNumber(v.value):
+				index := ""
+				if len(indexes) != 0 {
+					index = fmt.Sprintf(" and the index %s can't be used", strings.Join(indexes, ", "))
+					blocksIndex = true
+				}
+				warnings = append(warnings, fmt.Sprintf("%s.%s is %s but %s is a number. MySQL converts %s of every row to a number to compare them%s. '1.0', ' 1' and '1abc' are all equal to 1 this way. Pass the value as a string.", tableRef.name, ref.column, dataType, v.text, ref.column, index))
+				fields = append(fields, strings.ToLower(ref.column))
+			case isTypeOf(dataType, numericTypes) && isNonNumericString(v.value):
+				warnings = append(warnings, fmt.Sprintf("%s.%s is %s but %s is not a number. MySQL converts it to a number, a value such as 'abc' becomes 0 and matches the rows where %s is 0. Pass the value as a number.", tableRef.name, ref.column, dataType, v.text, ref.column))
+				fields = append(fields, strings.ToLower(ref.column))
+			}
+		}
+	}
+
+	// MySQL 8.0.18 and later show the conversions they add to a column in the rewritten query
+	// Casts written in the query are reported by checkColumnFunctions
+	written := castColumns(tokens)
+	for _, m := range rewrittenCast.FindAllStringSubmatch(r.explain.RewrittenSQL, -1) {
+		if slices.Contains(fields, strings.ToLower(m[2])) || slices.Contains(written, strings.ToLower(m[2])) {
+			continue
+		}
+		fields = append(fields, strings.ToLower(m[2]))
+		index := ""
+		if table, ok := s.table(ctx, m[1]); ok {
+			if indexes := table.IndexesOf(m[2]); len(indexes) != 0 {
+				index = fmt.Sprintf(" so the index %s can't be used", strings.Join(indexes, ", "))
+				blocksIndex = true
+			}
+		}
+		warnings = append(warnings, fmt.Sprintf("The optimizer converts %s.%s to %s in every row to compare it%s. Compare it with a value of its own type.", m[1], m[2], m[3], index))
+	}
+
+	for _, w := range r.explain.Warnings {
+		m := conversionNote.FindStringSubmatch(w.Message)
+		if m == nil || slices.Contains(fields, strings.ToLower(m[3])) {
+			continue
+		}
+		blocksIndex = true
+		fields = append(fields, strings.ToLower(m[3]))
+		warnings = append(warnings, fmt.Sprintf("MySQL can't use %s access on the index %s because %s is compared with a value or a column of a different type or collation. Compare it with a value of the same type, or make the collations of joined columns the same.", m[1], m[2], m[3]))
+	}
+
+	if len(warnings) == 0 {
+		return
+	}
+	if blocksIndex {
+		r.grade = grade.Dec(r.grade, 1.5)
+	} else {
+		r.grade = grade.Dec(r.grade, 0.25)
+	}
+	r.typeConversionWarning = strings.Join(warnings, " ")
+}
+
+// castColumns returns the lowercase columns that are wrapped in CAST() or CONVERT() in a comparison of the query
+func castColumns(tokens []sqllexer.Token) []string {
+	columns := make([]string, 0)
+	for _, c := range comparisons(tokens) {
+		for _, side := range [][]sqllexer.Token{c.left, c.right} {
+			if function, _ := wrapperOf(side); function != "cast" && function != "convert" {
+				continue
+			}
+			for _, ref := range columnRefs(side) {
+				if column := strings.ToLower(ref.column); !slices.Contains(columns, column) {
+					columns = append(columns, column)
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// comparedValues returns the literals and the bindings of one side of a comparison
+// Subqueries and columns are left out.
+func (r *Result) comparedValues(tokens []sqllexer.Token, positions map[int]int) []comparedValue {
+	if slices.ContainsFunc(tokens, func(t sqllexer.Token) bool { return t.Is("select") }) {
+		return nil
+	}
+	values := make([]comparedValue, 0)
+	for _, t := range tokens {
+		switch {
+		case t.Kind == sqllexer.Number || t.Kind == sqllexer.String:
+			if v, ok := literalValue(t); ok {
+				values = append(values, comparedValue{value: v, text: t.Text})
+			}
+		case t.Kind == sqllexer.Punct && t.Text == "?":
+			i, ok := positions[t.Pos]
+			if !ok || i >= len(r.explain.Query.Bindings) {
+				continue
+			}
+			v := r.explain.Query.Bindings[i]
+			values = append(values, comparedValue{value: v, text: fmt.Sprintf("binding #%d (%v)", i+1, bindingText(v))})
+		}
+	}
+	return values
+}
+
+// isTypeOf reports whether a column type such as varchar(255) or int unsigned is one of the types
+func isTypeOf(dataType string, types []string) bool {
+	name := strings.ToLower(dataType)
+	if i := strings.IndexAny(name, "( "); i != -1 {
+		name = name[:i]
+	}
+	return slices.Contains(types, name)
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int, int64, float64:
+		return true
+	}
+	return false
+}
+
+// isNonNumericString reports whether v is a string that MySQL can't convert to a number without losing it
+func isNonNumericString(v any) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err != nil
+}
+
+// bindingText returns a binding the way it's written in a query such as 'abc' or 123
+func bindingText(v any) string {
+	if s, ok := v.(string); ok {
+		return "'" + s + "'"
+	}
+	return fmt.Sprintf("%v", v)
+}
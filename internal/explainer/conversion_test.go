@@ -0,0 +1,80 @@
+package explainer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const usersDDL = `CREATE TABLE users (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	phone varchar(20) NOT NULL,
+	country_id int NOT NULL,
+	nickname varchar(50) NULL,
+	PRIMARY KEY (id),
+	KEY idx_phone (phone)
+)`
+
+func TestCheckTypeConversion_StringColumn(t *testing.T) {
+	s := testSchema(t, usersDDL)
+	q := newQuery("select * from users where country_id = ? and phone = ?")
+	q.Bindings = []any{int64(36), int64(123)}
+	res := newResult(ExplainResult{Query: q})
+	res.checkTypeConversion(context.Background(), s)
+
+	assert.Equal(t, "users.phone is varchar(20) but binding #2 (123) is a number. MySQL converts phone of every row to a number to compare them and the index idx_phone can't be used. '1.0', ' 1' and '1abc' are all equal to 1 this way. Pass the value as a string.", res.typeConversionWarning)
+	assert.Equal(t, float32(3.5), res.Grade())
+}
+
+func TestCheckTypeConversion_NumericColumn(t *testing.T) {
+	s := testSchema(t, usersDDL)
+	res := newResult(ExplainResult{Query: newQuery("select * from users u where u.country_id in ('36', 'abc') and u.nickname = 'John'")})
+	res.checkTypeConversion(context.Background(), s)
+
+	assert.Equal(t, "users.country_id is int but 'abc' is not a number. MySQL converts it to a number, a value such as 'abc' becomes 0 and matches the rows where country_id is 0. Pass the value as a number.", res.typeConversionWarning)
+	assert.Equal(t, float32(4.75), res.Grade())
+}
+
+func TestCheckTypeConversion_ExplainWarning(t *testing.T) {
+	s := testSchema(t, usersDDL)
+	res := newResult(ExplainResult{
+		Query: newQuery("select * from users u join contacts c on c.phone = u.phone"),
+		Warnings: []ExplainWarning{
+			{Level: "Warning", Code: 1739, Message: "Cannot use ref access on index 'idx_phone' due to type or collation conversion on field 'phone'"},
+			{Level: "Note", Code: 1003, Message: "/* select#1 */ select ..."},
+		},
+	})
+	res.checkTypeConversion(context.Background(), s)
+
+	assert.Equal(t, "MySQL can't use ref access on the index idx_phone because phone is compared with a value or a column of a different type or collation. Compare it with a value of the same type, or make the collations of joined columns the same.", res.typeConversionWarning)
+	assert.Equal(t, float32(3.5), res.Grade())
+}
+
+func TestCheckTypeConversion_RewrittenSQL(t *testing.T) {
+	s := testSchema(t, usersDDL)
+	res := newResult(ExplainResult{
+		Query:        newQuery("select * from users where phone = 123"),
+		RewrittenSQL: "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users` where (cast(`app`.`users`.`phone` as double) = 123)",
+	})
+	res.checkTypeConversion(context.Background(), s)
+	// The literal is already reported by the comparison
+	assert.NotContains(t, res.typeConversionWarning, "The optimizer converts")
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from users u where u.phone = u.country_id"),
+		RewrittenSQL: "/* select#1 */ select `app`.`u`.`id` AS `id` from `app`.`users` `u` where (cast(`app`.`users`.`phone` as double) = cast(`app`.`users`.`country_id` as double))",
+	})
+	res.checkTypeConversion(context.Background(), s)
+	assert.Contains(t, res.typeConversionWarning, "The optimizer converts users.phone to double in every row to compare it so the index idx_phone can't be used.")
+	assert.Contains(t, res.typeConversionWarning, "The optimizer converts users.country_id to double in every row to compare it.")
+	assert.Equal(t, float32(3.5), res.Grade())
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from users where CAST(phone AS double) = country_id"),
+		RewrittenSQL: "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users` where (cast(`app`.`users`.`phone` as double) = cast(`app`.`users`.`country_id` as double))",
+	})
+	res.checkTypeConversion(context.Background(), s)
+	// The cast of phone is written in the query and reported by checkColumnFunctions
+	assert.NotContains(t, res.typeConversionWarning, "users.phone")
+	assert.Contains(t, res.typeConversionWarning, "The optimizer converts users.country_id to double")
+}
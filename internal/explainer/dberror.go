@@ -0,0 +1,140 @@
+package explainer
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// errorClass groups the errors of EXPLAIN queries by what caused them and whether they are worth retrying
+type errorClass string
+
+const (
+	errorTooManyConnections errorClass = "too many connections"
+	errorLockWait           errorClass = "lock wait timeout"
+	errorDeadlock           errorClass = "deadlock"
+	// errorConnectionLost is a connection that was closed or killed while it was used such as 'server has gone away'
+	errorConnectionLost errorClass = "connection lost"
+	// errorTimeout is a query that ran longer than --query-timeout or max_execution_time
+	errorTimeout      errorClass = "query timeout"
+	errorSyntax       errorClass = "syntax error"
+	errorUnknownTable errorClass = "unknown table or column"
+	errorAccess       errorClass = "access denied"
+	errorOther        errorClass = "other"
+)
+
+// maxRetryBackoff is the longest wait between two attempts of a query
+const maxRetryBackoff = 10 * time.Second
+
+// errorClasses maps MySQL error numbers to their class
+// See https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+var errorClasses = map[uint16]errorClass{
+	1040: errorTooManyConnections, // ER_CON_COUNT_ERROR
+	1203: errorTooManyConnections, // ER_TOO_MANY_USER_CONNECTIONS
+	1205: errorLockWait,           // ER_LOCK_WAIT_TIMEOUT, also returned when waiting for a metadata lock
+	1213: errorDeadlock,           // ER_LOCK_DEADLOCK
+	1053: errorConnectionLost,     // ER_SERVER_SHUTDOWN
+	1927: errorConnectionLost,     // ER_CONNECTION_KILLED
+	2006: errorConnectionLost,     // CR_SERVER_GONE_ERROR
+	2013: errorConnectionLost,     // CR_SERVER_LOST
+	3024: errorTimeout,            // ER_QUERY_TIMEOUT
+	1317: errorTimeout,            // ER_QUERY_INTERRUPTED
+	1064: errorSyntax,             // ER_PARSE_ERROR
+	1149: errorSyntax,             // ER_SYNTAX_ERROR
+	1146: errorUnknownTable,       // ER_NO_SUCH_TABLE
+	1054: errorUnknownTable,       // ER_BAD_FIELD_ERROR
+	1049: errorUnknownTable,       // ER_BAD_DB_ERROR
+	1305: errorUnknownTable,       // ER_SP_DOES_NOT_EXIST
+	1044: errorAccess,             // ER_DBACCESS_DENIED_ERROR
+	1045: errorAccess,             // ER_ACCESS_DENIED_ERROR
+	1142: errorAccess,             // ER_TABLEACCESS_DENIED_ERROR
+	1143: errorAccess,             // ER_COLUMNACCESS_DENIED_ERROR
+}
+
+// classifyError returns the class of an error returned by an EXPLAIN query
+func classifyError(err error) errorClass {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		if class, ok := errorClasses[mysqlErr.Number]; ok {
+			return class
+		}
+		return errorOther
+	}
+	switch {
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn):
+		return errorConnectionLost
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorTimeout
+	}
+	return errorOther
+}
+
+// transient reports whether a query that failed with an error of the class may succeed if it's run again
+// A query that timed out is not retried, it would most likely time out again.
+func (c errorClass) transient() bool {
+	switch c {
+	case errorTooManyConnections, errorLockWait, errorDeadlock, errorConnectionLost:
+		return true
+	}
+	return false
+}
+
+// retryBackoff returns how long to wait before the next attempt after the given number of failed attempts
+// The wait doubles after every attempt up to [maxRetryBackoff].
+func retryBackoff(base time.Duration, attempts int) time.Duration {
+	d := base
+	for range attempts - 1 {
+		if d >= maxRetryBackoff/2 {
+			return maxRetryBackoff
+		}
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// explainFailures are the queries that could not be explained grouped by the class of their error
+type explainFailures map[errorClass][]QueryError
+
+func (f explainFailures) add(err QueryError) {
+	f[err.class] = append(f[err.class], err)
+}
+
+func (f explainFailures) total() int {
+	n := 0
+	for _, errs := range f {
+		n += len(errs)
+	}
+	return n
+}
+
+// String returns the failed queries under their class, the classes with the most queries first:
+//
+//	lock wait timeout (2):
+//	  query select ... failed after 4 attempts: Error 1205 (HY000): Lock wait timeout exceeded
+func (f explainFailures) String() string {
+	classes := make([]errorClass, 0, len(f))
+	for c := range f {
+		classes = append(classes, c)
+	}
+	slices.SortFunc(classes, func(a, b errorClass) int {
+		if c := len(f[b]) - len(f[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a), string(b))
+	})
+
+	var sb strings.Builder
+	for _, c := range classes {
+		fmt.Fprintf(&sb, "%s (%d):\n", c, len(f[c]))
+		for _, err := range f[c] {
+			fmt.Fprintf(&sb, "  %s\n", err)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,35 @@
+package explainer
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	assert.Equal(t, errorTooManyConnections, classifyError(&mysql.MySQLError{Number: 1040}))
+	assert.Equal(t, errorDeadlock, classifyError(fmt.Errorf("explain: %w", &mysql.MySQLError{Number: 1213})))
+	assert.Equal(t, errorTimeout, classifyError(&mysql.MySQLError{Number: 3024}))
+	assert.Equal(t, errorSyntax, classifyError(&mysql.MySQLError{Number: 1064}))
+	assert.Equal(t, errorOther, classifyError(&mysql.MySQLError{Number: 1210}))
+	assert.Equal(t, errorConnectionLost, classifyError(driver.ErrBadConn))
+	assert.Equal(t, errorConnectionLost, classifyError(mysql.ErrInvalidConn))
+	assert.Equal(t, errorTimeout, classifyError(context.DeadlineExceeded))
+	assert.Equal(t, errorOther, classifyError(errors.New("sql: converting argument")))
+
+	assert.True(t, errorLockWait.transient())
+	assert.False(t, errorTimeout.transient())
+	assert.False(t, errorSyntax.transient())
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, retryBackoff(100*time.Millisecond, 1))
+	assert.Equal(t, 200*time.Millisecond, retryBackoff(100*time.Millisecond, 2))
+	assert.Equal(t, 800*time.Millisecond, retryBackoff(100*time.Millisecond, 4))
+	assert.Equal(t, maxRetryBackoff, retryBackoff(time.Second, 10))
+}
@@ -4,7 +4,7 @@
 //
 // db, _ := sql.Open("mysql", "<connectionString>")
 //
-//	if err := explainer.Explain(db, "./queries.log"); err != nil {
+//	if err := explainer.Explain(context.Background(), db, explainer.Options{}, "./queries.log"); err != nil {
 //	    log.Fatal(err)
 //	}
 //
@@ -13,15 +13,16 @@
 package explainer
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/mmartinjoo/explainer/internal/platform"
 	"github.com/mmartinjoo/explainer/internal/platform/grade"
 	"log"
-	"os"
 	"slices"
 	"strings"
+	"time"
 )
 
 type (
@@ -34,15 +35,61 @@ type (
 		selectStarWarning       string
 		likePatternWarning      string
 		joinOrderWarning        string
+		joinIndexWarning        string
+		columnFunctionWarning   string
+		typeConversionWarning   string
+		semiJoinWarning         string
+		removedConditionWarning string
+		paginationWarning       string
+		nPlusOneWarning         string
+		orConditionWarning      string
+		indexMergeWarning       string
 		subqueryInSelectWarning string
+		writeIndexWarning       string
+		deleteLimitWarning      string
+		lockedRowsWarning       string
 		grade                   float32
 	}
 
 	Query struct {
 		SQL      string
 		Bindings []any
-	}
-
+		// Time is the execution time in milliseconds if the log contains it
+		Time float64
+		// Connection is the connection or database name if the log contains it
+		Connection string
+		// LoggedAt is the time of the log entry if the log contains it. It's the time of the last entry of the query
+		LoggedAt time.Time
+		// Burst is the longest run of the query with different bindings in the logs. Its Count is 0 if the query never ran in a burst
+		Burst Burst
+		// Offsets are the OFFSETs of every execution of the query in the logs in the order they were logged.
+		// It's empty if the query has no OFFSET
+		Offsets []int64
+	}
+
+	// Options configures how log files are read
+	Options struct {
+		// Format is the format of the log files: [FormatText], [FormatJSONL] or one of the framework presets such as [FormatRails]
+		Format string
+		// JSONPaths tells where the fields of a query are in a JSON log entry. It's only used with [FormatJSONL]
+		JSONPaths JSONPaths
+		// Parameterize extracts the literals of queries logged without bindings into placeholders and bindings
+		// so the values don't show up in the report and the checks that look at bindings work on them
+		Parameterize bool
+		// IncludeWrites explains UPDATE, DELETE and INSERT ... SELECT statements too. They are never executed
+		IncludeWrites bool
+		// Concurrency is the number of EXPLAIN queries that run at the same time. Values below 1 mean 1
+		Concurrency int
+		// Limit is the maximum number of unique queries to analyze in the order they first appear in the logs. 0 means no limit
+		Limit int
+		// Retries is the number of times a query is run again after a transient error such as a deadlock, a lock wait timeout
+		// or a lost connection
+		Retries int
+		// RetryBackoff is the wait before the first retry. It doubles after every retry
+		RetryBackoff time.Duration
+	}
+
+	// ExplainResult is the EXPLAIN of a query. The fields are the ones of the first row of the plan
 	ExplainResult struct {
 		Query        Query
 		ID           int
@@ -57,29 +104,92 @@ type (
 		NumberOfRows sql.NullInt64
 		Filtered     sql.NullFloat64
 		Extra        sql.NullString
+		// Plan is every row of the EXPLAIN in the order MySQL returns them.
+		// The tables of a join are in the order the optimizer joins them.
+		Plan []ExplainRow
+		// Warnings are the rows of SHOW WARNINGS after the EXPLAIN
+		Warnings []ExplainWarning
+		// RewrittenSQL is the query the way the optimizer rewrote it, taken from the note with code 1003 of the warnings.
+		// The names are fully qualified and it shows the subqueries converted into joins and the conditions that were removed
+		RewrittenSQL string
+	}
+
+	// ExplainWarning is a note or a warning MySQL reports about the plan of a query
+	ExplainWarning struct {
+		Level   string
+		Code    int
+		Message string
+	}
+
+	// ExplainRow is a row of an EXPLAIN, one for every table a query reads
+	ExplainRow struct {
+		// ID is NULL in the row of a UNION result
+		ID           sql.NullInt64
+		SelectType   sql.NullString
+		Table        sql.NullString
+		Partitions   sql.NullString
+		QueryType    sql.NullString
+		PossibleKeys sql.NullString
+		Key          sql.NullString
+		KeyLen       sql.NullInt64
+		Ref          sql.NullString
+		NumberOfRows sql.NullInt64
+		Filtered     sql.NullFloat64
+		Extra        sql.NullString
 	}
 )
 
+// reportTimeout is the time the checks have to look up the tables of the queries once they are explained
+const reportTimeout = 10 * time.Second
+
+const (
+	// FormatText is a log in which every entry contains a SQL query optionally followed by its bindings
+	FormatText = "text"
+	// FormatJSONL is a log in which every line is a JSON object such as Monolog, Logrus, Zap or Bunyan write
+	FormatJSONL = "jsonl"
+	// FormatRails is an ActiveRecord log
+	FormatRails = "rails"
+	// FormatDjango is a django.db.backends log
+	FormatDjango = "django"
+	// FormatHibernate is a Hibernate log with show_sql or the org.hibernate.SQL logger and optionally the BasicBinder logger
+	FormatHibernate = "hibernate"
+	// FormatGORM is a GORM log
+	FormatGORM = "gorm"
+	// FormatEcto is an Ecto log
+	FormatEcto = "ecto"
+)
+
 // Explain is the main entrypoint of the package:
-//   - Parses the log file
+//   - Reads the log files (see [readLogs] for the accepted paths)
+//   - Parses the queries of every file into one deduplicated set
 //   - Runs the EXPLAIN queries
 //   - Runs the checks
 //   - Prints the result to stdout
-func Explain(db *sql.DB, logFilePath string) error {
-	f, err := os.Open(logFilePath)
+//
+// If ctx is canceled, for example on Ctrl+C, no more queries are explained and the report of the queries
+// that have already been explained is printed before the error is returned.
+func Explain(ctx context.Context, db *sql.DB, opts Options, logFilePaths ...string) error {
+	logs, err := readLogs(logFilePaths, opts.Format)
 	if err != nil {
 		return fmt.Errorf("explainer.Explain: %w", err)
 	}
 
-	queries, err := parseLogs(f)
+	queries, skipped, err := opts.parse(logs)
 	if err != nil {
 		return fmt.Errorf("explainer.Explain: %w", err)
 	}
+	if n := skipped.total(); n != 0 {
+		log.Printf("Skipped %d log entries: %s\n", n, skipped)
+	}
 
+	if opts.Limit > 0 && len(queries) > opts.Limit {
+		log.Printf("Found %d unique queries, only the first %d are analyzed because of the limit\n", len(queries), opts.Limit)
+		queries = queries[:opts.Limit]
+	}
 	log.Printf("Analyzing %d unique queries...\n", len(queries))
 
 	var tooManyConnectionsErr error
-	explains, err := runExplainQueries(db, queries)
+	explains, failures, err := runExplainQueries(ctx, db, queries, opts)
 	if err != nil && !errors.As(err, &TooManyConnectionsError{}) {
 		return fmt.Errorf("explainer.Explain: %w", err)
 	}
@@ -87,16 +197,23 @@ func Explain(db *sql.DB, logFilePath string) error {
 		tooManyConnectionsErr = err
 	}
 
-	results, err := check(db, explains)
-	if err != nil {
-		return fmt.Errorf("explainer.Explain: %w", err)
-	}
+	// The checks look up the tables of the queries. After Ctrl+C ctx is canceled but the partial report still needs them
+	reportCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportTimeout)
+	defer cancel()
+	results := check(reportCtx, explains, newSchema(db))
 
 	for _, res := range results {
 		platform.PrintResults(&res)
 	}
 
 	log.Printf("%d unique queries were analyzed", len(explains))
+	if n := failures.total(); n != 0 {
+		log.Printf("Could not explain %d queries:\n%s", n, failures)
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("explainer.Explain: interrupted, the report contains %d of %d queries: %w", len(explains), len(queries), ctx.Err())
+	}
 
 	if tooManyConnectionsErr != nil {
 		return tooManyConnectionsErr
@@ -105,7 +222,8 @@ func Explain(db *sql.DB, logFilePath string) error {
 }
 
 // check runs all the checks and returns a [Result] slice ordered by grade
-func check(db *sql.DB, explains []ExplainResult) ([]Result, error) {
+// Results with the same grade keep the order of explains.
+func check(ctx context.Context, explains []ExplainResult, s *schema) []Result {
 	var results []Result
 	for _, e := range explains {
 		res := newResult(e)
@@ -116,13 +234,21 @@ func check(db *sql.DB, explains []ExplainResult) ([]Result, error) {
 		res.checkLikePattern()
 		res.checkSelectStar()
 		res.checkSubqueryInSelect()
-		if err := res.checkJoinOrder(db); err != nil {
-			log.Printf("unable to check join order: %s. Query: \"%s\"", err, e.Query.SQL)
-		}
+		res.checkWrite()
+		res.checkJoinOrder(ctx, s)
+		res.checkJoinIndex()
+		res.checkColumnFunctions(ctx, s)
+		res.checkTypeConversion(ctx, s)
+		res.checkSemiJoin()
+		res.checkRemovedConditions()
+		res.checkOrConditions(ctx, s)
+		res.checkIndexMerge(ctx, s)
+		res.checkPagination(ctx, s)
+		res.checkNPlusOne()
 		results = append(results, *res)
 	}
 
-	slices.SortFunc(results, func(a, b Result) int {
+	slices.SortStableFunc(results, func(a, b Result) int {
 		if a.grade < b.grade {
 			return 1
 		}
@@ -131,7 +257,7 @@ func check(db *sql.DB, explains []ExplainResult) ([]Result, error) {
 		}
 		return 0
 	})
-	return results, nil
+	return results
 }
 
 func (r *Result) Grade() float32 {
@@ -142,6 +268,12 @@ func (r *Result) String() string {
 	var str strings.Builder
 	str.WriteString(fmt.Sprintf("Query: %s\n", r.explain.Query.SQL))
 	str.WriteString(fmt.Sprintf("grade: %0.2f/%0.2f\n", r.grade, grade.MaxGrade))
+	if len(r.explain.Query.Connection) != 0 {
+		str.WriteString(fmt.Sprintf("Connection: %s\n", r.explain.Query.Connection))
+	}
+	if r.explain.Query.Time != 0 {
+		str.WriteString(fmt.Sprintf("Time: %0.2fms\n", r.explain.Query.Time))
+	}
 
 	if len(r.accessTypeWarning) != 0 {
 		str.WriteString(fmt.Sprintf("Access type: %s\n", r.accessTypeWarning))
@@ -159,7 +291,34 @@ func (r *Result) String() string {
 		str.WriteString(fmt.Sprintf("Like pattern: %s\n", r.likePatternWarning))
 	}
 	if len(r.joinOrderWarning) != 0 {
-		str.WriteString(fmt.Sprintf("Suboptimal join order: %s\n", r.joinOrderWarning))
+		str.WriteString(fmt.Sprintf("Join order: %s\n", r.joinOrderWarning))
+	}
+	if len(r.joinIndexWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Join index: %s\n", r.joinIndexWarning))
+	}
+	if len(r.columnFunctionWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Function on an indexed column: %s\n", r.columnFunctionWarning))
+	}
+	if len(r.typeConversionWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Type conversion: %s\n", r.typeConversionWarning))
+	}
+	if len(r.orConditionWarning) != 0 {
+		str.WriteString(fmt.Sprintf("OR condition: %s\n", r.orConditionWarning))
+	}
+	if len(r.indexMergeWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Index merge: %s\n", r.indexMergeWarning))
+	}
+	if len(r.semiJoinWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Subquery: %s\n", r.semiJoinWarning))
+	}
+	if len(r.removedConditionWarning) != 0 {
+		str.WriteString(fmt.Sprintf("WHERE clause: %s\n", r.removedConditionWarning))
+	}
+	if len(r.nPlusOneWarning) != 0 {
+		str.WriteString(fmt.Sprintf("N+1 queries: %s\n", r.nPlusOneWarning))
+	}
+	if len(r.paginationWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Pagination: %s\n", r.paginationWarning))
 	}
 	if len(r.subqueryInSelectWarning) != 0 {
 		str.WriteString(fmt.Sprintf("Subquery in SELECT: %s\n", r.subqueryInSelectWarning))
@@ -167,34 +326,59 @@ func (r *Result) String() string {
 	if len(r.selectStarWarning) != 0 {
 		str.WriteString(fmt.Sprintf("Select: %s\n", r.selectStarWarning))
 	}
+	if len(r.writeIndexWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Index for the WHERE clause: %s\n", r.writeIndexWarning))
+	}
+	if len(r.deleteLimitWarning) != 0 {
+		str.WriteString(fmt.Sprintf("DELETE without LIMIT: %s\n", r.deleteLimitWarning))
+	}
+	if len(r.lockedRowsWarning) != 0 {
+		str.WriteString(fmt.Sprintf("Locked rows: %s\n", r.lockedRowsWarning))
+	}
 	return str.String()
 }
 
 // checkAccessType checks for and provides information about the access type of a query and other useful information from the EXTRA column
+// The grades and the explanations of the access types are in [accessTypes]. Every row of the plan is graded
+// and the query gets the grade of the worst one: the inner tables of a join and the subqueries never come first.
 func (r *Result) checkAccessType() {
-	switch strings.ToLower(r.explain.QueryType.String) {
-	case "all":
-		r.accessTypeWarning = `The query uses the "ALL" access type. It scans ALL rows from the disk without using an index. It will cause you trouble if you have a large number of records.`
-		r.grade = 1
-	case "index":
-		r.accessTypeWarning = `The query uses the "index" access type. It scans every node in the index BTREE which is pretty inefficient. It will cause you trouble if you have a large number of records. Fortunately, the "Extra" column contains "Using index" which means the query does not run a large number of extra I/O operations.`
-		r.grade = 2
-		if !r.explain.UsingIndex() {
-			r.accessTypeWarning = `Altough your query uses the "index" access type, the "Extra" column does not contain "Using index". It means you effectively do a FULL TABLE SCAN. First, the DB scans the whole BTREE index and then runs I/O operations for each node to satisfy the SELECT statement. It often happens when "SELECT *" is used. It will cause you trouble if you have a large number of records.`
-			r.grade = 1
+	rows := r.explain.rows()
+	graded := false
+	warnings := make([]string, 0)
+	for _, row := range rows {
+		if isTemporaryTable(row) {
+			continue
+		}
+		t, ok := findAccessType(row.QueryType.String)
+		if !ok {
+			// The type is NULL if the row doesn't read a table such as "Impossible WHERE" or "No tables used"
+			continue
+		}
+		g, w := t.grade, t.warning
+		if t.uncoveredGrade != 0 && !hasExtra(row.Extra.String, "Using index") {
+			g, w = t.uncoveredGrade, t.uncoveredWarning
 		}
-	case "range":
-		r.accessTypeWarning = ""
-		r.grade = 4
-		if !r.explain.UsingIndex() {
-			r.accessTypeWarning = `Altough your query uses the "range" access type, the "Extra" column does not contain "Using index". It means you run unnecessary I/O operations. First, the DB scans the BTREE index for matching rows and then it runs I/O operations for each node. It can be slower if you have a large number of records.`
-			r.grade = 3
+		if !graded || g < r.grade {
+			r.grade = g
 		}
-	case "const":
-	case "ref":
-		r.accessTypeWarning = ""
-		r.grade = 5
+		graded = true
+		if len(w) == 0 {
+			continue
+		}
+		if len(rows) > 1 {
+			w = fmt.Sprintf("%s (%s): %s", row.Table.String, row.SelectType.String, w)
+		}
+		warnings = append(warnings, w)
 	}
+	r.accessTypeWarning = strings.Join(warnings, " ")
+}
+
+// isTemporaryTable reports whether a row of the plan reads a table MySQL creates for the query: the result of a UNION,
+// a materialized derived table or subquery. They are read with the ALL type, the rows that fill them are graded on their own.
+func isTemporaryTable(row ExplainRow) bool {
+	table := strings.ToLower(row.Table.String)
+	return strings.EqualFold(row.SelectType.String, "UNION RESULT") ||
+		strings.HasPrefix(table, "<union") || strings.HasPrefix(table, "<derived") || strings.HasPrefix(table, "<subquery")
 }
 
 // checkFilteredRows checks for and provides information about the rows and filtered columns of EXPLAIN
@@ -241,34 +425,6 @@ func (r *Result) checkLikePattern() {
 	}
 }
 
-// checkJoinOrder checks for and provides information about the order of JOIN statements:
-//   - It parses the table names from the query
-//   - Checks which table has how many rows running COUNT queries
-//   - Checks if the tables are ascending order by record count
-func (r *Result) checkJoinOrder(db *sql.DB) error {
-	tables := getJoinedTables(r.explain.Query.SQL)
-	counts := make([]int, len(tables))
-
-	for i, t := range tables {
-		count, err := countRows(db, t)
-		if err != nil {
-			return fmt.Errorf("explainer.analyeJoinOrder: %w", err)
-		}
-		counts[i] = count
-	}
-
-	countsDesc := make([]int, len(counts))
-	slices.SortFunc(countsDesc, func(a, b int) int {
-		return b - a
-	})
-
-	if slices.Compare(counts, countsDesc) != 0 {
-		r.grade = grade.Dec(r.grade, 0.25)
-		r.joinOrderWarning = "Tables in the query might be joined in a suboptimal way. MySQL can perform better if you join smaller tables earlier and larger ones later. If it's possible, of course."
-	}
-	return nil
-}
-
 // checkSubqueryInSelect checks for and provides information about "select users.id, (select ...) as foo" type queries
 func (r *Result) checkSubqueryInSelect() {
 	if r.explain.Query.HasSubqueryInSelect() {
@@ -277,56 +433,6 @@ func (r *Result) checkSubqueryInSelect() {
 	}
 }
 
-// getJoinedTables returns the table names from the JOIN statements
-//
-// For example:
-//
-// select * from users join orders on orders.user_id = users.id join order_items on order_items.order_id = orders.id
-//
-// Returns: {"orders", "order_items"}
-// TODO: can use a SQL parser
-func getJoinedTables(sql string) []string {
-	tables := make([]string, 0)
-	sqlLower := strings.ToLower(sql)
-
-	for {
-		startIdx := strings.Index(sqlLower, "join ")
-		if startIdx == -1 || startIdx+len("join ") >= len(sqlLower) {
-			break
-		}
-
-		startIdx += len("join ")
-		lenTableName := strings.Index(sqlLower[startIdx:], " ")
-		if lenTableName == -1 {
-			break
-		}
-		sqlLower = sqlLower[startIdx:]
-
-		r := strings.NewReader(sqlLower)
-		buf := make([]byte, lenTableName)
-		r.Read(buf)
-		tables = append(tables, string(buf))
-	}
-	return tables
-}
-
-// countRows returns the number of rows in a table
-func countRows(db *sql.DB, table string) (int, error) {
-	rows, err := db.Query(fmt.Sprintf("select count(*) from %s", table))
-	if err != nil {
-		return -1, fmt.Errorf("explainer.CountRows: exeuting query: %w", err)
-	}
-	defer rows.Close()
-
-	var count int
-	if rows.Next() {
-		if err := rows.Scan(&count); err != nil {
-			return -1, fmt.Errorf("explainer.CountRows: scanning count: %w", err)
-		}
-	}
-	return count, nil
-}
-
 func newResult(expl ExplainResult) *Result {
 	return &Result{
 		explain: expl,
@@ -360,7 +466,13 @@ func (q Query) HasSelectStar() bool {
 }
 
 func (q Query) HasSubqueryInSelect() bool {
+	if !strings.HasPrefix(strings.ToLower(q.SQL), "select") {
+		return false
+	}
 	fromIdx := strings.Index(strings.ToLower(q.SQL), "from")
+	if fromIdx < len("select") {
+		return false
+	}
 	sql := strings.ToLower(q.SQL[len("select"):fromIdx])
 
 	return strings.Contains(sql, "select")
@@ -389,7 +501,18 @@ func (q Query) HasJoins() bool {
 }
 
 func (e ExplainResult) UsingIndex() bool {
-	return strings.Contains(e.Extra.String, "Using index")
+	return hasExtra(e.Extra.String, "Using index")
+}
+
+// hasExtra reports whether one of the items of the Extra column is exactly item
+// "Using index condition" and "Using index for group-by" are not "Using index".
+func hasExtra(extra, item string) bool {
+	for _, e := range strings.Split(extra, ";") {
+		if strings.TrimSpace(e) == item {
+			return true
+		}
+	}
+	return false
 }
 
 func (e ExplainResult) UsingFilesort() bool {
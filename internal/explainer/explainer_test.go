@@ -159,32 +159,116 @@ func TestAnalyzeSubqueryInSelect(t *testing.T) {
 	assert.Equal(t, float32(3), res.Grade())
 }
 
-func TestGetJoinedTables(t *testing.T) {
-	sql := `
-		select *
-		from users
-		join orders on orders.user_id = users.id
-		join order_items on order_items.order_id = orders.id
-	`
-	assert.Equal(t, []string{"orders", "order_items"}, getJoinedTables(sql))
-}
-
-func TestGetJoinedTables_WithAlias(t *testing.T) {
-	sql := `
-		select *
-		from users
-		join orders as o on o.user_id = users.id
-		join order_items as i on i.order_id = o.id
-	`
-	assert.Equal(t, []string{"orders", "order_items"}, getJoinedTables(sql))
-}
-
-func TestGetJoinedTables_WithAliasShort(t *testing.T) {
-	sql := `
-		select *
-		from users
-		join orders o on o.user_id = users.id
-		join order_items i on i.order_id = o.id
-	`
-	assert.Equal(t, []string{"orders", "order_items"}, getJoinedTables(sql))
+func TestAnalyzeAccessType_Others(t *testing.T) {
+	grades := map[string]float32{
+		"system":      5,
+		"fulltext":    4,
+		"ref_or_null": 4,
+		"index_merge": 4,
+	}
+	for queryType, grade := range grades {
+		res := newResult(ExplainResult{QueryType: sql.NullString{String: queryType}})
+		res.checkAccessType()
+		assert.Equal(t, grade, res.Grade(), queryType)
+	}
+}
+
+func TestAnalyzeAccessType_DependentSubquery(t *testing.T) {
+	// select * from users where id in (select user_id from orders where total > ?) or active = 1
+	plan := []ExplainRow{
+		{ID: sql.NullInt64{Int64: 1, Valid: true}, SelectType: sql.NullString{String: "PRIMARY", Valid: true}, Table: sql.NullString{String: "users", Valid: true}, QueryType: sql.NullString{String: "ALL", Valid: true}, Extra: sql.NullString{String: "Using where", Valid: true}},
+		{ID: sql.NullInt64{Int64: 2, Valid: true}, SelectType: sql.NullString{String: "DEPENDENT SUBQUERY", Valid: true}, Table: sql.NullString{String: "orders", Valid: true}, QueryType: sql.NullString{String: "index_subquery", Valid: true}, Key: sql.NullString{String: "idx_user_id", Valid: true}, Extra: sql.NullString{String: "Using where", Valid: true}},
+	}
+	res := newResult(newExplainResult(newQuery("select * from users where id in (select user_id from orders where total > ?) or active = 1"), plan))
+	res.checkAccessType()
+
+	assert.Equal(t, float32(1), res.Grade())
+	assert.Contains(t, res.accessTypeWarning, `users (PRIMARY): The query uses the "ALL" access type`)
+	assert.Contains(t, res.accessTypeWarning, `orders (DEPENDENT SUBQUERY): The query uses the "index_subquery" access type`)
+
+	plan[0].QueryType = sql.NullString{String: "ref", Valid: true}
+	res = newResult(newExplainResult(newQuery("select * from users where id in (select user_id from orders where total > ?) and active = 1"), plan))
+	res.checkAccessType()
+
+	assert.Equal(t, float32(3), res.Grade())
+	assert.NotContains(t, res.accessTypeWarning, "users")
+	assert.Contains(t, res.accessTypeWarning, "index_subquery")
+}
+
+func TestAnalyzeAccessType_UniqueSubquery(t *testing.T) {
+	plan := []ExplainRow{
+		{ID: sql.NullInt64{Int64: 1, Valid: true}, SelectType: sql.NullString{String: "PRIMARY", Valid: true}, Table: sql.NullString{String: "orders", Valid: true}, QueryType: sql.NullString{String: "ref", Valid: true}},
+		{ID: sql.NullInt64{Int64: 2, Valid: true}, SelectType: sql.NullString{String: "DEPENDENT SUBQUERY", Valid: true}, Table: sql.NullString{String: "users", Valid: true}, QueryType: sql.NullString{String: "unique_subquery", Valid: true}, Key: sql.NullString{String: "PRIMARY", Valid: true}},
+	}
+	res := newResult(newExplainResult(newQuery("select * from orders where status = ? and user_id in (select id from users where active = 1)"), plan))
+	res.checkAccessType()
+
+	assert.Equal(t, float32(4), res.Grade())
+	assert.Contains(t, res.accessTypeWarning, "unique_subquery")
+}
+
+func TestAnalyzeAccessType_EqRef(t *testing.T) {
+	plan := []ExplainRow{
+		{ID: sql.NullInt64{Int64: 1, Valid: true}, SelectType: sql.NullString{String: "SIMPLE", Valid: true}, Table: sql.NullString{String: "o", Valid: true}, QueryType: sql.NullString{String: "ref", Valid: true}},
+		{ID: sql.NullInt64{Int64: 1, Valid: true}, SelectType: sql.NullString{String: "SIMPLE", Valid: true}, Table: sql.NullString{String: "u", Valid: true}, QueryType: sql.NullString{String: "eq_ref", Valid: true}, Key: sql.NullString{String: "PRIMARY", Valid: true}},
+	}
+	res := newResult(newExplainResult(newQuery("select * from orders o join users u on u.id = o.user_id where o.status = ?"), plan))
+	res.checkAccessType()
+
+	assert.Equal(t, float32(5), res.Grade())
+	assert.Empty(t, res.accessTypeWarning)
+}
+
+func TestAnalyzeAccessType_Union(t *testing.T) {
+	// select id from users where id = ? union select id from admins where id = ?
+	plan := []ExplainRow{
+		{ID: sql.NullInt64{Int64: 1, Valid: true}, SelectType: sql.NullString{String: "PRIMARY", Valid: true}, Table: sql.NullString{String: "users", Valid: true}, QueryType: sql.NullString{String: "const", Valid: true}, Extra: sql.NullString{String: "Using index", Valid: true}},
+		{ID: sql.NullInt64{Int64: 2, Valid: true}, SelectType: sql.NullString{String: "UNION", Valid: true}, Table: sql.NullString{String: "admins", Valid: true}, QueryType: sql.NullString{String: "const", Valid: true}, Extra: sql.NullString{String: "Using index", Valid: true}},
+		{SelectType: sql.NullString{String: "UNION RESULT", Valid: true}, Table: sql.NullString{String: "<union1,2>", Valid: true}, QueryType: sql.NullString{String: "ALL", Valid: true}, Extra: sql.NullString{String: "Using temporary", Valid: true}},
+	}
+	res := newResult(newExplainResult(newQuery("select id from users where id = ? union select id from admins where id = ?"), plan))
+	res.checkAccessType()
+
+	assert.Equal(t, float32(5), res.Grade())
+	assert.Empty(t, res.accessTypeWarning)
+}
+
+func TestAnalyzeAccessType_DerivedTable(t *testing.T) {
+	// select * from (select user_id, count(*) c from orders where status = ? group by user_id) t
+	plan := []ExplainRow{
+		{ID: sql.NullInt64{Int64: 1, Valid: true}, SelectType: sql.NullString{String: "PRIMARY", Valid: true}, Table: sql.NullString{String: "<derived2>", Valid: true}, QueryType: sql.NullString{String: "ALL", Valid: true}},
+		{ID: sql.NullInt64{Int64: 2, Valid: true}, SelectType: sql.NullString{String: "DERIVED", Valid: true}, Table: sql.NullString{String: "orders", Valid: true}, QueryType: sql.NullString{String: "ref", Valid: true}, Key: sql.NullString{String: "idx_status_user", Valid: true}, Extra: sql.NullString{String: "Using index", Valid: true}},
+	}
+	res := newResult(newExplainResult(newQuery("select * from (select user_id, count(*) c from orders where status = ? group by user_id) t"), plan))
+	res.checkAccessType()
+
+	assert.Equal(t, float32(5), res.Grade())
+	assert.Empty(t, res.accessTypeWarning)
+}
+
+func TestAnalyzeAccessType_IndexCondition(t *testing.T) {
+	for _, extra := range []string{"Using index condition", "Using where; Using index for group-by"} {
+		res := newResult(ExplainResult{
+			QueryType: sql.NullString{String: "range"},
+			Extra:     sql.NullString{String: extra},
+		})
+		res.checkAccessType()
+		assert.Equal(t, float32(3), res.Grade(), extra)
+	}
+
+	res := newResult(ExplainResult{
+		QueryType: sql.NullString{String: "range"},
+		Extra:     sql.NullString{String: "Using where; Using index"},
+	})
+	res.checkAccessType()
+	assert.Equal(t, float32(4), res.Grade())
+}
+
+func TestRules(t *testing.T) {
+	rules := Rules()
+	for _, name := range []string{"system", "const", "eq_ref", "ref", "fulltext", "ref_or_null", "index_merge", "unique_subquery", "index_subquery", "range", "index", "ALL"} {
+		assert.Contains(t, rules, name+" ")
+	}
+	assert.Contains(t, rules, "grade of its worst row")
+	assert.Regexp(t, `(?m)^range\s+4\.00\s+3\.00\s+A range of an index`, rules)
 }
@@ -0,0 +1,63 @@
+package explainer
+
+import (
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+// Fingerprint returns the normalized form of the query that is the same for every execution of it
+//
+// For example:
+//
+// SELECT * FROM `users` WHERE id IN (1, 2, 3) AND name = 'John' -- comment
+//
+// Returns: select * from users where id in (?+) and name = ?
+//
+// Keywords and identifiers are lowercased, comments and extra whitespace are removed, literals become ?
+// and lists of placeholders such as IN (?,?,?) become (?+) so the number of values doesn't matter.
+func (q Query) Fingerprint() string {
+	return fingerprint(q.SQL)
+}
+
+func fingerprint(sql string) string {
+	parts := make([]string, 0)
+	for _, t := range sqllexer.Tokenize(sql) {
+		switch t.Kind {
+		case sqllexer.String, sqllexer.Number:
+			parts = append(parts, "?")
+		case sqllexer.Word, sqllexer.QuotedIdent:
+			parts = append(parts, strings.ToLower(t.Name()))
+		default:
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(collapsePlaceholderLists(parts), " ")
+}
+
+// collapsePlaceholderLists replaces "( ? , ? , ? )" with "(?+)"
+func collapsePlaceholderLists(parts []string) []string {
+	res := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		if parts[i] != "(" {
+			res = append(res, parts[i])
+			continue
+		}
+		end := i + 1
+		for end < len(parts) && parts[end] == "?" {
+			if end+1 < len(parts) && parts[end+1] == "," {
+				end += 2
+				continue
+			}
+			end++
+			break
+		}
+		if end > i+1 && end < len(parts) && parts[end-1] == "?" && parts[end] == ")" {
+			res = append(res, "(?+)")
+			i = end
+			continue
+		}
+		res = append(res, parts[i])
+	}
+	return res
+}
@@ -0,0 +1,23 @@
+package explainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select * from `users` where `id` = ?", "select * from users where id = ?"},
+		{"SELECT *  FROM users\n WHERE id = 10", "select * from users where id = ?"},
+		{"select * from users where name = 'John' -- comment", "select * from users where name = ?"},
+		{"select * from users where id in (?, ?, ?)", "select * from users where id in (?+)"},
+		{"select * from users where id in (1,2)", "select * from users where id in (?+)"},
+		{"select count(*) from users where id in (select user_id from orders)", "select count ( * ) from users where id in ( select user_id from orders )"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, newQuery(tt.sql).Fingerprint())
+	}
+}
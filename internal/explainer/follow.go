@@ -0,0 +1,314 @@
+package explainer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mmartinjoo/explainer/internal/platform"
+)
+
+const (
+	// followPollInterval is how often the log file is checked for new lines in follow mode
+	followPollInterval = 250 * time.Millisecond
+	// followIdleTimeout is how long follow mode waits for more lines on stdin before it explains the last entry.
+	// A statement spanning multiple lines is usually written at once so a pause means it's complete.
+	followIdleTimeout = time.Second
+)
+
+type (
+	// tailer reads the lines appended to a file like `tail -F` does
+	tailer struct {
+		path string
+		// f is nil while the file doesn't exist, for example between a rotation and the creation of the new file
+		f      *os.File
+		offset int64
+		// partial is the last line of the file which is not terminated yet
+		partial []byte
+	}
+
+	// stdinLine is a line read from stdin. err is set on the last line, io.EOF if stdin was closed
+	stdinLine struct {
+		text string
+		size int
+		err  error
+	}
+
+	// findings remembers the grade of every fingerprint that has been printed in follow mode
+	findings struct {
+		seen map[string]float32
+		opts Options
+	}
+)
+
+// Follow is the entrypoint of follow mode:
+//   - Waits for new lines at the end of the log file
+//   - Parses and explains the new queries as they arrive
+//   - Prints a result only the first time a query is seen or when its grade changes
+//
+// It runs until ctx is canceled. Rotated and truncated log files are followed the same way as `tail -F`.
+func Follow(ctx context.Context, db *sql.DB, opts Options, logFilePath string) error {
+	if logFilePath == stdinPath {
+		return followStdin(ctx, db, opts, os.Stdin)
+	}
+
+	t, err := newTailer(logFilePath)
+	if err != nil {
+		return fmt.Errorf("explainer.Follow: %w", err)
+	}
+	defer t.close()
+
+	log.Printf("Following %s. New queries are analyzed as they arrive...\n", logFilePath)
+
+	seen := findings{seen: make(map[string]float32), opts: opts}
+	for {
+		lines, err := t.lines()
+		if err != nil {
+			return fmt.Errorf("explainer.Follow: %w", err)
+		}
+		if len(lines) != 0 && opts.isText() {
+			// A statement spans multiple lines if it's written at once
+			if lines, err = readQueries(strings.NewReader(strings.Join(lines, "\n"))); err != nil {
+				return fmt.Errorf("explainer.Follow: %w", err)
+			}
+		}
+		if len(lines) != 0 {
+			if err := seen.explain(ctx, db, lines); err != nil {
+				return fmt.Errorf("explainer.Follow: %w", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// followStdin explains the queries piped into the tool as they arrive, for example: kubectl logs -f app | myexplainer logs --follow -
+//
+// Text logs are joined into entries the same way as files are. An entry is explained once the next one starts
+// or when no line arrives for [followIdleTimeout]. The lines of the other formats are explained after a pause.
+func followStdin(ctx context.Context, db *sql.DB, opts Options, stdin io.Reader) error {
+	log.Println("Following stdin. New queries are analyzed as they arrive...")
+
+	lines := make(chan stdinLine)
+	go readStdin(ctx, stdin, lines)
+
+	seen := findings{seen: make(map[string]float32), opts: opts}
+	er := newEntryReader()
+	pending := make([]string, 0)
+	explain := func() error {
+		if opts.isText() {
+			er.flush()
+		}
+		entries := append(pending, er.take()...)
+		pending = make([]string, 0)
+		if len(entries) == 0 {
+			return nil
+		}
+		return seen.explain(ctx, db, entries)
+	}
+
+	lineNo := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followIdleTimeout):
+			if err := explain(); err != nil {
+				return fmt.Errorf("explainer.followStdin: %w", err)
+			}
+		case l := <-lines:
+			lineNo++
+			if l.err != nil && !errors.Is(l.err, io.EOF) {
+				return fmt.Errorf("explainer.followStdin: line %d: %w", lineNo, l.err)
+			}
+			switch {
+			case l.size == 0 && l.err != nil:
+			case opts.isText():
+				er.add(l.text, l.size, lineNo)
+			case l.size > maxEntryBytes:
+				log.Printf("line %d: skipping a line of %d bytes because it's longer than the limit of %d bytes: %.100s...\n", lineNo, l.size, maxEntryBytes, l.text)
+			default:
+				pending = append(pending, l.text)
+			}
+			// The entries before the current one are complete
+			if entries := er.take(); len(entries) != 0 {
+				if err := seen.explain(ctx, db, entries); err != nil {
+					return fmt.Errorf("explainer.followStdin: %w", err)
+				}
+			}
+			if l.err != nil {
+				if err := explain(); err != nil {
+					return fmt.Errorf("explainer.followStdin: %w", err)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// readStdin sends the lines of r until it's closed or ctx is canceled
+// The read blocks so it runs in its own goroutine and followStdin can stop on Ctrl+C without waiting for a line.
+func readStdin(ctx context.Context, r io.Reader, lines chan<- stdinLine) {
+	br := bufio.NewReader(r)
+	for {
+		text, size, err := readLine(br)
+		select {
+		case lines <- stdinLine{text: text, size: size, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// explain parses, explains and checks the queries of the given log entries then prints the new findings
+func (f findings) explain(ctx context.Context, db *sql.DB, lines []string) error {
+	// Skipped lines are not reported in follow mode, most lines of an application log are not queries
+	queries, _, err := f.opts.parse(lines)
+	if err != nil {
+		// A malformed line shouldn't stop follow mode
+		log.Println(err)
+		return nil
+	}
+
+	explains, failures, err := runExplainQueries(ctx, db, queries, f.opts)
+	if err != nil && !errors.As(err, &TooManyConnectionsError{}) {
+		return fmt.Errorf("explainer.findings.explain: %w", err)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+	for _, errs := range failures {
+		for _, qErr := range errs {
+			log.Println(qErr)
+		}
+	}
+
+	for _, res := range f.changed(check(ctx, explains, newSchema(db))) {
+		platform.PrintResults(&res)
+	}
+	return nil
+}
+
+// changed returns the results whose fingerprint hasn't been seen yet or whose grade is different since the last time
+func (f findings) changed(results []Result) []Result {
+	res := make([]Result, 0)
+	for _, r := range results {
+		fp := r.explain.Query.Fingerprint()
+		if g, ok := f.seen[fp]; ok && g == r.grade {
+			continue
+		}
+		f.seen[fp] = r.grade
+		res = append(res, r)
+	}
+	return res
+}
+
+// newTailer opens the file and starts reading it at the end so only new lines are returned
+func newTailer(path string) (*tailer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("explainer.newTailer: %w", err)
+	}
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("explainer.newTailer: %w", err)
+	}
+	return &tailer{
+		path:   path,
+		f:      f,
+		offset: offset,
+	}, nil
+}
+
+// lines returns the complete lines written to the file since the last call
+//
+// If the file was truncated it's read again from the beginning.
+// If it was rotated (the path points to a different file or to nothing) the old file is read to the end
+// and the next call continues with the new file from the beginning.
+func (t *tailer) lines() ([]string, error) {
+	if t.f == nil {
+		f, err := os.Open(t.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("explainer.tailer.lines: %w", err)
+		}
+		t.f, t.offset, t.partial = f, 0, nil
+	}
+
+	info, err := t.f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("explainer.tailer.lines: %w", err)
+	}
+	if info.Size() < t.offset {
+		if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("explainer.tailer.lines: %w", err)
+		}
+		t.offset, t.partial = 0, nil
+	}
+
+	lines, err := t.read()
+	if err != nil {
+		return nil, fmt.Errorf("explainer.tailer.lines: %w", err)
+	}
+
+	pathInfo, err := os.Stat(t.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return lines, fmt.Errorf("explainer.tailer.lines: %w", err)
+	}
+	if err != nil || !os.SameFile(info, pathInfo) {
+		// The rotated file won't be written anymore so its last line is complete
+		if len(t.partial) != 0 {
+			lines = append(lines, string(t.partial))
+		}
+		t.close()
+	}
+	return lines, nil
+}
+
+// read reads the file to the end and returns the complete lines
+func (t *tailer) read() ([]string, error) {
+	buf, err := io.ReadAll(t.f)
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(buf))
+
+	data := append(t.partial, buf...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end == -1 {
+		t.partial = data
+		return nil, nil
+	}
+	t.partial = bytes.Clone(data[end+1:])
+
+	lines := strings.Split(string(data[:end]), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines, nil
+}
+
+func (t *tailer) close() {
+	if t.f != nil {
+		t.f.Close()
+	}
+	t.f, t.partial = nil, nil
+}
@@ -0,0 +1,132 @@
+package explainer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.log")
+	assert.Nil(t, os.WriteFile(path, []byte("select * from `old`\n"), 0644))
+
+	tail, err := newTailer(path)
+	assert.Nil(t, err)
+	defer tail.close()
+
+	// Existing lines are skipped
+	lines, err := tail.lines()
+	assert.Nil(t, err)
+	assert.Empty(t, lines)
+
+	appendFile(t, path, "select * from `users`\nselect * from `po")
+	lines, err = tail.lines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"select * from `users`"}, lines)
+
+	appendFile(t, path, "sts`\r\n")
+	lines, err = tail.lines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"select * from `posts`"}, lines)
+}
+
+func TestTailer_Truncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.log")
+	assert.Nil(t, os.WriteFile(path, []byte("select * from `old`\nselect * from `older`\n"), 0644))
+
+	tail, err := newTailer(path)
+	assert.Nil(t, err)
+	defer tail.close()
+
+	assert.Nil(t, os.WriteFile(path, []byte("select * from `new`\n"), 0644))
+	lines, err := tail.lines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"select * from `new`"}, lines)
+}
+
+func TestTailer_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "queries.log")
+	assert.Nil(t, os.WriteFile(path, nil, 0644))
+
+	tail, err := newTailer(path)
+	assert.Nil(t, err)
+	defer tail.close()
+
+	appendFile(t, path, "select * from `users`\nselect * from `posts`")
+	assert.Nil(t, os.Rename(path, filepath.Join(dir, "queries.log.1")))
+
+	// The rotated file is read to the end
+	lines, err := tail.lines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"select * from `users`", "select * from `posts`"}, lines)
+
+	// Nothing happens until the new file is created
+	lines, err = tail.lines()
+	assert.Nil(t, err)
+	assert.Empty(t, lines)
+
+	assert.Nil(t, os.WriteFile(path, []byte("select * from `comments`\n"), 0644))
+	lines, err = tail.lines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"select * from `comments`"}, lines)
+}
+
+func TestFindingsChanged(t *testing.T) {
+	seen := findings{seen: make(map[string]float32)}
+	users := Result{explain: ExplainResult{Query: newQuery("select * from users where id = 1")}, grade: 5}
+	sameUsers := Result{explain: ExplainResult{Query: newQuery("select * from users where id = 2")}, grade: 5}
+	posts := Result{explain: ExplainResult{Query: newQuery("select * from posts")}, grade: 1}
+
+	assert.Len(t, seen.changed([]Result{users, posts}), 2)
+	assert.Empty(t, seen.changed([]Result{sameUsers, posts}))
+
+	// The grade of posts changed, for example after adding an index
+	posts.grade = 4
+	changed := seen.changed([]Result{users, posts})
+	assert.Len(t, changed, 1)
+	assert.Equal(t, float32(4), changed[0].grade)
+}
+
+func appendFile(t *testing.T, path, content string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	assert.Nil(t, err)
+}
+
+func TestFollowStdin_Cancel(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- followStdin(ctx, nil, Options{}, r)
+	}()
+	// Not a statement so nothing is explained
+	_, err := w.Write([]byte("GET /users 200\n"))
+	assert.Nil(t, err)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("followStdin didn't stop while waiting for a line")
+	}
+}
+
+func TestReadStdin(t *testing.T) {
+	lines := make(chan stdinLine)
+	go readStdin(context.Background(), strings.NewReader("select *\nfrom users"), lines)
+
+	assert.Equal(t, stdinLine{text: "select *", size: 8}, <-lines)
+	assert.Equal(t, stdinLine{text: "from users", size: 10, err: io.EOF}, <-lines)
+}
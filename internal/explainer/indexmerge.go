@@ -0,0 +1,325 @@
+package explainer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+// indexMergeRowsLimit is the estimated number of rows below which merging the rows of more than one index is cheap enough
+const indexMergeRowsLimit = 1000
+
+var (
+	// indexMergeExtra is the Extra of an index_merge plan such as: Using union(idx_email,idx_phone); Using where
+	// The algorithms can be nested: Using union(intersect(idx_a,idx_b),idx_c)
+	indexMergeExtra = regexp.MustCompile(`Using (sort_union|union|intersect)\((.*)\)`)
+
+	// indexMergeName is an index name in the Extra of an index_merge plan
+	indexMergeName = regexp.MustCompile(`[^(),\s]+`)
+
+	// whereClauseEnd are the words that end a WHERE clause
+	whereClauseEnd = []string{"group", "order", "limit", "having", "window", "for", "union", "lock"}
+)
+
+// checkOrConditions checks for OR conditions across different columns that make MySQL read the whole table
+//
+// For example:
+//
+// select * from users where email = ? or phone = ?
+//
+// An index on email can't find the rows where phone matches and the other way around. MySQL can only use indexes
+// for the condition if every column of the OR has one and it merges them, otherwise it reads every row.
+func (r *Result) checkOrConditions(ctx context.Context, s *schema) {
+	tokens := sqllexer.Tokenize(r.explain.Query.SQL)
+	tables := queryTables(tokens)
+	where := wordAtTop(tokens, "where")
+	if where == -1 || len(tables) == 0 {
+		return
+	}
+	end := clauseEnd(tokens, where)
+	clause := tokens[where+1 : end]
+
+	warnings := make([]string, 0)
+	for _, group := range orGroups(clause) {
+		columns, table, ok := orColumns(tables, group)
+		if !ok {
+			continue
+		}
+		row, ok := r.explain.planRow(table)
+		if !ok || !isFullScan(row) {
+			continue
+		}
+
+		missing := make([]string, 0)
+		if t, ok := s.table(ctx, table.name); ok {
+			for _, c := range columns {
+				if len(t.IndexesOf(c)) == 0 {
+					missing = append(missing, c)
+				}
+			}
+		}
+		text := r.tokensText(group[0][0], group[len(group)-1][len(group[len(group)-1])-1])
+		w := fmt.Sprintf("'%s' compares different columns of %s so no single index can find the rows and MySQL reads all of them.", text, table.name)
+		if len(missing) != 0 {
+			w += fmt.Sprintf(" %s has no index. Add an index on every column of the OR so MySQL can merge the indexes, or", strings.Join(missing, ", "))
+		} else {
+			w += " Every column has an index but the optimizer estimates that merging them is slower than a full scan. Check the selectivity of the conditions, or"
+		}
+		if rewrite, ok := r.unionAllRewrite(tokens, where, end, group); ok {
+			w += fmt.Sprintf(" rewrite it as a UNION ALL in which every part uses its own index: %s", rewrite)
+		} else {
+			w += " rewrite it as a UNION ALL of one query for every condition so every part uses its own index."
+		}
+		warnings = append(warnings, w)
+	}
+	if len(warnings) == 0 {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 1)
+	r.orConditionWarning = strings.Join(warnings, " ")
+}
+
+// checkIndexMerge checks the plans that read more than one index of a table and merge the rows they find
+//
+// An index merge union is the plan of an OR across columns that have separate indexes. It's fine while the conditions
+// match few rows, above that a UNION ALL avoids merging the row IDs. An index merge intersection is the plan of an AND
+// of conditions on columns that have separate indexes, a composite index on the columns finds the rows with one lookup.
+func (r *Result) checkIndexMerge(ctx context.Context, s *schema) {
+	warnings := make([]string, 0)
+	var dec float32
+	for _, row := range r.explain.rows() {
+		if !strings.EqualFold(row.QueryType.String, "index_merge") {
+			continue
+		}
+		m := indexMergeExtra.FindStringSubmatch(row.Extra.String)
+		if m == nil {
+			continue
+		}
+		indexes := make([]string, 0)
+		for _, name := range indexMergeName.FindAllString(m[2], -1) {
+			if !slices.Contains([]string{"union", "sort_union", "intersect"}, name) {
+				indexes = append(indexes, name)
+			}
+		}
+		table := row.Table.String
+		rows := row.NumberOfRows.Int64
+
+		switch m[1] {
+		case "intersect":
+			dec = max(dec, 1)
+			columns := make([]string, 0)
+			if t, ok := s.table(ctx, r.tableName(table)); ok {
+				for _, idx := range indexes {
+					if parts := t.IndexColumns(idx); len(parts) != 0 && !slices.Contains(columns, parts[0]) {
+						columns = append(columns, parts[0])
+					}
+				}
+			}
+			w := fmt.Sprintf("MySQL reads the indexes %s of %s and keeps the rows that all of them find. It's the plan of an AND of conditions on columns that have separate indexes.", strings.Join(indexes, ", "), table)
+			if len(columns) > 1 {
+				w += fmt.Sprintf(" A composite index finds the rows with one lookup: ALTER TABLE %s ADD INDEX (%s). Put the columns compared with = first.", r.tableName(table), strings.Join(columns, ", "))
+			} else {
+				w += " A composite index on the columns of these indexes finds the rows with one lookup. Put the columns compared with = first."
+			}
+			warnings = append(warnings, w)
+		case "union", "sort_union":
+			w := fmt.Sprintf("MySQL reads the indexes %s of %s and merges the rows they find. It's the plan of an OR of conditions on columns that have separate indexes.", strings.Join(indexes, ", "), table)
+			if m[1] == "sort_union" {
+				dec = max(dec, 0.5)
+				w += " The conditions are ranges so the row IDs of every index have to be sorted before they can be merged. A UNION ALL of one query for every condition avoids the sort."
+			} else if rows > indexMergeRowsLimit {
+				dec = max(dec, 0.5)
+				w += fmt.Sprintf(" It estimates %d rows. With that many rows a UNION ALL of one query for every condition is often faster because every part reads its own index and nothing has to be merged.", rows)
+			} else {
+				w += " It's fine while the conditions match few rows."
+			}
+			warnings = append(warnings, w)
+		}
+	}
+	if len(warnings) == 0 {
+		return
+	}
+	r.grade = grade.Dec(r.grade, dec)
+	r.indexMergeWarning = strings.Join(warnings, " ")
+}
+
+// clauseEnd returns the index of the token after the clause that starts at tokens[start]
+func clauseEnd(tokens []sqllexer.Token, start int) int {
+	depth := 0
+	for i := start + 1; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			depth--
+		case depth == 0 && (t.Is(";") || t.Kind == sqllexer.Word && slices.Contains(whereClauseEnd, strings.ToLower(t.Text))):
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+// orGroups returns the OR conditions of a WHERE clause split into their alternatives
+// Every group is an OR at the top of the clause or inside a pair of parentheses. Subqueries are left out.
+func orGroups(tokens []sqllexer.Token) [][][]sqllexer.Token {
+	groups := make([][][]sqllexer.Token, 0)
+	seen := make(map[int]bool)
+	opens := make([]int, 0)
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			opens = append(opens, i)
+		case t.Is(")"):
+			if len(opens) != 0 {
+				opens = opens[:len(opens)-1]
+			}
+		case t.Kind == sqllexer.Word && t.Is("or"):
+			start, end := 0, len(tokens)
+			if len(opens) != 0 {
+				start, end = opens[len(opens)-1]+1, closingParen(tokens, opens[len(opens)-1])
+			}
+			if seen[start] || start >= end || tokens[start].Is("select") {
+				continue
+			}
+			seen[start] = true
+			groups = append(groups, splitAtTop(tokens[start:end], "or"))
+		}
+	}
+	return groups
+}
+
+// splitAtTop splits the tokens at the keyword outside of parentheses
+func splitAtTop(tokens []sqllexer.Token, word string) [][]sqllexer.Token {
+	parts := make([][]sqllexer.Token, 0)
+	depth := 0
+	start := 0
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			depth--
+		case depth == 0 && t.Kind == sqllexer.Word && t.Is(word):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// orColumns returns the columns of an OR group if its alternatives compare different columns of the same table
+// An OR of the same column such as status = 1 OR status = 2 is a list of values that one index can find.
+func orColumns(tables []tableRef, group [][]sqllexer.Token) ([]string, tableRef, bool) {
+	columns := make([]string, 0)
+	var table tableRef
+	sets := make([]string, 0)
+	for _, alternative := range group {
+		set := make([]string, 0)
+		for _, ref := range columnRefs(alternative) {
+			t, ok := resolveTable(tables, ref)
+			if !ok || len(table.name) != 0 && t != table {
+				return nil, tableRef{}, false
+			}
+			table = t
+			column := strings.ToLower(ref.column)
+			if !slices.Contains(set, column) {
+				set = append(set, column)
+			}
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+		if len(set) == 0 {
+			return nil, tableRef{}, false
+		}
+		slices.Sort(set)
+		sets = append(sets, strings.Join(set, ","))
+	}
+	slices.Sort(sets)
+	if len(slices.Compact(sets)) == 1 {
+		return nil, tableRef{}, false
+	}
+	return columns, table, true
+}
+
+// unionAllRewrite returns the query with the OR at the top of its WHERE clause rewritten as a UNION ALL
+//
+// For example:
+//
+// select * from users where email = ? or phone = ?
+//
+// Returns: select * from users where email = ? union all select * from users where phone = ? and (email = ?) is not true
+//
+// Every part leaves out the rows of the parts before it so no row is returned twice. IS NOT TRUE keeps the rows
+// where the earlier condition is NULL. Queries with a GROUP BY, ORDER BY or LIMIT are not rewritten.
+func (r *Result) unionAllRewrite(tokens []sqllexer.Token, where, end int, group [][]sqllexer.Token) (string, bool) {
+	if end != len(tokens) && !tokens[end].Is(";") {
+		return "", false
+	}
+	if len(group) < 2 || group[0][0].Pos != tokens[where+1].Pos {
+		return "", false
+	}
+	last := group[len(group)-1]
+	if last[len(last)-1].Pos != tokens[end-1].Pos {
+		return "", false
+	}
+
+	prefix := r.explain.Query.SQL[:tokens[where].Pos] + tokens[where].Text + " "
+	parts := make([]string, 0, len(group))
+	for i, alternative := range group {
+		part := prefix + r.tokensText(alternative[0], alternative[len(alternative)-1])
+		for _, prev := range group[:i] {
+			part += " and (" + r.tokensText(prev[0], prev[len(prev)-1]) + ") is not true"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " union all "), true
+}
+
+// tokensText returns the text of the query from the first token to the last one as it's written
+func (r *Result) tokensText(first, last sqllexer.Token) string {
+	return r.explain.Query.SQL[first.Pos : last.Pos+len(last.Text)]
+}
+
+// tableName returns the name of the table that the alias in a row of the plan refers to
+func (r *Result) tableName(alias string) string {
+	for _, t := range queryTables(sqllexer.Tokenize(r.explain.Query.SQL)) {
+		if strings.EqualFold(t.alias, alias) || strings.EqualFold(t.name, alias) {
+			return t.name
+		}
+	}
+	return alias
+}
+
+// rows returns the rows of the plan or the first row if the plan is not known
+func (e ExplainResult) rows() []ExplainRow {
+	if len(e.Plan) != 0 {
+		return e.Plan
+	}
+	return []ExplainRow{{
+		SelectType:   e.SelectType,
+		Table:        e.Table,
+		QueryType:    e.QueryType,
+		PossibleKeys: e.PossibleKeys,
+		Key:          e.Key,
+		NumberOfRows: e.NumberOfRows,
+		Filtered:     e.Filtered,
+		Extra:        e.Extra,
+	}}
+}
+
+// planRow returns the row of the plan that reads the table
+func (e ExplainResult) planRow(table tableRef) (ExplainRow, bool) {
+	for _, row := range e.rows() {
+		if strings.EqualFold(row.Table.String, table.alias) || strings.EqualFold(row.Table.String, table.name) {
+			return row, true
+		}
+	}
+	return ExplainRow{}, false
+}
@@ -0,0 +1,98 @@
+package explainer
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const contactsDDL = `CREATE TABLE contacts (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	email varchar(255) NOT NULL,
+	phone varchar(20) NULL,
+	status tinyint NOT NULL,
+	country_id int NOT NULL,
+	PRIMARY KEY (id),
+	KEY idx_email (email),
+	KEY idx_status (status),
+	KEY idx_country (country_id)
+)`
+
+func TestOrGroups(t *testing.T) {
+	tokens := sqllexer.Tokenize("a = 1 and (b = 2 or c = 3) or d in (select x from y where p = 1 or q = 2)")
+	groups := orGroups(tokens)
+	// The OR of the subquery is left out
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "b = 2", expressionText(groups[0][0]))
+	assert.Equal(t, "c = 3", expressionText(groups[0][1]))
+	assert.Len(t, groups[1], 2)
+	assert.Equal(t, "a = 1 and(b = 2 or c = 3)", expressionText(groups[1][0]))
+}
+
+func TestCheckOrConditions(t *testing.T) {
+	s := testSchema(t, contactsDDL)
+	res := newResult(ExplainResult{
+		Query: newQuery("select * from contacts where email = ? or phone = ?"),
+		Plan:  []ExplainRow{{Table: sql.NullString{String: "contacts", Valid: true}, QueryType: sql.NullString{String: "ALL", Valid: true}}},
+	})
+	res.checkOrConditions(context.Background(), s)
+	assert.Contains(t, res.orConditionWarning, "'email = ? or phone = ?' compares different columns of contacts")
+	assert.Contains(t, res.orConditionWarning, "phone has no index.")
+	assert.Contains(t, res.orConditionWarning, "select * from contacts where email = ? union all select * from contacts where phone = ? and (email = ?) is not true")
+	assert.Equal(t, float32(4), res.Grade())
+
+	// The same column is a list of values
+	res = newResult(ExplainResult{
+		Query: newQuery("select * from contacts where status = 1 or status = 2"),
+		Plan:  []ExplainRow{{Table: sql.NullString{String: "contacts", Valid: true}, QueryType: sql.NullString{String: "ALL", Valid: true}}},
+	})
+	res.checkOrConditions(context.Background(), s)
+	assert.Empty(t, res.orConditionWarning)
+
+	// An index can be used
+	res = newResult(ExplainResult{
+		Query: newQuery("select * from contacts c where c.country_id = 36 and (c.email = ? or c.phone = ?) order by id"),
+		Plan:  []ExplainRow{{Table: sql.NullString{String: "c", Valid: true}, QueryType: sql.NullString{String: "ref", Valid: true}}},
+	})
+	res.checkOrConditions(context.Background(), s)
+	assert.Empty(t, res.orConditionWarning)
+}
+
+func TestCheckIndexMerge(t *testing.T) {
+	s := testSchema(t, contactsDDL)
+	res := newResult(ExplainResult{
+		Query:        newQuery("select * from contacts where status = 1 and country_id = 36"),
+		Table:        sql.NullString{String: "contacts", Valid: true},
+		QueryType:    sql.NullString{String: "index_merge", Valid: true},
+		NumberOfRows: sql.NullInt64{Int64: 120, Valid: true},
+		Extra:        sql.NullString{String: "Using intersect(idx_status,idx_country); Using where", Valid: true},
+	})
+	res.checkIndexMerge(context.Background(), s)
+	assert.Contains(t, res.indexMergeWarning, "MySQL reads the indexes idx_status, idx_country of contacts and keeps the rows that all of them find.")
+	assert.Contains(t, res.indexMergeWarning, "ALTER TABLE contacts ADD INDEX (status, country_id)")
+	assert.Equal(t, float32(4), res.Grade())
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from contacts where email = ? or status = ?"),
+		Table:        sql.NullString{String: "contacts", Valid: true},
+		QueryType:    sql.NullString{String: "index_merge", Valid: true},
+		NumberOfRows: sql.NullInt64{Int64: 12, Valid: true},
+		Extra:        sql.NullString{String: "Using union(idx_email,idx_status); Using where", Valid: true},
+	})
+	res.checkIndexMerge(context.Background(), s)
+	assert.Contains(t, res.indexMergeWarning, "It's fine while the conditions match few rows.")
+	assert.Equal(t, float32(5), res.Grade())
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from contacts where email > ? or status > ?"),
+		Table:        sql.NullString{String: "contacts", Valid: true},
+		QueryType:    sql.NullString{String: "index_merge", Valid: true},
+		NumberOfRows: sql.NullInt64{Int64: 12, Valid: true},
+		Extra:        sql.NullString{String: "Using sort_union(idx_email,idx_status); Using where", Valid: true},
+	})
+	res.checkIndexMerge(context.Background(), s)
+	assert.Contains(t, res.indexMergeWarning, "the row IDs of every index have to be sorted")
+	assert.Equal(t, float32(4.5), res.Grade())
+}
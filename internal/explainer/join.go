@@ -0,0 +1,128 @@
+package explainer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+)
+
+const (
+	// joinCombinationsLimit is the estimated number of row combinations above which a join is considered expensive
+	joinCombinationsLimit = 100_000
+	// joinScanRowsLimit is the number of rows below which scanning the inner table of a join is cheap enough
+	joinScanRowsLimit = 100
+)
+
+// checkJoinOrder reports the order in which the optimizer joins the tables when the order is a problem
+//
+// MySQL joins tables with nested loops: for every row of the first table it looks up the matching rows
+// of the second one, and so on. The order of the EXPLAIN rows is the order the optimizer chose, not the one in the query.
+// The rows of an inner table are estimated for a single row of the tables before it so their product
+// is the number of row combinations the join produces.
+//
+// The order is reported if the join produces too many row combinations, if an inner table can't use an index
+// or if the join has more tables than optimizer_search_depth so the optimizer didn't compare every order.
+func (r *Result) checkJoinOrder(ctx context.Context, s *schema) {
+	tables := r.explain.joinedTables()
+	if len(tables) < 2 {
+		return
+	}
+
+	steps := make([]string, 0, len(tables))
+	combinations := 1.0
+	scanned := make([]string, 0)
+	for i, t := range tables {
+		rows := estimatedRows(t)
+		combinations *= max(rows, 1)
+		access := t.QueryType.String
+		if t.Key.Valid {
+			access += " on " + t.Key.String
+		}
+		if i == 0 {
+			steps = append(steps, fmt.Sprintf("%s (~%.0f rows, %s)", t.Table.String, rows, access))
+			continue
+		}
+		steps = append(steps, fmt.Sprintf("%s (~%.0f rows for each row before it, %s)", t.Table.String, rows, access))
+		if scansInnerTable(t) {
+			scanned = append(scanned, t.Table.String)
+		}
+	}
+
+	problems := make([]string, 0)
+	if combinations > joinCombinationsLimit {
+		r.grade = grade.Dec(r.grade, 0.25)
+		problems = append(problems, fmt.Sprintf("The join starts with %s, every row of it that matches the WHERE clause is joined with the other tables. A more selective condition or an index on the filtered columns of the first table makes the whole join cheaper.", tables[0].Table.String))
+	}
+	if len(scanned) != 0 {
+		// checkJoinIndex lowers the grade for these tables
+		problems = append(problems, fmt.Sprintf("%s can't use an index so it's scanned for every row of the tables before it.", strings.Join(scanned, ", ")))
+	}
+	if depth := s.searchDepth(ctx); len(tables) > depth {
+		r.grade = grade.Dec(r.grade, 0.25)
+		problems = append(problems, fmt.Sprintf("The join has %d tables but optimizer_search_depth is %d so the optimizer didn't compare every join order and this one might not be the cheapest. Use STRAIGHT_JOIN or a JOIN_ORDER hint if you know a better order.", len(tables), depth))
+	}
+	if len(problems) == 0 {
+		return
+	}
+	r.joinOrderWarning = fmt.Sprintf("MySQL joins the tables in this order: %s. It estimates about %.0f row combinations. %s", strings.Join(steps, " -> "), combinations, strings.Join(problems, " "))
+}
+
+// checkJoinIndex checks for inner tables of a join that can't use an index to look up the matching rows
+// For every row of the tables before it MySQL scans the whole inner table, usually through a join buffer.
+func (r *Result) checkJoinIndex() {
+	tables := r.explain.joinedTables()
+	if len(tables) < 2 {
+		return
+	}
+
+	scanned := make([]string, 0)
+	for _, t := range tables[1:] {
+		if scansInnerTable(t) {
+			scanned = append(scanned, fmt.Sprintf("%s (~%d rows)", t.Table.String, t.NumberOfRows.Int64))
+		}
+	}
+	if len(scanned) == 0 {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 2)
+	r.joinIndexWarning = fmt.Sprintf("No index can be used to look up the matching rows of %s. MySQL reads every row of these tables for every row of the tables joined before them. Add an index on the columns of their ON clause.", strings.Join(scanned, ", "))
+}
+
+// scansInnerTable reports whether an inner table of a join is scanned instead of looked up with an index
+// Scanning a table with no more than [joinScanRowsLimit] rows is cheap enough and is not reported.
+func scansInnerTable(t ExplainRow) bool {
+	// Batched Key Access uses a join buffer with an index lookup, Block Nested Loop and hash joins scan the table
+	extra := strings.ToLower(t.Extra.String)
+	fullScan := isFullScan(t) || (strings.Contains(extra, "join buffer") && !strings.Contains(extra, "batched key access"))
+	return fullScan && t.NumberOfRows.Int64 > joinScanRowsLimit
+}
+
+// joinedTables returns the rows of the plan that take part in the join of the outermost SELECT in the order they are joined
+// Subqueries, derived tables and the rest of a UNION have a different id.
+func (e ExplainResult) joinedTables() []ExplainRow {
+	rows := make([]ExplainRow, 0, len(e.Plan))
+	for _, row := range e.Plan {
+		// A plan without a table such as "Impossible WHERE" has NULL in the table column
+		if row.ID.Valid && row.ID.Int64 == int64(e.ID) && row.Table.Valid {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// isFullScan reports whether a table is read from the beginning to the end, either the table itself or a whole index
+func isFullScan(row ExplainRow) bool {
+	t := strings.ToLower(row.QueryType.String)
+	return t == "all" || t == "index"
+}
+
+// estimatedRows returns the number of rows that are read from a table and match the conditions on it
+func estimatedRows(row ExplainRow) float64 {
+	filtered := 100.0
+	if row.Filtered.Valid {
+		filtered = row.Filtered.Float64
+	}
+	return float64(row.NumberOfRows.Int64) * filtered / 100
+}
@@ -0,0 +1,90 @@
+package explainer
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func explainRow(id int64, table, queryType, key string, rows int64, extra string) ExplainRow {
+	return ExplainRow{
+		ID:           sql.NullInt64{Int64: id, Valid: true},
+		SelectType:   sql.NullString{String: "SIMPLE", Valid: true},
+		Table:        sql.NullString{String: table, Valid: true},
+		QueryType:    sql.NullString{String: queryType, Valid: true},
+		Key:          sql.NullString{String: key, Valid: key != ""},
+		NumberOfRows: sql.NullInt64{Int64: rows, Valid: true},
+		Filtered:     sql.NullFloat64{Float64: 100, Valid: true},
+		Extra:        sql.NullString{String: extra, Valid: extra != ""},
+	}
+}
+
+func TestCheckJoinOrder(t *testing.T) {
+	q := newQuery("select * from orders o join users u on u.id = o.user_id where u.country = ?")
+	res := newResult(newExplainResult(q, []ExplainRow{
+		explainRow(1, "u", "ref", "idx_country", 200, "Using index condition"),
+		explainRow(1, "o", "ref", "idx_user_id", 5, ""),
+		// A subquery isn't part of the join
+		explainRow(2, "p", "ALL", "", 100000, ""),
+	}))
+	res.checkJoinOrder(context.Background(), newSchema(nil))
+	res.checkJoinIndex()
+
+	assert.Empty(t, res.joinOrderWarning)
+	assert.Empty(t, res.joinIndexWarning)
+	assert.Equal(t, float32(5), res.Grade())
+}
+
+func TestCheckJoinOrder_ManyCombinations(t *testing.T) {
+	q := newQuery("select * from orders o join order_items i on i.order_id = o.id")
+	res := newResult(newExplainResult(q, []ExplainRow{
+		explainRow(1, "o", "ALL", "", 50000, ""),
+		explainRow(1, "i", "ref", "idx_order_id", 4, ""),
+	}))
+	res.checkJoinOrder(context.Background(), newSchema(nil))
+
+	assert.Contains(t, res.joinOrderWarning, "about 200000 row combinations. The join starts with o")
+	assert.Equal(t, float32(4.75), res.Grade())
+}
+
+func TestCheckJoinIndex(t *testing.T) {
+	q := newQuery("select * from users u join orders o on o.email = u.email join countries c on c.code = u.country")
+	res := newResult(newExplainResult(q, []ExplainRow{
+		explainRow(1, "u", "ALL", "", 1000, ""),
+		explainRow(1, "o", "ALL", "", 80000, "Using where; Using join buffer (hash join)"),
+		explainRow(1, "c", "ALL", "", 20, "Using where; Using join buffer (hash join)"),
+	}))
+	res.checkJoinOrder(context.Background(), newSchema(nil))
+	res.checkJoinIndex()
+
+	assert.Equal(t, "MySQL joins the tables in this order: u (~1000 rows, ALL) -> o (~80000 rows for each row before it, ALL) -> c (~20 rows for each row before it, ALL). It estimates about 1600000000 row combinations. The join starts with u, every row of it that matches the WHERE clause is joined with the other tables. A more selective condition or an index on the filtered columns of the first table makes the whole join cheaper. o can't use an index so it's scanned for every row of the tables before it.", res.joinOrderWarning)
+	assert.Contains(t, res.joinIndexWarning, "No index can be used to look up the matching rows of o (~80000 rows).")
+	assert.Equal(t, float32(2.75), res.Grade())
+}
+
+func TestCheckJoinOrder_SearchDepth(t *testing.T) {
+	q := newQuery("select * from orders o join users u on u.id = o.user_id join countries c on c.id = u.country_id")
+	res := newResult(newExplainResult(q, []ExplainRow{
+		explainRow(1, "o", "range", "idx_created_at", 20, ""),
+		explainRow(1, "u", "eq_ref", "PRIMARY", 1, ""),
+		explainRow(1, "c", "eq_ref", "PRIMARY", 1, ""),
+	}))
+	s := newSchema(nil)
+	s.depth = 2
+	res.checkJoinOrder(context.Background(), s)
+
+	assert.Contains(t, res.joinOrderWarning, "The join has 3 tables but optimizer_search_depth is 2")
+	assert.Equal(t, float32(4.75), res.Grade())
+}
+
+func TestCheckJoinIndex_SingleTable(t *testing.T) {
+	res := newResult(newExplainResult(newQuery("select * from users"), []ExplainRow{
+		explainRow(1, "users", "ALL", "", 1000, ""),
+	}))
+	res.checkJoinOrder(context.Background(), newSchema(nil))
+	res.checkJoinIndex()
+
+	assert.Empty(t, res.joinOrderWarning)
+	assert.Empty(t, res.joinIndexWarning)
+}
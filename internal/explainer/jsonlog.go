@@ -0,0 +1,293 @@
+package explainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONPaths tells where the fields of a query are in a JSON log entry
+//
+// A path is a dot-separated list of keys such as "context.bindings". Array elements are addressed by their index: "args.0".
+// Every field can have multiple paths, the first one that exists is used.
+type JSONPaths struct {
+	SQL        []string
+	Bindings   []string
+	Time       []string
+	Connection []string
+	// Timestamp is the time the entry was logged as a date string or as seconds since the epoch
+	Timestamp []string
+}
+
+// DefaultJSONPaths covers the field names Monolog (Laravel), Logrus, Zap and Bunyan loggers usually use
+var DefaultJSONPaths = JSONPaths{
+	SQL:        []string{"message", "msg", "sql", "query", "context.sql", "context.query"},
+	Bindings:   []string{"context.bindings", "bindings", "args", "params"},
+	Time:       []string{"context.time", "duration", "elapsed", "time_ms"},
+	Connection: []string{"context.connection", "connection", "database", "db"},
+	Timestamp:  []string{"datetime", "timestamp", "@timestamp", "time", "ts"},
+}
+
+// ParseJSONPath splits a comma-separated list of paths such as "message,msg"
+func ParseJSONPath(s string) []string {
+	paths := make([]string, 0)
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); len(p) != 0 {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// isText reports whether the logs are text logs whose statements can span multiple lines
+func (o Options) isText() bool {
+	return o.Format == "" || o.Format == FormatText
+}
+
+// parse turns log entries into unique SELECT queries based on the format
+// It also returns the number of entries that were skipped by the reason they were skipped.
+func (o Options) parse(logs []string) ([]Query, skipStats, error) {
+	var queries []Query
+	skipped := make(skipStats)
+	switch o.Format {
+	case "", FormatText:
+		queries = parseLogs(logs, o.IncludeWrites, skipped)
+	case FormatJSONL:
+		queries = parseJSONLogs(logs, o.JSONPaths, o.IncludeWrites, skipped)
+	default:
+		preset, ok := presets[o.Format]
+		if !ok {
+			return nil, nil, fmt.Errorf("explainer.Options.parse: unknown log format: %s", o.Format)
+		}
+		queries = uniqueQueries(preset(logs, o.IncludeWrites, skipped))
+	}
+
+	if o.Parameterize {
+		for i, q := range queries {
+			queries[i] = parameterize(q)
+		}
+	}
+	return queries, skipped, nil
+}
+
+// skipInvalidJSON is the reason of JSON log lines that are not JSON objects
+const skipInvalidJSON = "invalid JSON"
+
+// parseJSONLogs turns JSON log lines into unique SELECT queries
+//
+// Lines that are not JSON objects or don't contain a query are skipped. Bindings keep their JSON types
+// so numbers are bound as numbers and strings as strings.
+func parseJSONLogs(lines []string, paths JSONPaths, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(line, "{") {
+			skipped.skip(skipInvalidJSON)
+			continue
+		}
+
+		d := json.NewDecoder(strings.NewReader(line))
+		d.UseNumber()
+		var entry map[string]any
+		if err := d.Decode(&entry); err != nil {
+			log.Printf("skipping invalid JSON log entry: %s: %.100s\n", err, line)
+			skipped.skip(skipInvalidJSON)
+			continue
+		}
+
+		q, ok := jsonQuery(entry, paths, writes, skipped)
+		if !ok {
+			continue
+		}
+		queries = append(queries, q)
+	}
+	return uniqueQueries(queries)
+}
+
+// jsonQuery returns the SELECT query of a JSON log entry
+func jsonQuery(entry map[string]any, paths JSONPaths, writes bool, skipped skipStats) (Query, bool) {
+	var sql string
+	for _, p := range paths.SQL {
+		v, ok := lookupJSON(entry, p)
+		if s, isString := v.(string); ok && isString {
+			if _, kind := locateStatement(s); kind != statementNone {
+				sql = s
+				break
+			}
+		}
+	}
+	if len(sql) == 0 {
+		skipped.skip(statementNone.String())
+		return Query{}, false
+	}
+
+	var positional []any
+	var named map[string]any
+	if v, ok := lookupFirst(entry, paths.Bindings); ok {
+		switch bindings := v.(type) {
+		case []any:
+			for _, b := range bindings {
+				positional = append(positional, jsonBinding(b))
+			}
+		case map[string]any:
+			named = make(map[string]any)
+			for k, b := range bindings {
+				named[k] = jsonBinding(b)
+			}
+		}
+	}
+	q, ok := selectQuery(sql, positional, named, writes, skipped)
+	if !ok {
+		return Query{}, false
+	}
+	if v, ok := lookupFirst(entry, paths.Time); ok {
+		if n, isNumber := v.(json.Number); isNumber {
+			q.Time, _ = n.Float64()
+		}
+	}
+	if v, ok := lookupFirst(entry, paths.Connection); ok {
+		if s, isString := v.(string); isString {
+			q.Connection = s
+		}
+	}
+	if v, ok := lookupFirst(entry, paths.Timestamp); ok {
+		q.LoggedAt = jsonTimestamp(v)
+	}
+	return q, true
+}
+
+// jsonTimestamp converts the timestamp of a JSON log entry such as "2024-12-13T20:05:44.123+00:00", 1734120344.123
+// or 1734120344123 in milliseconds
+func jsonTimestamp(v any) time.Time {
+	switch ts := v.(type) {
+	case string:
+		t, _ := parseTimestamp(ts)
+		return t
+	case json.Number:
+		f, err := ts.Float64()
+		// Small numbers are durations such as a "time" field in milliseconds
+		if err != nil || f < 1e9 {
+			return time.Time{}
+		}
+		if f >= 1e12 {
+			f /= 1000
+		}
+		sec, frac := math.Modf(f)
+		// float64 is precise to about a microsecond for the current epoch
+		return time.Unix(int64(sec), int64(math.Round(frac*1e6))*int64(time.Microsecond))
+	}
+	return time.Time{}
+}
+
+// jsonBinding converts a decoded JSON value into a value the MySQL driver can bind
+func jsonBinding(v any) any {
+	switch b := v.(type) {
+	case json.Number:
+		if i, err := b.Int64(); err == nil {
+			return i
+		}
+		f, _ := b.Float64()
+		return f
+	case map[string]any, []any:
+		// Objects and arrays are usually stored in JSON columns
+		j, _ := json.Marshal(b)
+		return string(j)
+	default:
+		// string, bool and nil
+		return b
+	}
+}
+
+func lookupFirst(entry map[string]any, paths []string) (any, bool) {
+	for _, p := range paths {
+		if v, ok := lookupJSON(entry, p); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lookupJSON returns the value at a dot-separated path such as "context.bindings" or "args.0"
+func lookupJSON(entry map[string]any, path string) (any, bool) {
+	var v any = entry
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// selectQuery returns the query of a log entry whose SQL and bindings are already separated
+// It classifies the statement the same way as in text logs so every format results in the same queries.
+// Entries without bindings are explained as they are.
+func selectQuery(sql string, positional []any, named map[string]any, writes bool, skipped skipStats) (Query, bool) {
+	stmt, ok := selectStatement(sql, writes, skipped)
+	if !ok {
+		return Query{}, false
+	}
+
+	q := newQuery(stmt)
+	if positional != nil || named != nil {
+		sql, bindings, err := rewritePlaceholders(stmt, positional, named)
+		if err != nil {
+			log.Printf("skipping query: %s\n", err)
+			skipped.skip(skipInvalidBindings)
+			return Query{}, false
+		}
+		q.SQL = sql
+		q.Bindings = append(q.Bindings, bindings...)
+	}
+	return q, true
+}
+
+// uniqueQueries keeps the last query of every fingerprint in the order the fingerprints first appear
+// The queries have to be in the order they were logged. The OFFSETs of every query of a fingerprint are collected
+// in [Query.Offsets] and its longest run with different bindings in [Query.Burst].
+func uniqueQueries(queries []Query) []Query {
+	idx := make(map[string]int)
+	unique := make([]Query, 0)
+	fingerprints := make([]string, 0)
+	offsets := make([][]int64, 0)
+	for _, q := range queries {
+		fp := q.Fingerprint()
+		i, ok := idx[fp]
+		if !ok {
+			i = len(unique)
+			idx[fp] = i
+			unique = append(unique, q)
+			fingerprints = append(fingerprints, fp)
+			offsets = append(offsets, nil)
+		}
+		unique[i] = q
+		if o, ok := q.offset(); ok {
+			offsets[i] = append(offsets[i], o)
+		}
+	}
+	bursts := findBursts(queries)
+	for i := range unique {
+		unique[i].Offsets = offsets[i]
+		unique[i].Burst = bursts[fingerprints[i]]
+	}
+	return unique
+}
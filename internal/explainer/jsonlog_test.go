@@ -0,0 +1,83 @@
+package explainer
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseJSONLogs(t *testing.T) {
+	lines := []string{
+		`{"message":"select * from users where id = ? and name = ?","context":{"bindings":[10,"John"],"time":1.25,"connection":"mysql"},"level":200}`,
+		`{"msg":"query","sql":"select * from posts where score > ? and data = ?","args":[1.5,{"a":1}],"duration":3,"level":"info"}`,
+		`{"message":"insert into users (name) values (?)","context":{"bindings":["John"]}}`,
+		`{"message":"User logged in"}`,
+		`not json`,
+		`{"message":"select * from users where id = ?","context":{"bindings":[1,2]}}`,
+		`{"message":"select * from users where id = ? and name = ?","context":{"bindings":[20,"Jane"]}}`,
+	}
+	queries := parseJSONLogs(lines, DefaultJSONPaths, false, nil)
+	assert.Len(t, queries, 2)
+
+	assert.Equal(t, "select * from users where id = ? and name = ?", queries[0].SQL)
+	// The last query of the same fingerprint is kept
+	assert.Equal(t, []any{int64(20), "Jane"}, queries[0].Bindings)
+
+	assert.Equal(t, "select * from posts where score > ? and data = ?", queries[1].SQL)
+	assert.Equal(t, []any{1.5, `{"a":1}`}, queries[1].Bindings)
+	assert.Equal(t, float64(3), queries[1].Time)
+}
+
+func TestParseJSONLogs_CustomPaths(t *testing.T) {
+	lines := []string{
+		`{"event":{"statement":"select * from users where id = ?","params":[5],"ms":0.4,"db":"analytics"}}`,
+	}
+	paths := JSONPaths{
+		SQL:        ParseJSONPath("event.statement"),
+		Bindings:   ParseJSONPath("event.params"),
+		Time:       ParseJSONPath("event.ms"),
+		Connection: ParseJSONPath("event.db"),
+	}
+	queries := parseJSONLogs(lines, paths, false, nil)
+	assert.Len(t, queries, 1)
+	assert.Equal(t, []any{int64(5)}, queries[0].Bindings)
+	assert.Equal(t, 0.4, queries[0].Time)
+	assert.Equal(t, "analytics", queries[0].Connection)
+}
+
+func TestLookupJSON(t *testing.T) {
+	entry := map[string]any{"args": []any{"a", map[string]any{"b": "c"}}}
+
+	v, ok := lookupJSON(entry, "args.1.b")
+	assert.True(t, ok)
+	assert.Equal(t, "c", v)
+
+	_, ok = lookupJSON(entry, "args.2")
+	assert.False(t, ok)
+	_, ok = lookupJSON(entry, "context.bindings")
+	assert.False(t, ok)
+}
+
+func TestParseJSONPath(t *testing.T) {
+	assert.Equal(t, []string{"message", "msg"}, ParseJSONPath("message, msg,"))
+}
+
+func TestParseJSONLogs_NamedBindings(t *testing.T) {
+	lines := []string{
+		`{"message":"select * from users where id = :id and name = :name","context":{"bindings":{"name":"John","id":5}}}`,
+	}
+	queries := parseJSONLogs(lines, DefaultJSONPaths, false, nil)
+	assert.Len(t, queries, 1)
+	assert.Equal(t, "select * from users where id = ? and name = ?", queries[0].SQL)
+	assert.Equal(t, []any{int64(5), "John"}, queries[0].Bindings)
+}
+
+func TestJSONTimestamp(t *testing.T) {
+	expected := time.Date(2024, 12, 13, 20, 5, 44, 123000000, time.UTC)
+	assert.True(t, expected.Equal(jsonTimestamp("2024-12-13T20:05:44.123+00:00")))
+	assert.True(t, expected.Equal(jsonTimestamp(json.Number("1734120344.123"))))
+	assert.True(t, expected.Equal(jsonTimestamp(json.Number("1734120344123"))))
+	assert.True(t, jsonTimestamp(json.Number("1.25")).IsZero())
+	assert.True(t, jsonTimestamp("yesterday").IsZero())
+}
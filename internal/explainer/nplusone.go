@@ -0,0 +1,149 @@
+package explainer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+const (
+	// nPlusOneMinimum is the number of different bindings a burst needs to be reported as N+1 queries
+	nPlusOneMinimum = 5
+	// burstGap is the number of other queries that can run between two queries of a burst.
+	// Loading more than one relation of every row runs the queries of the relations in turns.
+	burstGap = 3
+	// burstWindow is the longest time between two queries of a burst if the log has timestamps
+	burstWindow = time.Second
+)
+
+// Burst is a run of the same query with different bindings, the pattern of loading a relation one row at a time
+type Burst struct {
+	// Count is the number of queries in the burst
+	Count int
+	// Start and End are the times of the first and the last query. They're zero if the log has no timestamps
+	Start time.Time
+	End   time.Time
+	// Varying are the indexes of the bindings that are not the same in every query of the burst.
+	// The literals of queries logged without bindings are compared as if they were bindings
+	Varying []int
+}
+
+// burstRun is a burst that is still being collected
+type burstRun struct {
+	Burst
+	// last is the index of the last query of the run
+	last int
+	// first are the bindings of the first query of the run
+	first  []any
+	values map[string]bool
+}
+
+// findBursts returns the longest burst of every fingerprint that has at least [nPlusOneMinimum] different bindings
+// The queries are every query of the logs in the order they were logged. The keys are the fingerprints.
+func findBursts(queries []Query) map[string]Burst {
+	bursts := make(map[string]Burst)
+	runs := make(map[string]*burstRun)
+	end := func(fp string, run *burstRun) {
+		if len(run.values) >= nPlusOneMinimum && run.Count > bursts[fp].Count {
+			slices.Sort(run.Varying)
+			bursts[fp] = run.Burst
+		}
+	}
+
+	for i, q := range queries {
+		fp := q.Fingerprint()
+		p := parameterize(q)
+		run, ok := runs[fp]
+		if ok && (i-run.last-1 > burstGap || !q.LoggedAt.IsZero() && !run.End.IsZero() && q.LoggedAt.Sub(run.End) > burstWindow) {
+			end(fp, run)
+			ok = false
+		}
+		if !ok {
+			run = &burstRun{
+				Burst:  Burst{Start: q.LoggedAt},
+				first:  p.Bindings,
+				values: make(map[string]bool),
+			}
+			runs[fp] = run
+		}
+		run.Count++
+		run.End = q.LoggedAt
+		run.last = i
+		run.values[fmt.Sprintf("%v", p.Bindings)] = true
+		for j, v := range p.Bindings {
+			if j < len(run.first) && !slices.Contains(run.Varying, j) && fmt.Sprint(v) != fmt.Sprint(run.first[j]) {
+				run.Varying = append(run.Varying, j)
+			}
+		}
+	}
+	for fp, run := range runs {
+		end(fp, run)
+	}
+	return bursts
+}
+
+// checkNPlusOne checks for queries that ran many times in a row with different bindings
+//
+// For example:
+//
+// select * from orders where user_id = ?
+//
+// running once for every user of a list. It's the N+1 query problem of ORMs: the relation of every row is loaded
+// with its own query instead of one query for all the rows. Every query is fast on its own so only the log shows it.
+// Paginated queries are left to [Result.checkPagination].
+func (r *Result) checkNPlusOne() {
+	q := r.explain.Query
+	b := q.Burst
+	if b.Count < nPlusOneMinimum || len(q.Offsets) != 0 {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 1)
+
+	duration := ""
+	if !b.Start.IsZero() && !b.End.IsZero() {
+		duration = fmt.Sprintf(" within %s", b.End.Sub(b.Start))
+	}
+	warnings := []string{fmt.Sprintf("The query ran %d times in a row with different bindings%s. It looks like an N+1 problem: a relation is loaded with one query for every row instead of one query for all of them.", b.Count, duration)}
+	if rewrite, ok := inListRewrite(q, b); ok {
+		warnings = append(warnings, fmt.Sprintf("Load them with one query: %s", rewrite))
+	} else {
+		warnings = append(warnings, "Load them with one query that has the values in an IN (...) list.")
+	}
+	if tables := queryTables(sqllexer.Tokenize(q.SQL)); len(tables) != 0 {
+		warnings = append(warnings, fmt.Sprintf("If the rows belong to the results of an earlier query, eager load the relation, for example with('%[1]s') in Laravel, includes(:%[1]s) in Rails, prefetch_related('%[1]s') in Django or Preload(\"%[1]s\") in GORM.", tables[0].name))
+	}
+	r.nPlusOneWarning = strings.Join(warnings, " ")
+}
+
+// inListRewrite returns the query of a burst with the comparison of the binding that changes replaced by an IN list
+//
+// For example:
+//
+// select * from orders where user_id = ? and status = ?
+//
+// Returns: select * from orders where user_id in (?, ?, ?, ...) and status = ?
+//
+// It's false if more than one binding changes or the one that changes is not compared with = to a column.
+func inListRewrite(q Query, b Burst) (string, bool) {
+	if len(b.Varying) != 1 {
+		return "", false
+	}
+	p := parameterize(q)
+	placeholders := findPlaceholders(p.SQL)
+	if b.Varying[0] >= len(placeholders) {
+		return "", false
+	}
+	ph := placeholders[b.Varying[0]]
+	for _, c := range comparisons(sqllexer.Tokenize(p.SQL)) {
+		if c.op != "=" || len(c.right) != 1 || c.right[0].Pos != ph.start || !isColumn(c.left) {
+			continue
+		}
+		last := c.left[len(c.left)-1]
+		return p.SQL[:last.Pos+len(last.Text)] + " in (?, ?, ?, ...)" + p.SQL[ph.end:], true
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+package explainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFindBursts(t *testing.T) {
+	queries := make([]Query, 0)
+	queries = append(queries, newQuery("select * from users where active = 1"))
+	for i := range 6 {
+		q := newQuery("select * from orders where user_id = ? and status = ?")
+		q.Bindings = []any{int64(i + 1), "paid"}
+		queries = append(queries, q)
+		// Another relation loaded in turns doesn't end the burst
+		queries = append(queries, newQuery("select * from profiles where user_id = 1"))
+	}
+	// The same bindings every time is not a burst
+	for range 6 {
+		queries = append(queries, newQuery("select * from settings where name = 'theme'"))
+	}
+
+	bursts := findBursts(queries)
+	assert.Len(t, bursts, 1)
+	b := bursts[fingerprint("select * from orders where user_id = ? and status = ?")]
+	assert.Equal(t, 6, b.Count)
+	assert.Equal(t, []int{0}, b.Varying)
+}
+
+func TestFindBursts_Gap(t *testing.T) {
+	start := time.Date(2024, 12, 13, 20, 6, 25, 0, time.UTC)
+	queries := make([]Query, 0)
+	for i := range 10 {
+		q := newQuery("select * from orders where id = " + strconv.Itoa(i))
+		q.LoggedAt = start.Add(time.Duration(i) * 100 * time.Millisecond)
+		// The runs are more than a second apart after the fifth query
+		if i >= 5 {
+			q.LoggedAt = q.LoggedAt.Add(time.Minute)
+		}
+		queries = append(queries, q)
+	}
+	b := findBursts(queries)[fingerprint("select * from orders where id = 1")]
+	assert.Equal(t, 5, b.Count)
+	assert.Equal(t, start, b.Start)
+	assert.Equal(t, start.Add(400*time.Millisecond), b.End)
+
+	// Too many other queries between them
+	queries = make([]Query, 0)
+	for i := range 10 {
+		queries = append(queries, newQuery("select * from orders where id = "+strconv.Itoa(i)))
+		for range burstGap + 1 {
+			queries = append(queries, newQuery("select * from users"))
+		}
+	}
+	assert.Empty(t, findBursts(queries))
+}
+
+func TestCheckNPlusOne(t *testing.T) {
+	q := newQueryWithBindings("select * from orders where user_id = ? and status = ?", []string{"6", "paid"})
+	q.Burst = Burst{Count: 20, Varying: []int{0}}
+	res := newResult(ExplainResult{Query: q})
+	res.checkNPlusOne()
+	assert.Contains(t, res.nPlusOneWarning, "The query ran 20 times in a row with different bindings.")
+	assert.Contains(t, res.nPlusOneWarning, "Load them with one query: select * from orders where user_id in (?, ?, ?, ...) and status = ?")
+	assert.Contains(t, res.nPlusOneWarning, "with('orders') in Laravel")
+	assert.Equal(t, float32(4), res.Grade())
+
+	q.Burst = Burst{Count: 3, Varying: []int{0}}
+	res = newResult(ExplainResult{Query: q})
+	res.checkNPlusOne()
+	assert.Empty(t, res.nPlusOneWarning)
+}
+
+func TestParseLogs_Bursts(t *testing.T) {
+	logs := make([]string, 0)
+	for i := range 5 {
+		logs = append(logs, "[2024-12-13 20:06:25] local.INFO: select * from orders where user_id = ? ["+strconv.Itoa(i+1)+"]")
+	}
+	queries := parseLogs(logs, false, make(skipStats))
+	assert.Len(t, queries, 1)
+	assert.Equal(t, 5, queries[0].Burst.Count)
+	assert.Equal(t, time.Date(2024, 12, 13, 20, 6, 25, 0, time.UTC), queries[0].Burst.Start)
+}
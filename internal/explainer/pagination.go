@@ -0,0 +1,358 @@
+package explainer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+	"github.com/mmartinjoo/explainer/internal/tableanalyzer"
+)
+
+const (
+	// deepOffsetLimit is the OFFSET above which a query is reported even if the log contains it only once
+	deepOffsetLimit = 1000
+	// growingOffsetSteps is the number of times the OFFSET of a query has to grow in the log to look like page-by-page pagination
+	growingOffsetSteps = 3
+)
+
+// limitClause is the top-level LIMIT of a query with an OFFSET
+type limitClause struct {
+	// pos is the index of the LIMIT token
+	pos    int
+	count  int64
+	offset int64
+}
+
+// sortKey is a column of the ORDER BY of a paginated query
+type sortKey struct {
+	// column is the column the way it's written in the query such as o.created_at
+	column string
+	ref    columnRef
+	desc   bool
+}
+
+// limitClause returns the top-level LIMIT of a query if it has an OFFSET
+// LIMIT 15 OFFSET 30 and LIMIT 30, 15 are the same. The values can be literals or bindings.
+func (q Query) limitClause(tokens []sqllexer.Token) (limitClause, bool) {
+	i := wordAtTop(tokens, "limit")
+	if i == -1 || i+3 >= len(tokens) {
+		return limitClause{}, false
+	}
+	positions := make(map[int]int)
+	for j, p := range findPlaceholders(q.SQL) {
+		positions[p.start] = j
+	}
+	value := func(t sqllexer.Token) (int64, bool) {
+		if t.Kind == sqllexer.Number {
+			n, err := strconv.ParseInt(t.Text, 10, 64)
+			return n, err == nil
+		}
+		j, ok := positions[t.Pos]
+		if !t.Is("?") || !ok || j >= len(q.Bindings) {
+			return 0, false
+		}
+		switch v := q.Bindings[j].(type) {
+		case int64:
+			return v, true
+		case int:
+			return int64(v), true
+		case float64:
+			return int64(v), v == float64(int64(v))
+		case string:
+			n, err := strconv.ParseInt(v, 10, 64)
+			return n, err == nil
+		}
+		return 0, false
+	}
+
+	first, ok := value(tokens[i+1])
+	if !ok {
+		return limitClause{}, false
+	}
+	second, ok := value(tokens[i+3])
+	if !ok {
+		return limitClause{}, false
+	}
+	switch {
+	case tokens[i+2].Is(","):
+		return limitClause{pos: i, count: second, offset: first}, true
+	case tokens[i+2].Is("offset"):
+		return limitClause{pos: i, count: first, offset: second}, true
+	}
+	return limitClause{}, false
+}
+
+// offset returns the OFFSET of a query
+func (q Query) offset() (int64, bool) {
+	limit, ok := q.limitClause(sqllexer.Tokenize(q.SQL))
+	return limit.offset, ok
+}
+
+// checkPagination checks for queries that skip a lot of rows with OFFSET
+//
+// For example:
+//
+// select * from orders order by created_at desc limit 15 offset 30000
+//
+// MySQL can't jump to the 30000th row. It reads 30015 rows and throws away the first 30000, so every page is slower
+// than the one before it. Laravel's paginate() runs queries like this one with growing offsets.
+// The query is reported if the offset is large or if the log contains it with growing offsets.
+func (r *Result) checkPagination(ctx context.Context, s *schema) {
+	tokens := sqllexer.Tokenize(r.explain.Query.SQL)
+	limit, ok := r.explain.Query.limitClause(tokens)
+	if !ok {
+		return
+	}
+	deep := limit.offset > deepOffsetLimit
+	growing := growingOffsets(r.explain.Query.Offsets)
+	if !deep && !growing {
+		return
+	}
+
+	warnings := make([]string, 0)
+	if deep {
+		r.grade = grade.Dec(r.grade, 1)
+		warnings = append(warnings, fmt.Sprintf("LIMIT %d OFFSET %d makes MySQL read %d rows and throw away the first %d of them. The deeper the page the more rows are read for nothing.", limit.count, limit.offset, limit.offset+limit.count, limit.offset))
+	}
+	if growing {
+		if !deep {
+			r.grade = grade.Dec(r.grade, 0.5)
+		}
+		warnings = append(warnings, fmt.Sprintf("The log contains the query with growing offsets (%s). Every page reads and throws away the rows of all the pages before it.", offsetList(r.explain.Query.Offsets)))
+	}
+	warnings = append(warnings, r.keysetRewrite(ctx, s, tokens, limit))
+	r.paginationWarning = strings.Join(warnings, " ")
+}
+
+// keysetRewrite returns the advice to use keyset pagination with the query rewritten
+//
+// For example:
+//
+// select * from orders where status = ? order by created_at desc limit 15 offset 30000
+//
+// Returns: select * from orders where (status = ?) and (created_at < ? or (created_at = ? and id < ?)) order by created_at desc, id desc limit 15
+//
+// The primary key is added to the ORDER BY so every row is on exactly one page. A query without an ORDER BY
+// is ordered by the columns of the index it uses.
+func (r *Result) keysetRewrite(ctx context.Context, s *schema, tokens []sqllexer.Token, limit limitClause) string {
+	fallback := "Use keyset pagination instead: order by a unique column such as id, remember the last row of the page and start the next page after it with WHERE id > ? instead of an OFFSET. In Laravel cursorPaginate() does this."
+	if !tokens[0].Is("select") || hasWordAtTop(tokens, "union") || hasWordAtTop(tokens, "group") || hasWordAtTop(tokens, "having") {
+		return fallback
+	}
+	// queryTables skips derived tables so the first table would be the one inside the subquery
+	if i := wordAtTop(tokens, "from"); i == -1 || i+1 >= len(tokens) || tokens[i+1].Is("(") {
+		return fallback
+	}
+	tables := queryTables(tokens)
+	if len(tables) == 0 {
+		return fallback
+	}
+	from := tables[0]
+	qualifier := ""
+	if len(tables) > 1 {
+		qualifier = cmp.Or(from.alias, from.name) + "."
+	}
+	table, known := s.table(ctx, from.name)
+
+	var notes []string
+	keys, ok := orderKeys(r.explain.Query.SQL, tokens[:limit.pos])
+	if !ok {
+		return fallback
+	}
+	if len(keys) == 0 {
+		if !known {
+			return fallback
+		}
+		note := "The query has no ORDER BY so the rows can be in a different order on every page and a row can show up on two pages or on none. Order it by the primary key."
+		columns := table.IndexColumns("PRIMARY")
+		if key := r.explain.Key.String; len(key) != 0 && !strings.EqualFold(key, "PRIMARY") {
+			note = fmt.Sprintf("The query has no ORDER BY so the rows can be in a different order on every page and a row can show up on two pages or on none. Order it by the columns of the index %s it uses.", key)
+			columns = table.IndexColumns(key)
+		}
+		for _, c := range columns {
+			keys = append(keys, sortKey{column: qualifier + c, ref: columnRef{qualifier: strings.TrimSuffix(qualifier, "."), column: c}})
+		}
+		notes = append(notes, note)
+	}
+	if len(keys) == 0 || slices.ContainsFunc(keys, func(k sortKey) bool { return strings.HasPrefix(k.ref.column, "(") }) {
+		return fallback
+	}
+
+	// The primary key is the tiebreaker of rows with the same values
+	if known {
+		for _, c := range table.IndexColumns("PRIMARY") {
+			if !slices.ContainsFunc(keys, func(k sortKey) bool { return strings.EqualFold(k.ref.column, c) && onTable(tables, k, from) }) {
+				keys = append(keys, sortKey{column: qualifier + c, ref: columnRef{qualifier: strings.TrimSuffix(qualifier, "."), column: c}, desc: keys[len(keys)-1].desc})
+			}
+		}
+	}
+
+	sql := r.explain.Query.SQL
+	end := tokens[limit.pos].Pos
+	if i := wordAtTop(tokens[:limit.pos], "order"); i != -1 {
+		end = tokens[i].Pos
+	}
+	body := strings.TrimSpace(sql[:end]) + " where " + keysetCondition(keys)
+	if i := wordAtTop(tokens[:limit.pos], "where"); i != -1 {
+		w := tokens[i]
+		body = sql[:w.Pos] + w.Text + " (" + strings.TrimSpace(sql[w.Pos+len(w.Text):end]) + ") and " + keysetCondition(keys)
+	}
+	order := make([]string, 0, len(keys))
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.ref.column)
+		if k.desc {
+			order = append(order, k.column+" desc")
+			continue
+		}
+		order = append(order, k.column)
+	}
+	notes = append(notes, fmt.Sprintf("Use keyset pagination instead: remember the %s of the last row of the page and start the next page after it: %s order by %s limit %d. In Laravel cursorPaginate() does this.", strings.Join(names, ", "), body, strings.Join(order, ", "), limit.count))
+
+	if !known {
+		notes = append(notes, "The ORDER BY has to end with a unique column such as the primary key, otherwise rows with the same values are skipped or repeated.")
+		return strings.Join(notes, " ")
+	}
+	// Only an index of a single table can return the rows in order
+	if slices.ContainsFunc(keys, func(k sortKey) bool { return !onTable(tables, k, from) }) {
+		return strings.Join(notes, " ")
+	}
+	if index, ok := keysetIndex(table, equalityColumns(tokens, tables, from), names); ok {
+		notes = append(notes, fmt.Sprintf("MySQL can read the index %s from the last row of the previous page.", index))
+	} else {
+		notes = append(notes, fmt.Sprintf("No index starts with (%s). Add one, after the columns compared with = in the WHERE clause, so MySQL can start reading at the last row of the previous page instead of sorting the rows.", strings.Join(names, ", ")))
+	}
+	return strings.Join(notes, " ")
+}
+
+// orderKeys returns the columns of the top-level ORDER BY
+// It's false if the ORDER BY contains an expression that is not a column.
+func orderKeys(sql string, tokens []sqllexer.Token) ([]sortKey, bool) {
+	keys := make([]sortKey, 0)
+	i := wordAtTop(tokens, "order")
+	if i == -1 || i+1 >= len(tokens) || !tokens[i+1].Is("by") {
+		return keys, true
+	}
+
+	item := make([]sqllexer.Token, 0)
+	depth := 0
+	add := func() bool {
+		desc := false
+		if len(item) != 0 && item[len(item)-1].Is("asc", "desc") {
+			desc = item[len(item)-1].Is("desc")
+			item = item[:len(item)-1]
+		}
+		if !isColumn(item) {
+			return false
+		}
+		last := item[len(item)-1]
+		keys = append(keys, sortKey{column: sql[item[0].Pos : last.Pos+len(last.Text)], ref: columnRefs(item)[0], desc: desc})
+		item = item[:0]
+		return true
+	}
+	for _, t := range tokens[i+2:] {
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			depth--
+		case depth == 0 && t.Is(","):
+			if !add() {
+				return nil, false
+			}
+			continue
+		}
+		item = append(item, t)
+	}
+	if !add() {
+		return nil, false
+	}
+	return keys, true
+}
+
+// onTable reports whether a sort key is a column of the table
+func onTable(tables []tableRef, k sortKey, table tableRef) bool {
+	t, ok := resolveTable(tables, k.ref)
+	return ok && t == table
+}
+
+// keysetCondition returns the condition that starts a page after the last row of the previous one
+// It's always the expanded form such as created_at < ? or (created_at = ? and id < ?). MySQL is less likely to use an index
+// for a row comparison such as (created_at, id) < (?, ?) if the columns don't cover the prefix of the index.
+func keysetCondition(keys []sortKey) string {
+	op := func(k sortKey) string {
+		if k.desc {
+			return "<"
+		}
+		return ">"
+	}
+	if len(keys) == 1 {
+		return fmt.Sprintf("%s %s ?", keys[0].column, op(keys[0]))
+	}
+
+	alternatives := make([]string, 0, len(keys))
+	for i, k := range keys {
+		terms := make([]string, 0, i+1)
+		for _, prev := range keys[:i] {
+			terms = append(terms, prev.column+" = ?")
+		}
+		terms = append(terms, fmt.Sprintf("%s %s ?", k.column, op(k)))
+		if len(terms) == 1 {
+			alternatives = append(alternatives, terms[0])
+			continue
+		}
+		alternatives = append(alternatives, "("+strings.Join(terms, " and ")+")")
+	}
+	return "(" + strings.Join(alternatives, " or ") + ")"
+}
+
+// equalityColumns returns the columns of a table that are compared with = in the query
+func equalityColumns(tokens []sqllexer.Token, tables []tableRef, table tableRef) []string {
+	columns := make([]string, 0)
+	for _, c := range comparisons(tokens) {
+		if c.op != "=" || !isColumn(c.left) || isColumn(c.right) {
+			continue
+		}
+		ref := columnRefs(c.left)[0]
+		if t, ok := resolveTable(tables, ref); ok && t == table && !slices.Contains(columns, ref.column) {
+			columns = append(columns, ref.column)
+		}
+	}
+	return columns
+}
+
+// keysetIndex returns the index that can return the rows of a page in order starting after the last row of the previous one
+func keysetIndex(table *tableanalyzer.Table, equalities []string, keys []string) (string, bool) {
+	if index, ok := table.IndexStartingWith(append(slices.Clone(equalities), keys...)); ok {
+		return index, true
+	}
+	return table.IndexStartingWith(keys)
+}
+
+// growingOffsets reports whether the offsets grow often enough to look like someone paging through the results
+func growingOffsets(offsets []int64) bool {
+	steps := 0
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] > offsets[i-1] {
+			steps++
+		}
+	}
+	return steps >= growingOffsetSteps
+}
+
+// offsetList returns the offsets for the report such as 0, 15, 30, ..., 4485, 4500
+func offsetList(offsets []int64) string {
+	values := make([]string, 0, len(offsets))
+	for _, o := range offsets {
+		values = append(values, strconv.FormatInt(o, 10))
+	}
+	if len(values) > 6 {
+		values = append(append(values[:3:3], "..."), values[len(values)-2:]...)
+	}
+	return strings.Join(values, ", ")
+}
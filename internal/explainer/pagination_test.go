@@ -0,0 +1,97 @@
+package explainer
+
+import (
+	"context"
+	"database/sql"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const ordersDDL = `CREATE TABLE orders (
+	id bigint unsigned NOT NULL AUTO_INCREMENT,
+	user_id bigint unsigned NOT NULL,
+	status varchar(20) NOT NULL,
+	created_at timestamp NULL,
+	PRIMARY KEY (id),
+	KEY idx_status_created (status, created_at)
+)`
+
+func TestLimitClause(t *testing.T) {
+	tests := []struct {
+		query  Query
+		limit  int64
+		offset int64
+		ok     bool
+	}{
+		{newQuery("select * from orders limit 15 offset 30"), 15, 30, true},
+		{newQuery("select * from orders LIMIT 30, 15"), 15, 30, true},
+		{newQueryWithBindings("select * from orders where status = ? limit ? offset ?", []string{"paid", "15", "45"}), 15, 45, true},
+		{newQuery("select * from orders where id in (select order_id from items limit 10 offset 5)"), 0, 0, false},
+		{newQuery("select * from orders limit 15"), 0, 0, false},
+	}
+	for _, tt := range tests {
+		limit, ok := tt.query.limitClause(sqllexer.Tokenize(tt.query.SQL))
+		assert.Equal(t, tt.ok, ok, tt.query.SQL)
+		assert.Equal(t, tt.limit, limit.count, tt.query.SQL)
+		assert.Equal(t, tt.offset, limit.offset, tt.query.SQL)
+	}
+}
+
+func TestCheckPagination_DeepOffset(t *testing.T) {
+	s := testSchema(t, ordersDDL)
+	res := newResult(ExplainResult{Query: newQuery("select * from orders where status = 'paid' order by created_at desc limit 15 offset 30000")})
+	res.checkPagination(context.Background(), s)
+	assert.Contains(t, res.paginationWarning, "LIMIT 15 OFFSET 30000 makes MySQL read 30015 rows and throw away the first 30000 of them.")
+	assert.Contains(t, res.paginationWarning, "select * from orders where (status = 'paid') and (created_at < ? or (created_at = ? and id < ?)) order by created_at desc, id desc limit 15.")
+	assert.Contains(t, res.paginationWarning, "MySQL can read the index idx_status_created")
+	assert.Equal(t, float32(4), res.Grade())
+}
+
+func TestCheckPagination_GrowingOffsets(t *testing.T) {
+	s := testSchema(t, ordersDDL)
+	q := newQuery("select * from orders o join users u on u.id = o.user_id order by u.nickname, o.id desc limit 15 offset 60")
+	q.Offsets = []int64{0, 15, 30, 45, 60}
+	res := newResult(ExplainResult{Query: q})
+	res.checkPagination(context.Background(), s)
+	assert.Contains(t, res.paginationWarning, "growing offsets (0, 15, 30, 45, 60)")
+	assert.Contains(t, res.paginationWarning, "where (u.nickname > ? or (u.nickname = ? and o.id < ?)) order by u.nickname, o.id desc limit 15.")
+	assert.NotContains(t, res.paginationWarning, "index")
+	assert.Equal(t, float32(4.5), res.Grade())
+
+	q.Offsets = []int64{60, 0, 60}
+	res = newResult(ExplainResult{Query: q})
+	res.checkPagination(context.Background(), s)
+	assert.Empty(t, res.paginationWarning)
+}
+
+func TestCheckPagination_NoOrderBy(t *testing.T) {
+	s := testSchema(t, ordersDDL)
+	res := newResult(ExplainResult{
+		Query: newQuery("select id from orders where status = 'paid' limit 50, 25"),
+		Key:   sql.NullString{String: "idx_status_created", Valid: true},
+	})
+	res.checkPagination(context.Background(), s)
+	assert.Empty(t, res.paginationWarning)
+
+	res = newResult(ExplainResult{
+		Query: newQuery("select id from orders where status = 'paid' limit 5000, 25"),
+		Key:   sql.NullString{String: "idx_status_created", Valid: true},
+	})
+	res.checkPagination(context.Background(), s)
+	assert.Contains(t, res.paginationWarning, "Order it by the columns of the index idx_status_created it uses.")
+	assert.Contains(t, res.paginationWarning, "select id from orders where (status = 'paid') and (status > ? or (status = ? and created_at > ?) or (status = ? and created_at = ? and id > ?)) order by status, created_at, id limit 25.")
+}
+
+func TestCheckPagination_DerivedTable(t *testing.T) {
+	s := testSchema(t, ordersDDL)
+	res := newResult(ExplainResult{Query: newQuery("select * from (select * from orders where status = 'paid') t order by id limit 10 offset 5000")})
+	res.checkPagination(context.Background(), s)
+	assert.Contains(t, res.paginationWarning, "Use keyset pagination instead: order by a unique column such as id")
+	assert.NotContains(t, res.paginationWarning, "MySQL can read the index")
+}
+
+func TestOffsetList(t *testing.T) {
+	assert.Equal(t, "0, 15, 30", offsetList([]int64{0, 15, 30}))
+	assert.Equal(t, "0, 15, 30, ..., 120, 135", offsetList([]int64{0, 15, 30, 45, 60, 75, 90, 105, 120, 135}))
+}
@@ -0,0 +1,124 @@
+package explainer
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+var (
+	// parameterizedClauses are the clauses whose literals are values that can be bound
+	// Literals in the SELECT list, ORDER BY and GROUP BY can't be replaced because ORDER BY 1 means the first column
+	// and LIMIT is kept as it is so pagination stays readable.
+	parameterizedClauses = []string{"where", "on", "having", "set", "values"}
+
+	// clauseKeywords start a new clause
+	clauseKeywords = []string{"select", "from", "join", "where", "on", "group", "order", "having", "limit", "offset", "set", "values", "union", "window", "for"}
+
+	// typedLiteralWords are followed by a literal that must stay in the query such as DATE '2024-12-13' or CHAR(10)
+	typedLiteralWords = []string{"date", "time", "timestamp", "interval", "char", "varchar", "binary", "decimal", "numeric", "float", "double", "nchar", "datetime"}
+)
+
+// parameterize extracts the string and number literals of a query into ? placeholders and bindings
+//
+// For example:
+//
+// select * from users where name like '%John%' and age > 30
+//
+// Returns: select * from users where name like ? and age > ? with ["%John%", 30]
+//
+// Queries that already have placeholders or bindings are returned unchanged.
+func parameterize(q Query) Query {
+	if len(q.Bindings) != 0 || placeholderCount(q.SQL) != 0 {
+		return q
+	}
+
+	tokens := sqllexer.Tokenize(q.SQL)
+	clause := ""
+	// clauses remembers the clause of the outer query when a subquery starts
+	clauses := make([]string, 0)
+
+	var sql strings.Builder
+	bindings := make([]any, 0)
+	last := 0
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			clauses = append(clauses, clause)
+		case t.Is(")") && len(clauses) != 0:
+			clause = clauses[len(clauses)-1]
+			clauses = clauses[:len(clauses)-1]
+		case t.Kind == sqllexer.Word && slices.Contains(clauseKeywords, strings.ToLower(t.Text)):
+			clause = strings.ToLower(t.Text)
+		}
+
+		if !slices.Contains(parameterizedClauses, clause) || !parameterizable(tokens, i) {
+			continue
+		}
+		v, ok := literalValue(t)
+		if !ok {
+			continue
+		}
+		bindings = append(bindings, v)
+		sql.WriteString(q.SQL[last:t.Pos])
+		sql.WriteString("?")
+		last = t.Pos + len(t.Text)
+	}
+	if len(bindings) == 0 {
+		return q
+	}
+	sql.WriteString(q.SQL[last:])
+
+	res := q
+	res.SQL = sql.String()
+	res.Bindings = bindings
+	return res
+}
+
+// parameterizable reports whether the literal at tokens[i] can be replaced with a placeholder
+func parameterizable(tokens []sqllexer.Token, i int) bool {
+	t := tokens[i]
+	if t.Kind != sqllexer.String && t.Kind != sqllexer.Number {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	prev := tokens[i-1]
+	// Charset introducers such as _utf8mb4'abc'
+	if prev.Kind == sqllexer.Word && strings.HasPrefix(prev.Text, "_") && prev.Pos+len(prev.Text) == t.Pos {
+		return false
+	}
+	if prev.Kind == sqllexer.Word && slices.Contains(typedLiteralWords, strings.ToLower(prev.Text)) {
+		return false
+	}
+	// Lengths and precisions such as CHAR(10) or DECIMAL(10, 2)
+	for j := i - 1; j > 0 && (tokens[j].Is(",") || tokens[j].Kind == sqllexer.Number); j-- {
+		i = j
+	}
+	if tokens[i-1].Is("(") && i >= 2 && tokens[i-2].Kind == sqllexer.Word && slices.Contains(typedLiteralWords, strings.ToLower(tokens[i-2].Text)) {
+		return false
+	}
+	return true
+}
+
+// literalValue returns the Go value of a string or number literal
+func literalValue(t sqllexer.Token) (any, bool) {
+	if t.Kind == sqllexer.String {
+		return t.Value(), true
+	}
+	// Hex literals are binary strings
+	if strings.HasPrefix(strings.ToLower(t.Text), "0x") {
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(t.Text, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(t.Text, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
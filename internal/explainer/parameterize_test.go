@@ -0,0 +1,54 @@
+package explainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParameterize(t *testing.T) {
+	tests := []struct {
+		sql      string
+		wantSQL  string
+		bindings []any
+	}{
+		{
+			"select * from users where name like '%John%' and age > 30.5",
+			"select * from users where name like ? and age > ?",
+			[]any{"%John%", 30.5},
+		},
+		{
+			"SELECT id, 'x' AS label FROM users u JOIN orders o ON o.user_id = u.id AND o.status = 'paid' WHERE u.id IN (1, 2) ORDER BY 1 LIMIT 10",
+			"SELECT id, 'x' AS label FROM users u JOIN orders o ON o.user_id = u.id AND o.status = ? WHERE u.id IN (?, ?) ORDER BY 1 LIMIT 10",
+			[]any{"paid", int64(1), int64(2)},
+		},
+		{
+			"select * from users where id in (select user_id from orders where total > 100) and created_at > DATE '2024-12-13'",
+			"select * from users where id in (select user_id from orders where total > ?) and created_at > DATE '2024-12-13'",
+			[]any{int64(100)},
+		},
+		{
+			"select * from users where cast(score as decimal(10, 2)) > 1 and name = _utf8mb4'it''s' and created_at > now() - interval 1 day",
+			"select * from users where cast(score as decimal(10, 2)) > ? and name = _utf8mb4'it''s' and created_at > now() - interval 1 day",
+			[]any{int64(1)},
+		},
+	}
+	for _, tt := range tests {
+		q := parameterize(newQuery(tt.sql))
+		assert.Equal(t, tt.wantSQL, q.SQL)
+		assert.Equal(t, tt.bindings, q.Bindings)
+	}
+}
+
+func TestParameterize_Unchanged(t *testing.T) {
+	q := newQueryWithBindings("select * from users where id = ? and status = 'active'", []string{"1"})
+	assert.Equal(t, q, parameterize(q))
+
+	q = newQuery("select * from users")
+	assert.Equal(t, q, parameterize(q))
+}
+
+func TestParameterize_LikePattern(t *testing.T) {
+	q := newQuery("select * from users where name like '%John%'")
+	assert.False(t, q.HasLikePattern())
+	assert.True(t, parameterize(q).HasLikePattern())
+}
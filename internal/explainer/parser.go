@@ -1,160 +1,181 @@
 package explainer
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
-	"io"
+	"log"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
 )
 
-func parseLogs(r io.Reader) ([]Query, error) {
-	logs, err := readQueries(r)
-	if err != nil {
-		return nil, fmt.Errorf("explainer.parseLogs: %w", err)
-	}
-	queries, err := rejectWriteQueries(logs)
-	if err != nil {
-		return nil, fmt.Errorf("explainer.parseLogs: %w", err)
-	}
-	selectQueries, err := sanitizeQueries(queries)
-	if err != nil {
-		return nil, fmt.Errorf("explainer.parseLogs: %w", err)
-	}
-	uniqueQueries, err := getUniqueQueries(selectQueries)
-	if err != nil {
-		return nil, fmt.Errorf("explainer.parseLogs: %w", err)
-	}
-	res, err := constructQueries(uniqueQueries)
-	if err != nil {
-		return nil, fmt.Errorf("explainer.parseLogs: %w", err)
-	}
-	return res, nil
-}
+var (
+	// logTimestamp is the date and time of a log entry
+	logTimestamp = regexp.MustCompile(`\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
 
-func readQueries(r io.Reader) ([]string, error) {
-	queries := make([]string, 0)
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		queries = append(queries, scanner.Text())
-	}
-	return queries, nil
-}
+	// timestampLayouts are the layouts of logTimestamp after the separators are normalized
+	timestampLayouts = []string{"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z0700", "2006-01-02 15:04:05.999999999"}
+)
 
-func rejectWriteQueries(logLines []string) ([]string, error) {
-	writeCmds := []string{"insert", "update", "delete"}
-	queries := make([]string, 0)
-	for _, line := range logLines {
-		if len(line) == 0 {
+// parseLogs turns log lines into unique SELECT queries
+// UPDATE, DELETE and INSERT ... SELECT statements are also returned if writes is true.
+// The entries that are not explained are counted in skipped by the reason they were skipped.
+func parseLogs(logs []string, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	for _, line := range logs {
+		stmt, ok := selectStatement(line, writes, skipped)
+		if !ok {
 			continue
 		}
-		words := strings.Split(line, " ")
-		isWriteCmd := false
-		for _, w := range words {
-			if slices.Contains(writeCmds, w) {
-				isWriteCmd = true
-				break
-			}
-		}
-		if isWriteCmd {
+		q, ok := constructQuery(stmt, skipped)
+		if !ok {
 			continue
 		}
-		queries = append(queries, line)
+		q.LoggedAt = entryTime(line)
+		queries = append(queries, q)
 	}
-	return queries, nil
+	return uniqueQueries(queries)
 }
 
-func sanitizeQueries(logLines []string) ([]string, error) {
-	queries := make([]string, 0)
-	for _, line := range logLines {
-		idx := strings.Index(line, "select")
-		if idx == -1 {
-			continue
-		}
-		q := strings.Trim(line[idx:], " ")
-		queries = append(queries, q)
+// entryTime returns the timestamp in the prefix of a log entry such as [2024-12-13 20:05:44] or 2024-12-13T20:05:44.123Z
+// It's zero if the prefix doesn't contain one.
+func entryTime(entry string) time.Time {
+	start, _ := locateStatement(entry)
+	if start == -1 {
+		return time.Time{}
 	}
-	return queries, nil
+	return prefixTime(entry[:start])
 }
 
-func getUniqueQueries(queries []string) ([]string, error) {
-	unique := make([]string, 0)
-
-	// keys are queries without bindings which represents a unique query
-	// values are queries with bindings
-	hmap := make(map[string]string)
+// prefixTime returns the timestamp in the part of a log line before the query
+// It's zero if the prefix doesn't contain one.
+func prefixTime(prefix string) time.Time {
+	m := logTimestamp.FindString(prefix)
+	if len(m) == 0 {
+		return time.Time{}
+	}
+	t, _ := parseTimestamp(m)
+	return t
+}
 
-	for _, q := range queries {
-		if !hasBindings(q) {
-			hmap[q] = q
-			continue
+// parseTimestamp parses a date and time such as 2024-12-13 20:05:44, 2024/12/13 20:05:44.123 or 2024-12-13T20:05:44+01:00
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.NewReplacer("/", "-", "T", " ", ",", ".").Replace(strings.TrimSpace(s))
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
 		}
-		idx := strings.LastIndex(q, "[")
-		sql := strings.Trim(q[:idx], " ")
-		hmap[sql] = q
 	}
+	return time.Time{}, false
+}
 
-	for _, fullSQL := range hmap {
-		unique = append(unique, fullSQL)
+// selectStatements classifies the statement of every log entry and returns the explainable ones without their log prefix
+// Blank entries are ignored, other entries are counted in skipped by their statement kind.
+// Entries with more than one statement are never explained.
+func selectStatements(logs []string, writes bool, skipped skipStats) []string {
+	queries := make([]string, 0)
+	for _, line := range logs {
+		if stmt, ok := selectStatement(line, writes, skipped); ok {
+			queries = append(queries, stmt)
+		}
 	}
+	return queries
+}
 
-	return unique, nil
+// selectStatement classifies the statement of a log entry and returns it without its log prefix if it's explainable
+func selectStatement(entry string, writes bool, skipped skipStats) (string, bool) {
+	if len(strings.TrimSpace(entry)) == 0 {
+		return "", false
+	}
+	start, kind := locateStatement(entry)
+	if !kind.explainable(writes) {
+		skipped.skip(kind.String())
+		return "", false
+	}
+	stmt := strings.TrimSpace(entry[start:])
+	if len(sqllexer.SplitStatements(stmt)) > 1 {
+		skipped.skip(skipMultipleStatements)
+		return "", false
+	}
+	return stmt, true
 }
 
-func constructQueries(selectQueries []string) ([]Query, error) {
+// constructQueries turns log entries into queries with typed bindings
+// Placeholders are rewritten to ?. Entries with malformed bindings or with a different number of bindings than placeholders are reported and skipped
+func constructQueries(selectQueries []string, skipped skipStats) []Query {
 	queries := make([]Query, 0)
 	for _, q := range selectQueries {
-		if !hasBindings(q) {
-			queries = append(queries, newQuery(q))
-			continue
+		if query, ok := constructQuery(q, skipped); ok {
+			queries = append(queries, query)
 		}
-		bindings, err := getBindings(q)
-		if err != nil {
-			return nil, fmt.Errorf("constructQueries: %w", err)
-		}
-		c := strings.Count(q, "?")
-		if c != len(bindings) {
-			return nil, fmt.Errorf("constructQueries: argument number mismatch: %d \"?\" and the following bindings: %v", c, bindings)
-		}
-		idx := strings.LastIndex(q, "[")
-		sql := strings.Trim(q[:idx], " ")
-		queries = append(queries, newQueryWithBindings(sql, bindings))
 	}
-	return queries, nil
+	return queries
 }
 
-func hasBindings(query string) bool {
-	return strings.Index(query, "]") == len(query)-1
+// constructQuery turns a log entry into a query with typed bindings
+// It's false if the bindings of the entry are malformed.
+func constructQuery(entry string, skipped skipStats) (Query, bool) {
+	q := trimContext(entry)
+	if !hasBindings(q) {
+		return newQuery(q), true
+	}
+	positional, named, err := getBindings(q)
+	if err != nil {
+		log.Printf("skipping query: %s\n", err)
+		skipped.skip(skipInvalidBindings)
+		return Query{}, false
+	}
+	sql, bindings, err := rewritePlaceholders(strings.Trim(q[:bindingsIndex(q)], " "), positional, named)
+	if err != nil {
+		log.Printf("skipping query: %s\n", err)
+		skipped.skip(skipInvalidBindings)
+		return Query{}, false
+	}
+	query := newQuery(sql)
+	query.Bindings = bindings
+	return query, true
 }
 
-func getBindings(query string) ([]string, error) {
-	idx := strings.LastIndex(query, "[")
-	if idx == -1 {
-		return nil, fmt.Errorf("trying to parse bindings but \"[\" not found in query: %s", query)
+// hasBindings reports whether a log entry ends with a bindings block
+// A trailing object is only a block of named bindings if the query has :name or @p placeholders. Otherwise it's
+// the context of the log entry such as Monolog writes it: select * from `sites` {"time":1.2}
+func hasBindings(query string) bool {
+	q := strings.TrimSpace(query)
+	if strings.HasSuffix(q, "]") {
+		return true
 	}
+	if !strings.HasSuffix(q, "}") {
+		return false
+	}
+	i := blockIndex(q, "{")
+	if i == -1 {
+		i = strings.Index(q, "{")
+	}
+	return slices.ContainsFunc(findPlaceholders(q[:i]), func(p placeholder) bool {
+		return p.style == placeholderNamed || p.style == placeholderAt
+	})
+}
 
-	// [10,20,30]
-	bindingsStr := query[idx:]
-
-	// Only one binding: [10]
-	if !strings.Contains(bindingsStr, ",") {
-		b := bindingsStr[1 : len(bindingsStr)-1]
-		return []string{b}, nil
+// trimContext removes the trailing object of a log entry if it's the context of the entry and not named bindings
+func trimContext(entry string) string {
+	q := strings.TrimSpace(entry)
+	if !strings.HasSuffix(q, "}") || hasBindings(q) {
+		return entry
 	}
+	if i := blockIndex(q, "{"); i != -1 {
+		return strings.TrimSpace(q[:i])
+	}
+	return entry
+}
 
-	bindings := make([]string, 0)
-	buf := bytes.Buffer{}
-	for _, c := range bindingsStr {
-		if c == '[' {
-			continue
-		}
-		if c == ',' || c == ']' {
-			bindings = append(bindings, buf.String())
-			buf.Reset()
-			continue
-		}
-		buf.WriteRune(c)
+// getBindings returns the typed values of the bindings block at the end of a query
+// An array results in positional bindings, an object in named bindings.
+func getBindings(query string) ([]any, map[string]any, error) {
+	idx := bindingsIndex(query)
+	if idx == -1 {
+		return nil, nil, fmt.Errorf("explainer.getBindings: no valid bindings block found in query: %s", query)
 	}
-	return bindings, nil
+	return parseBindings(query[idx:])
 }
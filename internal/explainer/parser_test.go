@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadQueries(t *testing.T) {
@@ -19,61 +20,159 @@ func TestReadQueries(t *testing.T) {
 	assert.Equal(t, logs, queries)
 }
 
-func TestRejectWriteQueries(t *testing.T) {
+func TestSelectStatements_RejectsWrites(t *testing.T) {
 	logs := []string{
 		"[2024-12-13 20:06:25] local.INFO: select * from `page_views`",
 		"insert into users(username, email) values(john.doe, john@doe.com)",
 		"delete from product",
 		"update categories set is_active=0 where id=10",
 	}
-	queries, err := rejectWriteQueries(logs)
-	assert.Nil(t, err)
+	skipped := make(skipStats)
+	queries := selectStatements(logs, false, skipped)
 	assert.Len(t, queries, 1)
+	assert.Equal(t, skipStats{"INSERT": 1, "DELETE": 1, "UPDATE": 1}, skipped)
 }
 
-func TestSanitizeQueries(t *testing.T) {
+func TestSelectStatements(t *testing.T) {
 	logs := []string{
 		"[2024-12-13 20:06:25] local.INFO: select * from `page_views`   ",
 		"other log line",
 		"local.INFO: select * from `page_views` where id=? [10]",
 		"select * from `page_views`",
+		"",
 	}
-	queries, err := sanitizeQueries(logs)
-	assert.Nil(t, err)
+	skipped := make(skipStats)
+	queries := selectStatements(logs, false, skipped)
 	assert.Len(t, queries, 3)
 
 	assert.Equal(t, "select * from `page_views`", queries[0])
 	assert.Equal(t, "select * from `page_views` where id=? [10]", queries[1])
 	assert.Equal(t, "select * from `page_views`", queries[2])
+	assert.Equal(t, skipStats{"no SQL statement": 1}, skipped)
+}
+
+func TestSelectStatements_CaseCommentsAndCTE(t *testing.T) {
+	logs := []string{
+		"  User Load (0.4ms)  SELECT `users`.* FROM `users`",
+		"[2024-12-13 20:06:25] local.INFO: WITH recent AS (select * from orders) select * from recent",
+		"local.INFO: /* users#index */ SELECT * FROM users",
+		"local.INFO: select * from users where status = 'update' [1]",
+		"local.INFO: Update failed, retrying: select * from jobs",
+		"local.INFO: WITH ids AS (select id from users) DELETE FROM sessions WHERE user_id IN (select id from ids)",
+	}
+	skipped := make(skipStats)
+	queries := selectStatements(logs, false, skipped)
+	assert.Equal(t, []string{
+		"SELECT `users`.* FROM `users`",
+		"WITH recent AS (select * from orders) select * from recent",
+		"/* users#index */ SELECT * FROM users",
+		"select * from users where status = 'update' [1]",
+		"select * from jobs",
+	}, queries)
+	assert.Equal(t, skipStats{"DELETE": 1}, skipped)
 }
 
-func TestGetUniqueQueries(t *testing.T) {
+func TestParseLogs_Unique(t *testing.T) {
 	logs := []string{
 		"select * from `page_views`",
 		"select * from `page_views` where id=? [10]",
 		"select * from `page_views` where id=? [15]",
 		"select * from `page_views` where id IN (?,?) [10,15]",
 	}
-	queries, err := getUniqueQueries(logs)
-	assert.Nil(t, err)
+	queries := parseLogs(logs, false, make(skipStats))
+	// The last query of every fingerprint in the order the fingerprints first appear
 	assert.Len(t, queries, 3)
+	assert.Equal(t, "select * from `page_views`", queries[0].SQL)
+	assert.Equal(t, []any{int64(15)}, queries[1].Bindings)
+	assert.Equal(t, "select * from `page_views` where id IN (?,?)", queries[2].SQL)
+}
 
-	assert.Contains(t, queries, "select * from `page_views`")
-	assert.Contains(t, queries, "select * from `page_views` where id=? [15]")
-	assert.Contains(t, queries, "select * from `page_views` where id IN (?,?) [10,15]")
+func TestParseLogs_Offsets(t *testing.T) {
+	logs := []string{
+		"select * from orders order by id limit 15 offset 0",
+		"select * from orders where id = ? [1]",
+		"select * from orders order by id limit 15 offset 15",
+		"select * from orders order by id limit 30, 15",
+	}
+	queries := parseLogs(logs, false, make(skipStats))
+	assert.Len(t, queries, 3)
+	assert.Equal(t, []int64{0, 15}, queries[0].Offsets)
+	assert.Empty(t, queries[1].Offsets)
+	assert.Equal(t, []int64{30}, queries[2].Offsets)
 }
 
 func TestGetBindings(t *testing.T) {
-	queries, err := getBindings("select * from `page_views` where id IN (?,?,?) [10,20,30]")
+	queries, _, err := getBindings("select * from `page_views` where id IN (?,?,?) [10,20,30]")
 	assert.Nil(t, err)
 	assert.Len(t, queries, 3)
 
-	assert.Equal(t, "10", queries[0])
-	assert.Equal(t, "20", queries[1])
-	assert.Equal(t, "30", queries[2])
+	assert.Equal(t, int64(10), queries[0])
+	assert.Equal(t, int64(20), queries[1])
+	assert.Equal(t, int64(30), queries[2])
+}
+
+func TestGetBindings_Typed(t *testing.T) {
+	bindings, _, err := getBindings(`select * from users where name = ? and note = ? and deleted_at is ? and active = ? and score > ? and tags = ? and meta = ? and created_at > ? ["Doe, John", 'it''s \"ok\"', null, true, 1.5, [1,"a,b"], {"k":"v, w"}, 2024-12-13 20:05:44]`)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{"Doe, John", `it's "ok"`, nil, true, 1.5, `[1,"a,b"]`, `{"k":"v, w"}`, "2024-12-13 20:05:44"}, bindings)
+}
+
+func TestGetBindings_BracketsInQuery(t *testing.T) {
+	bindings, _, err := getBindings("select data->'$[0]' from users where id = ? [[1,2]]")
+	assert.Nil(t, err)
+	assert.Equal(t, []any{"[1,2]"}, bindings)
+}
+
+func TestGetBindings_Malformed(t *testing.T) {
+	_, _, err := getBindings(`select * from users where name = ? ["unterminated]`)
+	assert.NotNil(t, err)
+}
+
+func TestConstructQueries_SkipsMalformed(t *testing.T) {
+	skipped := make(skipStats)
+	queries := constructQueries([]string{
+		"select * from users where id = ? [1, 2]",
+		`select * from users where name = ? ["unterminated]`,
+		"select * from users where name = ? and id = ? ['Doe, John', 5]",
+	}, skipped)
+	assert.Len(t, queries, 1)
+	assert.Equal(t, skipStats{skipInvalidBindings: 2}, skipped)
+	assert.Equal(t, []any{"Doe, John", int64(5)}, queries[0].Bindings)
 }
 
 func TestGetBindings_NoBindings(t *testing.T) {
-	_, err := getBindings("select * from `page_views`")
+	_, _, err := getBindings("select * from `page_views`")
 	assert.NotNil(t, err)
 }
+
+func TestGetBindings_Named(t *testing.T) {
+	positional, named, err := getBindings(`select * from users where id = :id and name = :name {"id": 10, name: 'Doe, John'}`)
+	assert.Nil(t, err)
+	assert.Nil(t, positional)
+	assert.Equal(t, map[string]any{"id": int64(10), "name": "Doe, John"}, named)
+}
+
+func TestConstructQueries_LogContext(t *testing.T) {
+	skipped := make(skipStats)
+	queries := constructQueries([]string{
+		"select * from `sites` {\"time\":1.2}",
+		"select * from `sites` where id = ? [5] {\"time\":0.4}",
+		"select * from users where id = :id {\"id\": 10}",
+	}, skipped)
+	assert.Empty(t, skipped)
+	assert.Len(t, queries, 3)
+
+	assert.Equal(t, "select * from `sites`", queries[0].SQL)
+	assert.Empty(t, queries[0].Bindings)
+	assert.Equal(t, "select * from `sites` where id = ?", queries[1].SQL)
+	assert.Equal(t, []any{int64(5)}, queries[1].Bindings)
+	assert.Equal(t, "select * from users where id = ?", queries[2].SQL)
+	assert.Equal(t, []any{int64(10)}, queries[2].Bindings)
+}
+
+func TestEntryTime(t *testing.T) {
+	assert.Equal(t, time.Date(2024, 12, 13, 20, 6, 25, 0, time.UTC), entryTime("[2024-12-13 20:06:25] local.INFO: select * from users"))
+	assert.Equal(t, time.Date(2024, 12, 13, 20, 6, 25, 500000000, time.UTC), entryTime("2024/12/13 20:06:25.5 select * from users"))
+	// Dates in the query are not the time of the entry
+	assert.True(t, entryTime("select * from users where created_at > '2024-12-13 20:06:25'").IsZero())
+}
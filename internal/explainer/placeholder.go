@@ -0,0 +1,166 @@
+package explainer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+type placeholderStyle int
+
+const (
+	// placeholderPositional is ? (MySQL, PDO, JDBC)
+	placeholderPositional placeholderStyle = iota + 1
+	// placeholderNamed is :name (PDO, Doctrine, sqlx)
+	placeholderNamed
+	// placeholderNumbered is $1 (pgx, Postgres logs)
+	placeholderNumbered
+	// placeholderAt is @p1 (Sequelize, Entity Framework and some other ORMs)
+	placeholderAt
+)
+
+var (
+	numberedPlaceholder = regexp.MustCompile(`^\$(\d+)$`)
+	atPlaceholder       = regexp.MustCompile(`^[pP](\d+)$`)
+)
+
+// placeholder is a placeholder in a query. key is the name or the number of a named, numbered or @p placeholder
+type placeholder struct {
+	style placeholderStyle
+	key   string
+	// start and end are the byte offsets of the placeholder in the query
+	start int
+	end   int
+}
+
+// findPlaceholders returns the placeholders of a query
+//
+// Placeholders in strings and comments are ignored and so are ? characters that are part of
+// JSON operators such as ->?, ?| and ?&, and :: casts.
+func findPlaceholders(sql string) []placeholder {
+	res := make([]placeholder, 0)
+	tokens := sqllexer.Tokenize(sql)
+	adjacent := func(i, j int) bool {
+		return i >= 0 && j < len(tokens) && tokens[i].Pos+len(tokens[i].Text) == tokens[j].Pos
+	}
+
+	for i, t := range tokens {
+		switch {
+		case t.Kind == sqllexer.Punct && t.Text == "?":
+			if adjacent(i-1, i) && tokens[i-1].Is("->", "->>") {
+				continue
+			}
+			if adjacent(i, i+1) && tokens[i+1].Is("|", "&") {
+				continue
+			}
+			res = append(res, placeholder{style: placeholderPositional, start: t.Pos, end: t.Pos + 1})
+		case t.Kind == sqllexer.Word && numberedPlaceholder.MatchString(t.Text):
+			res = append(res, placeholder{style: placeholderNumbered, key: t.Text[1:], start: t.Pos, end: t.Pos + len(t.Text)})
+		case t.Is(":") && adjacent(i, i+1) && tokens[i+1].Kind == sqllexer.Word:
+			// ::int is a cast, not a placeholder
+			if adjacent(i-1, i) && tokens[i-1].Is(":") {
+				continue
+			}
+			next := tokens[i+1]
+			res = append(res, placeholder{style: placeholderNamed, key: next.Text, start: t.Pos, end: next.Pos + len(next.Text)})
+		case t.Is("@") && adjacent(i, i+1) && atPlaceholder.MatchString(tokens[i+1].Text):
+			next := tokens[i+1]
+			res = append(res, placeholder{style: placeholderAt, key: next.Text[1:], start: t.Pos, end: next.Pos + len(next.Text)})
+		}
+	}
+	return res
+}
+
+// placeholderCount returns the number of placeholders in a query
+func placeholderCount(sql string) int {
+	return len(findPlaceholders(sql))
+}
+
+// rewritePlaceholders rewrites the placeholders of a query to ? and orders the bindings the way they appear in the query
+//
+// For example:
+//
+// select * from users where id = :id and name = :name with {"name": "John", "id": 1}
+//
+// Returns: select * from users where id = ? and name = ? with [1, "John"]
+//
+// Positional bindings are used for ?, $1 and @p1 placeholders. $1 is the first binding, @p placeholders start at @p0 if the query has one and at @p1 otherwise.
+// Named bindings are used for :name placeholders. The same numbered or named placeholder can appear multiple times.
+func rewritePlaceholders(sql string, positional []any, named map[string]any) (string, []any, error) {
+	placeholders := findPlaceholders(sql)
+	if len(placeholders) == 0 {
+		if len(positional) != 0 || len(named) != 0 {
+			return "", nil, fmt.Errorf("explainer.rewritePlaceholders: argument number mismatch: 0 placeholders and the following bindings: %v", bindingsOf(positional, named))
+		}
+		return sql, positional, nil
+	}
+
+	style := placeholders[0].style
+	atBase := 1
+	for _, p := range placeholders {
+		if p.style != style {
+			return "", nil, fmt.Errorf("explainer.rewritePlaceholders: the query mixes placeholder styles: %s", sql)
+		}
+		if p.style == placeholderAt && p.key == "0" {
+			atBase = 0
+		}
+	}
+
+	if style == placeholderPositional {
+		if len(placeholders) != len(positional) {
+			return "", nil, fmt.Errorf("explainer.rewritePlaceholders: argument number mismatch: %d \"?\" and the following bindings: %v", len(placeholders), bindingsOf(positional, named))
+		}
+		return sql, positional, nil
+	}
+
+	var rewritten strings.Builder
+	bindings := make([]any, 0, len(placeholders))
+	last := 0
+	for _, p := range placeholders {
+		v, err := placeholderValue(p, atBase, positional, named)
+		if err != nil {
+			return "", nil, fmt.Errorf("explainer.rewritePlaceholders: %w", err)
+		}
+		bindings = append(bindings, v)
+		rewritten.WriteString(sql[last:p.start])
+		rewritten.WriteString("?")
+		last = p.end
+	}
+	rewritten.WriteString(sql[last:])
+	return rewritten.String(), bindings, nil
+}
+
+func placeholderValue(p placeholder, atBase int, positional []any, named map[string]any) (any, error) {
+	switch p.style {
+	case placeholderNamed:
+		if v, ok := named[p.key]; ok {
+			return v, nil
+		}
+		// Some loggers keep the colon in the keys
+		if v, ok := named[":"+p.key]; ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("no binding for :%s in %v", p.key, named)
+	default:
+		base := 1
+		prefix := "$"
+		if p.style == placeholderAt {
+			base, prefix = atBase, "@p"
+		}
+		n, _ := strconv.Atoi(p.key)
+		if n-base < 0 || n-base >= len(positional) {
+			return nil, fmt.Errorf("no binding for %s%s in %v", prefix, p.key, positional)
+		}
+		return positional[n-base], nil
+	}
+}
+
+func bindingsOf(positional []any, named map[string]any) any {
+	if len(named) != 0 {
+		return named
+	}
+	return positional
+}
@@ -0,0 +1,61 @@
+package explainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFindPlaceholders(t *testing.T) {
+	tests := []struct {
+		sql   string
+		count int
+	}{
+		{"select * from users where id = ? and name = ?", 2},
+		{"select * from users where name = '?' and id = ? -- ?", 1},
+		{"select data->? from users where data ?| array['a'] and id = ?", 1},
+		{"select * from users where id = :id and created_at > '10:30'", 1},
+		{"select id::text from users where id = $1 and name = $2", 2},
+		{"select * from users where id = @p1 and @rank := 1", 1},
+		{"select * from users", 0},
+	}
+	for _, tt := range tests {
+		assert.Len(t, findPlaceholders(tt.sql), tt.count, tt.sql)
+	}
+}
+
+func TestRewritePlaceholders(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		positional []any
+		named      map[string]any
+		wantSQL    string
+		want       []any
+	}{
+		{"positional", "select * from users where id = ?", []any{1}, nil, "select * from users where id = ?", []any{1}},
+		{"named", "select * from users where id = :id and name = :name or parent_id = :id", nil, map[string]any{"name": "John", "id": 1}, "select * from users where id = ? and name = ? or parent_id = ?", []any{1, "John", 1}},
+		{"named with colon keys", "select * from users where id = :id", nil, map[string]any{":id": 1}, "select * from users where id = ?", []any{1}},
+		{"numbered", "select * from users where name = $2 and id = $1", []any{1, "John"}, nil, "select * from users where name = ? and id = ?", []any{"John", 1}},
+		{"at one-based", "select * from users where id = @p1 and name = @p2", []any{1, "John"}, nil, "select * from users where id = ? and name = ?", []any{1, "John"}},
+		{"at zero-based", "select * from users where id = @p0 and name = @p1", []any{1, "John"}, nil, "select * from users where id = ? and name = ?", []any{1, "John"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, bindings, err := rewritePlaceholders(tt.sql, tt.positional, tt.named)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.want, bindings)
+		})
+	}
+}
+
+func TestRewritePlaceholders_Errors(t *testing.T) {
+	_, _, err := rewritePlaceholders("select * from users where id = ?", []any{1, 2}, nil)
+	assert.NotNil(t, err)
+	_, _, err = rewritePlaceholders("select * from users where id = :id", nil, map[string]any{"name": "John"})
+	assert.NotNil(t, err)
+	_, _, err = rewritePlaceholders("select * from users where id = $3", []any{1}, nil)
+	assert.NotNil(t, err)
+	_, _, err = rewritePlaceholders("select * from users where id = ? and name = :name", []any{1}, map[string]any{"name": "John"})
+	assert.NotNil(t, err)
+}
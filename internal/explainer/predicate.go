@@ -0,0 +1,268 @@
+package explainer
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+var (
+	// comparisonOperators compare the two sides of a predicate
+	comparisonOperators = []string{"=", "<", ">", "<=", ">=", "<>", "!=", "<=>", "like", "in", "between", "is", "regexp", "rlike"}
+
+	// predicateClauses are the clauses whose comparisons can use an index
+	predicateClauses = []string{"where", "on", "having"}
+
+	// operandBoundaries end the operand of a comparison
+	operandBoundaries = []string{"and", "or", "xor", "not", "when", "then", "else", "end", ","}
+
+	// expressionKeywords are the words of an expression that are not columns
+	expressionKeywords = []string{
+		"null", "true", "false", "interval", "and", "or", "not", "is", "as", "div", "mod", "like", "escape", "binary", "collate",
+		"case", "when", "then", "else", "end", "distinct", "unknown", "using",
+	}
+
+	// tableClauseEnd are the words after a table name in a FROM or JOIN clause that are not its alias
+	tableClauseEnd = []string{
+		"where", "join", "inner", "left", "right", "cross", "natural", "straight_join", "full", "outer", "on", "using",
+		"group", "order", "limit", "having", "union", "for", "force", "use", "ignore", "partition", "set", "window", "lock",
+		"into", "values", "select", "as",
+	}
+)
+
+// tableRef is a table of a query and the alias it's referred to by
+type tableRef struct {
+	name  string
+	alias string
+}
+
+// comparison is a predicate of a WHERE, ON or HAVING clause such as DATE(o.created_at) = ?
+type comparison struct {
+	left  []sqllexer.Token
+	op    string
+	right []sqllexer.Token
+}
+
+// columnRef is a column in an expression, qualifier is the table or alias before it if there is one
+type columnRef struct {
+	qualifier string
+	column    string
+}
+
+// queryTables returns the tables of the FROM, JOIN and UPDATE clauses of a query and its subqueries
+//
+// For example:
+//
+// select * from users u join orders as o on o.user_id = u.id
+//
+// Returns: {users u} {orders o}
+func queryTables(tokens []sqllexer.Token) []tableRef {
+	tables := make([]tableRef, 0)
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].Is("from", "join", "update", "straight_join") {
+			continue
+		}
+		i++
+		for i < len(tokens) {
+			ref, next, ok := tableAt(tokens, i)
+			if !ok {
+				break
+			}
+			tables = append(tables, ref)
+			i = next
+			// FROM users, orders
+			if i < len(tokens) && tokens[i].Is(",") {
+				i++
+				continue
+			}
+			break
+		}
+		i--
+	}
+	return tables
+}
+
+// tableAt parses a table name with an optional alias at tokens[i] and returns the index after it
+// Derived tables such as (select ...) t are not tables.
+func tableAt(tokens []sqllexer.Token, i int) (tableRef, int, bool) {
+	isName := func(i int) bool {
+		return i < len(tokens) && (tokens[i].Kind == sqllexer.Word || tokens[i].Kind == sqllexer.QuotedIdent)
+	}
+	if !isName(i) || tokens[i].Is("select", "lateral") {
+		return tableRef{}, i, false
+	}
+	ref := tableRef{name: tokens[i].Name()}
+	i++
+	// analytics.users
+	if i+1 < len(tokens) && tokens[i].Is(".") && isName(i+1) {
+		ref.name += "." + tokens[i+1].Name()
+		i += 2
+	}
+	ref.alias = ref.name[strings.LastIndex(ref.name, ".")+1:]
+
+	if i < len(tokens) && tokens[i].Is("as") {
+		i++
+	}
+	if isName(i) && (tokens[i].Kind == sqllexer.QuotedIdent || !slices.Contains(tableClauseEnd, strings.ToLower(tokens[i].Text))) {
+		ref.alias = tokens[i].Name()
+		i++
+	}
+	return ref, i, true
+}
+
+// comparisons returns the comparisons of the WHERE, ON and HAVING clauses of a query and its subqueries
+func comparisons(tokens []sqllexer.Token) []comparison {
+	res := make([]comparison, 0)
+	clause := ""
+	// clauses remembers the clause of the outer query when a subquery starts
+	clauses := make([]string, 0)
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			clauses = append(clauses, clause)
+			continue
+		case t.Is(")") && len(clauses) != 0:
+			clause = clauses[len(clauses)-1]
+			clauses = clauses[:len(clauses)-1]
+			continue
+		case t.Kind == sqllexer.Word && slices.Contains(clauseKeywords, strings.ToLower(t.Text)):
+			clause = strings.ToLower(t.Text)
+			continue
+		}
+		if !slices.Contains(predicateClauses, clause) || !t.Is(comparisonOperators...) {
+			continue
+		}
+		left := operandBefore(tokens, i)
+		right := operandAfter(tokens, i)
+		if len(left) == 0 || len(right) == 0 {
+			continue
+		}
+		res = append(res, comparison{left: left, op: strings.ToLower(t.Text), right: right})
+	}
+	return res
+}
+
+// operandBefore returns the tokens of the left side of the comparison operator at tokens[op]
+func operandBefore(tokens []sqllexer.Token, op int) []sqllexer.Token {
+	depth := 0
+	start := op
+	for j := op - 1; j >= 0; j-- {
+		t := tokens[j]
+		switch {
+		case t.Is(")"):
+			depth++
+		case t.Is("("):
+			if depth == 0 {
+				return tokens[start:op]
+			}
+			depth--
+		case depth == 0 && (t.Is(operandBoundaries...) || t.Is(comparisonOperators...) || t.Is(predicateClauses...)):
+			return tokens[start:op]
+		}
+		start = j
+	}
+	return tokens[start:op]
+}
+
+// operandAfter returns the tokens of the right side of the comparison operator at tokens[op]
+// The AND of a BETWEEN belongs to the operand.
+func operandAfter(tokens []sqllexer.Token, op int) []sqllexer.Token {
+	depth := 0
+	between := tokens[op].Is("between")
+	end := op + 1
+	for ; end < len(tokens); end++ {
+		t := tokens[end]
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			if depth == 0 {
+				return tokens[op+1 : end]
+			}
+			depth--
+		case depth == 0 && between && t.Is("and"):
+			between = false
+		case depth == 0 && t.Is("not") && end == op+1:
+			// IS NOT NULL
+		case depth == 0 && (t.Is(operandBoundaries...) || t.Is(comparisonOperators...) || t.Is(clauseKeywords...) || t.Is(";")):
+			return tokens[op+1 : end]
+		}
+	}
+	return tokens[op+1 : end]
+}
+
+// columnRefs returns the columns an expression refers to
+// Words followed by a parenthesis are functions, keywords such as NULL and INTERVAL are not columns.
+func columnRefs(tokens []sqllexer.Token) []columnRef {
+	refs := make([]columnRef, 0)
+	for i, t := range tokens {
+		if t.Kind != sqllexer.Word && t.Kind != sqllexer.QuotedIdent {
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].Is("(", ".") {
+			continue
+		}
+		if t.Kind == sqllexer.Word && slices.Contains(expressionKeywords, strings.ToLower(t.Text)) {
+			continue
+		}
+		// CAST(col AS DATE), CONVERT(col USING utf8mb4), col COLLATE utf8mb4_bin and INTERVAL 1 DAY
+		if i >= 1 && tokens[i-1].Is("as", "using", "collate") || i >= 2 && tokens[i-2].Is("interval") {
+			continue
+		}
+		ref := columnRef{column: t.Name()}
+		if i >= 2 && tokens[i-1].Is(".") {
+			ref.qualifier = tokens[i-2].Name()
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// isColumn reports whether the tokens are a single column such as email, u.email or `users`.`email`
+func isColumn(tokens []sqllexer.Token) bool {
+	return len(columnRefs(tokens)) == 1 && (len(tokens) == 1 || (len(tokens) == 3 && tokens[1].Is(".")))
+}
+
+// resolveTable returns the table of the query a column belongs to
+// A column without a qualifier belongs to the only table of the query. If there are more tables, it's ambiguous.
+func resolveTable(tables []tableRef, ref columnRef) (tableRef, bool) {
+	if len(ref.qualifier) == 0 {
+		if len(tables) == 1 {
+			return tables[0], true
+		}
+		return tableRef{}, false
+	}
+	for _, t := range tables {
+		if strings.EqualFold(t.alias, ref.qualifier) || strings.EqualFold(t.name, ref.qualifier) {
+			return t, true
+		}
+	}
+	return tableRef{}, false
+}
+
+// expressionText returns an expression without the qualifiers of its columns such as LOWER(email)
+func expressionText(tokens []sqllexer.Token) string {
+	var sb strings.Builder
+	for i, t := range tokens {
+		if i+1 < len(tokens) && tokens[i+1].Is(".") && (t.Kind == sqllexer.Word || t.Kind == sqllexer.QuotedIdent) {
+			continue
+		}
+		if t.Is(".") && i > 0 {
+			continue
+		}
+		if i > 0 && sb.Len() != 0 && needsSpace(tokens[i-1], t) {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(t.Text)
+	}
+	return sb.String()
+}
+
+// needsSpace reports whether two tokens of an expression are separated by a space when they are printed
+func needsSpace(prev, t sqllexer.Token) bool {
+	if t.Is("(", ")", ",") || prev.Is("(", ".") {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package explainer
+
+import (
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQueryTables(t *testing.T) {
+	tokens := sqllexer.Tokenize("select * from users u, `countries` join analytics.orders as o on o.user_id = u.id where u.id in (select user_id from bans) for update")
+	assert.Equal(t, []tableRef{
+		{name: "users", alias: "u"},
+		{name: "countries", alias: "countries"},
+		{name: "analytics.orders", alias: "o"},
+		{name: "bans", alias: "bans"},
+	}, queryTables(tokens))
+
+	tokens = sqllexer.Tokenize("select * from (select id from users) t join posts on posts.user_id = t.id")
+	assert.Equal(t, []tableRef{{name: "users", alias: "users"}, {name: "posts", alias: "posts"}}, queryTables(tokens))
+}
+
+func TestComparisons(t *testing.T) {
+	tokens := sqllexer.Tokenize("select * from orders o join users u on u.id = o.user_id where date(o.created_at) = ? and o.total between 10 and 20 and (o.status is not null or o.id in (1, 2)) order by o.id")
+	var texts []string
+	for _, c := range comparisons(tokens) {
+		texts = append(texts, expressionText(c.left)+" "+c.op+" "+expressionText(c.right))
+	}
+	assert.Equal(t, []string{
+		"id = user_id",
+		"date(created_at) = ?",
+		"total between 10 and 20",
+		"status is not null",
+		"id in (1, 2)",
+	}, texts)
+}
+
+func TestColumnRefs(t *testing.T) {
+	tokens := sqllexer.Tokenize("cast(u.created_at as date) + interval 1 day")
+	assert.Equal(t, []columnRef{{qualifier: "u", column: "created_at"}}, columnRefs(tokens))
+
+	assert.True(t, isColumn(sqllexer.Tokenize("`u`.`email`")))
+	assert.False(t, isColumn(sqllexer.Tokenize("lower(email)")))
+}
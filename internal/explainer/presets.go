@@ -0,0 +1,324 @@
+package explainer
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// presets parse the query logs of frameworks. The keys are the formats
+var presets = map[string]func(lines []string, writes bool, skipped skipStats) []Query{
+	FormatRails:     parseRailsLogs,
+	FormatDjango:    parseDjangoLogs,
+	FormatHibernate: parseHibernateLogs,
+	FormatGORM:      parseGORMLogs,
+	FormatEcto:      parseEctoLogs,
+}
+
+var (
+	// ansiColor matches the color codes of colorized logs such as Rails and GORM write to the terminal
+	ansiColor = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// railsQuery is an ActiveRecord log line such as: User Load (0.4ms)  SELECT ... [["id", 1]]
+	railsQuery = regexp.MustCompile(`\(([\d.]+)ms\)\s+(.*)$`)
+
+	// gormQuery is a GORM log line such as: [1.234ms] [rows:1] SELECT ...
+	gormQuery = regexp.MustCompile(`\[([\d.]+)ms\]\s+\[rows:[-\d]+\]\s+(.*)$`)
+
+	// djangoQuery is a django.db.backends log line such as: (0.001) SELECT ...; args=(1,); alias=default
+	djangoQuery = regexp.MustCompile(`\((\d+(?:\.\d+)?)\)\s+(.*?);\s*args=(.*?)(?:;\s*alias=\w+)?$`)
+
+	// hibernateQuery is a show_sql line (Hibernate: select ...) or an org.hibernate.SQL log line
+	hibernateQuery = regexp.MustCompile(`(?:\bHibernate:|\borg\.hibernate\.SQL\s*[:-]?)\s*(.*)$`)
+
+	// hibernateBinding is a BasicBinder log line of Hibernate 5 (binding parameter [1] as [BIGINT] - [1])
+	// or Hibernate 6 (binding parameter (1:BIGINT) <- [1])
+	hibernateBinding = regexp.MustCompile(`binding parameter (?:\[(\d+)\] as \[(\w+)\] -|\((\d+):(\w+)\) <-) \[(.*)\]\s*$`)
+
+	// ectoQuery is the line before the SQL in an Ecto log such as: QUERY OK source="users" db=1.2ms queue=0.1ms
+	ectoQuery = regexp.MustCompile(`\bQUERY (?:OK|ERROR)\b`)
+	ectoTime  = regexp.MustCompile(`\bdb=([\d.]+)ms`)
+	// ectoSigil is a date or time binding such as ~U[2024-12-13 20:05:44Z]
+	ectoSigil = regexp.MustCompile(`~[UNDT]\[([^\]]*)\]`)
+)
+
+// parseRailsLogs returns the SELECT queries of an ActiveRecord log
+//
+// For example:
+//
+// User Load (0.4ms)  SELECT `users`.* FROM `users` WHERE `users`.`id` = ? LIMIT ?  [["id", 1], ["LIMIT", 1]]
+//
+// Values that ActiveRecord doesn't bind are inlined in the query.
+func parseRailsLogs(lines []string, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	for _, line := range lines {
+		line = ansiColor.ReplaceAllString(line, "")
+		m := railsQuery.FindStringSubmatch(line)
+		if m == nil {
+			skipped.skipLine(line)
+			continue
+		}
+
+		sql := m[2]
+		var positional []any
+		if idx := bindingsIndex(sql); idx != -1 {
+			b, err := railsBindings(sql[idx:])
+			if err != nil {
+				log.Printf("skipping query: %s\n", err)
+				skipped.skip(skipInvalidBindings)
+				continue
+			}
+			sql, positional = sql[:idx], b
+		}
+		q, ok := selectQuery(sql, positional, nil, writes, skipped)
+		if !ok {
+			continue
+		}
+		q.Time, _ = strconv.ParseFloat(m[1], 64)
+		q.LoggedAt = prefixTime(strings.TrimSuffix(line, m[0]))
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// railsBindings returns the values of ActiveRecord binds such as [["id", 1], ["LIMIT", 1]]
+func railsBindings(block string) ([]any, error) {
+	p := bindingsParser{s: block}
+	if !p.accept('[') {
+		return nil, nil
+	}
+	binds, err := p.array()
+	if err != nil {
+		return nil, fmt.Errorf("explainer.railsBindings: %w: %s", err, block)
+	}
+
+	values := make([]any, 0, len(binds))
+	for _, b := range binds {
+		pair, ok := b.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("explainer.railsBindings: binds must be name and value pairs: %s", block)
+		}
+		values = append(values, flatBinding(pair[1]))
+	}
+	return values, nil
+}
+
+// parseGORMLogs returns the SELECT queries of a GORM log
+//
+// For example:
+//
+// [1.234ms] [rows:1] SELECT * FROM `users` WHERE id = 1
+//
+// GORM inlines the values into the query so there are no bindings. Use [Options.Parameterize] to extract them.
+func parseGORMLogs(lines []string, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	for _, line := range lines {
+		line = ansiColor.ReplaceAllString(line, "")
+		m := gormQuery.FindStringSubmatch(line)
+		if m == nil {
+			skipped.skipLine(line)
+			continue
+		}
+		q, ok := selectQuery(m[2], nil, nil, writes, skipped)
+		if !ok {
+			continue
+		}
+		q.Time, _ = strconv.ParseFloat(m[1], 64)
+		q.LoggedAt = prefixTime(strings.TrimSuffix(line, m[0]))
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// parseDjangoLogs returns the SELECT queries of a django.db.backends log
+//
+// For example:
+//
+// (0.001) SELECT `auth_user`.`id` FROM `auth_user` WHERE `auth_user`.`id` = 1 LIMIT 21; args=(1,); alias=default
+//
+// The duration is logged in seconds. Most database backends log the query with the values inlined,
+// args are only used if the query still has %s placeholders.
+func parseDjangoLogs(lines []string, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	for _, line := range lines {
+		m := djangoQuery.FindStringSubmatch(line)
+		if m == nil {
+			skipped.skipLine(line)
+			continue
+		}
+
+		sql := m[2]
+		var positional []any
+		if strings.Contains(sql, "%s") {
+			b, err := djangoArgs(m[3])
+			if err != nil {
+				log.Printf("skipping query: %s\n", err)
+				skipped.skip(skipInvalidBindings)
+				continue
+			}
+			sql = strings.ReplaceAll(strings.ReplaceAll(sql, "%s", "?"), "%%", "%")
+			positional = b
+		}
+		q, ok := selectQuery(sql, positional, nil, writes, skipped)
+		if !ok {
+			continue
+		}
+		secs, _ := strconv.ParseFloat(m[1], 64)
+		q.Time = secs * 1000
+		q.LoggedAt = prefixTime(strings.TrimSuffix(line, m[0]))
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// djangoArgs parses the Python tuple or list of the args such as (1, 'John', None)
+func djangoArgs(args string) ([]any, error) {
+	args = strings.TrimSpace(args)
+	if len(args) < 2 || !strings.ContainsAny(args[:1], "([") || !strings.ContainsAny(args[len(args)-1:], ")]") {
+		return nil, fmt.Errorf("explainer.djangoArgs: args must be a tuple or a list: %s", args)
+	}
+	// A one-element tuple has a trailing comma: (1,)
+	values := strings.TrimSuffix(strings.TrimSpace(args[1:len(args)-1]), ",")
+	positional, _, err := parseBindings("[" + values + "]")
+	if err != nil {
+		return nil, fmt.Errorf("explainer.djangoArgs: %w", err)
+	}
+	return positional, nil
+}
+
+// parseHibernateLogs returns the SELECT queries of a Hibernate log
+//
+// Queries are either printed by show_sql (Hibernate: select ...) or logged by the org.hibernate.SQL logger.
+// Queries printed by format_sql span the indented lines after them. Bindings are read from the
+// binding parameter lines of the BasicBinder logger that follow the query and typed by their JDBC type.
+//
+// Hibernate doesn't log the duration of queries.
+func parseHibernateLogs(lines []string, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	var sql strings.Builder
+	var bindings []any
+	var at time.Time
+	pending, bound := false, false
+
+	flush := func() {
+		if !pending {
+			return
+		}
+		if q, ok := selectQuery(strings.TrimSpace(sql.String()), bindings, nil, writes, skipped); ok {
+			q.LoggedAt = at
+			queries = append(queries, q)
+		}
+		sql.Reset()
+		bindings, pending, bound = nil, false, false
+	}
+
+	for _, line := range lines {
+		if m := hibernateBinding.FindStringSubmatch(line); m != nil && pending {
+			n, typ := m[1], m[2]
+			if n == "" {
+				n, typ = m[3], m[4]
+			}
+			i, _ := strconv.Atoi(n)
+			for len(bindings) < i {
+				bindings = append(bindings, nil)
+			}
+			if i > 0 {
+				bindings[i-1] = hibernateValue(typ, m[5])
+			}
+			bound = true
+			continue
+		}
+		if m := hibernateQuery.FindStringSubmatch(line); m != nil {
+			flush()
+			pending = true
+			at = prefixTime(strings.TrimSuffix(line, m[0]))
+			sql.WriteString(m[1])
+			continue
+		}
+		// format_sql writes the query on the indented lines after the marker
+		if pending && !bound && len(line) != 0 && (line[0] == ' ' || line[0] == '\t') {
+			sql.WriteString("\n")
+			sql.WriteString(line)
+			continue
+		}
+		skipped.skipLine(line)
+	}
+	flush()
+	return queries
+}
+
+// hibernateValue converts a logged binding into a value based on its JDBC type
+func hibernateValue(typ, v string) any {
+	if v == "null" || v == "<null>" {
+		return nil
+	}
+	switch strings.ToUpper(typ) {
+	case "BIGINT", "INTEGER", "SMALLINT", "TINYINT":
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case "DOUBLE", "FLOAT", "REAL", "NUMERIC", "DECIMAL":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case "BOOLEAN", "BIT":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// parseEctoLogs returns the SELECT queries of an Ecto log
+//
+// For example:
+//
+// [debug] QUERY OK source="users" db=1.2ms queue=0.1ms idle=1000.0ms
+// SELECT u0.`id`, u0.`name` FROM `users` AS u0 WHERE (u0.`id` = ?) [1]
+//
+// The duration is the db time of the QUERY line, the query and its bindings are on the next line.
+func parseEctoLogs(lines []string, writes bool, skipped skipStats) []Query {
+	queries := make([]Query, 0)
+	next := false
+	var ms float64
+	var at time.Time
+	for _, line := range lines {
+		line = ansiColor.ReplaceAllString(line, "")
+		if loc := ectoQuery.FindStringIndex(line); loc != nil {
+			next, ms = true, 0
+			at = prefixTime(line[:loc[0]])
+			if m := ectoTime.FindStringSubmatch(line); m != nil {
+				ms, _ = strconv.ParseFloat(m[1], 64)
+			}
+			continue
+		}
+		if !next {
+			skipped.skipLine(line)
+			continue
+		}
+		next = false
+
+		sql := ectoSigil.ReplaceAllString(line, `"$1"`)
+		var positional []any
+		if idx := bindingsIndex(sql); idx != -1 {
+			b, _, err := parseBindings(sql[idx:])
+			if err != nil {
+				log.Printf("skipping query: %s\n", err)
+				skipped.skip(skipInvalidBindings)
+				continue
+			}
+			sql, positional = sql[:idx], b
+		}
+		q, ok := selectQuery(sql, positional, nil, writes, skipped)
+		if !ok {
+			continue
+		}
+		q.Time = ms
+		q.LoggedAt = at
+		queries = append(queries, q)
+	}
+	return queries
+}
@@ -0,0 +1,149 @@
+package explainer
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRailsLogs(t *testing.T) {
+	lines := []string{
+		"Started GET \"/users/1\" for 127.0.0.1 at 2024-12-13 20:05:44 +0100",
+		"  \x1b[1m\x1b[36mUser Load (0.4ms)\x1b[0m  \x1b[1m\x1b[34mSELECT `users`.* FROM `users` WHERE `users`.`id` = ? LIMIT ?\x1b[0m  [[\"id\", 1], [\"LIMIT\", 1]]",
+		"  ↳ app/controllers/users_controller.rb:5:in `show'",
+		"  Post Load (1.2ms)  SELECT `posts`.* FROM `posts` WHERE `posts`.`user_id` = 1 AND `posts`.`deleted_at` IS NULL",
+		"  User Update (0.8ms)  UPDATE `users` SET `users`.`name` = ? WHERE `users`.`id` = ?  [[\"name\", \"John\"], [\"id\", 1]]",
+		"  Comment Load (0.3ms)  SELECT `comments`.* FROM `comments` WHERE `comments`.`body` = ?  [[nil, \"Doe, John\"]]",
+	}
+	queries := parseRailsLogs(lines, false, nil)
+	assert.Len(t, queries, 3)
+
+	assert.Equal(t, "SELECT `users`.* FROM `users` WHERE `users`.`id` = ? LIMIT ?", queries[0].SQL)
+	assert.Equal(t, []any{int64(1), int64(1)}, queries[0].Bindings)
+	assert.Equal(t, 0.4, queries[0].Time)
+
+	assert.Equal(t, "SELECT `posts`.* FROM `posts` WHERE `posts`.`user_id` = 1 AND `posts`.`deleted_at` IS NULL", queries[1].SQL)
+	assert.Empty(t, queries[1].Bindings)
+	assert.Equal(t, 1.2, queries[1].Time)
+
+	assert.Equal(t, []any{"Doe, John"}, queries[2].Bindings)
+}
+
+func TestParseGORMLogs(t *testing.T) {
+	lines := []string{
+		"2024/12/13 20:05:44 /app/users.go:42",
+		"\x1b[33m[1.234ms] \x1b[34;1m[rows:1]\x1b[0m SELECT * FROM `users` WHERE id = 1 ORDER BY `users`.`id` LIMIT 1",
+		"2024/12/13 20:05:45 /app/users.go:50 SLOW SQL >= 200ms",
+		"[250.100ms] [rows:-] SELECT * FROM `orders` WHERE status = 'pending'",
+		"[0.500ms] [rows:1] INSERT INTO `users` (`name`) VALUES ('John')",
+	}
+	queries := parseGORMLogs(lines, false, nil)
+	assert.Len(t, queries, 2)
+
+	assert.Equal(t, "SELECT * FROM `users` WHERE id = 1 ORDER BY `users`.`id` LIMIT 1", queries[0].SQL)
+	assert.Equal(t, 1.234, queries[0].Time)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE status = 'pending'", queries[1].SQL)
+	assert.Equal(t, 250.1, queries[1].Time)
+}
+
+func TestParseDjangoLogs(t *testing.T) {
+	lines := []string{
+		"(0.001) SELECT `auth_user`.`id` FROM `auth_user` WHERE `auth_user`.`id` = 1 LIMIT 21; args=(1,); alias=default",
+		"(0.020) SELECT `blog_post`.`id` FROM `blog_post` WHERE (`blog_post`.`title` LIKE %s AND `blog_post`.`draft` = %s); args=('%django%', False)",
+		"(0.002) UPDATE `auth_user` SET `last_login` = '2024-12-13 20:05:44' WHERE `auth_user`.`id` = 1; args=('2024-12-13 20:05:44', 1); alias=default",
+	}
+	queries := parseDjangoLogs(lines, false, nil)
+	assert.Len(t, queries, 2)
+
+	assert.Equal(t, "SELECT `auth_user`.`id` FROM `auth_user` WHERE `auth_user`.`id` = 1 LIMIT 21", queries[0].SQL)
+	assert.Empty(t, queries[0].Bindings)
+	assert.Equal(t, 1.0, queries[0].Time)
+
+	assert.Equal(t, "SELECT `blog_post`.`id` FROM `blog_post` WHERE (`blog_post`.`title` LIKE ? AND `blog_post`.`draft` = ?)", queries[1].SQL)
+	assert.Equal(t, []any{"%django%", false}, queries[1].Bindings)
+	assert.Equal(t, 20.0, queries[1].Time)
+}
+
+func TestParseHibernateLogs(t *testing.T) {
+	lines := []string{
+		"Hibernate: select u1_0.id,u1_0.name from users u1_0 where u1_0.id=?",
+		"2024-12-13 20:05:44.123 TRACE 1 --- [main] o.h.type.descriptor.sql.BasicBinder : binding parameter [1] as [BIGINT] - [42]",
+		"2024-12-13 20:05:45.001 DEBUG 1 --- [main] org.hibernate.SQL                        : ",
+		"    select",
+		"        p1_0.id",
+		"    from",
+		"        posts p1_0",
+		"    where",
+		"        p1_0.title=?",
+		"        and p1_0.published=?",
+		"2024-12-13 20:05:45.002 TRACE 1 --- [main] org.hibernate.orm.jdbc.bind : binding parameter (1:VARCHAR) <- [Hello, World]",
+		"2024-12-13 20:05:45.002 TRACE 1 --- [main] org.hibernate.orm.jdbc.bind : binding parameter (2:BOOLEAN) <- [true]",
+		"Hibernate: insert into users (name,id) values (?,?)",
+	}
+	queries := parseHibernateLogs(lines, false, nil)
+	assert.Len(t, queries, 2)
+
+	assert.Equal(t, "select u1_0.id,u1_0.name from users u1_0 where u1_0.id=?", queries[0].SQL)
+	assert.Equal(t, []any{int64(42)}, queries[0].Bindings)
+	// show_sql prints no timestamp
+	assert.True(t, queries[0].LoggedAt.IsZero())
+
+	assert.Contains(t, queries[1].SQL, "p1_0.title=?")
+	assert.Equal(t, []any{"Hello, World", true}, queries[1].Bindings)
+	assert.Equal(t, time.Date(2024, 12, 13, 20, 5, 45, 1_000_000, time.UTC), queries[1].LoggedAt)
+}
+
+func TestParseEctoLogs(t *testing.T) {
+	lines := []string{
+		"20:05:44.123 [debug] QUERY OK source=\"users\" db=1.2ms queue=0.1ms idle=1000.0ms",
+		"SELECT u0.`id`, u0.`name` FROM `users` AS u0 WHERE (u0.`id` = ?) [1]",
+		"20:05:44.200 [debug] QUERY OK source=\"posts\" db=3.5ms",
+		"SELECT p0.`id` FROM `posts` AS p0 WHERE (p0.`inserted_at` > ?) AND (p0.`author` = ?) [~U[2024-12-13 20:05:44Z], nil]",
+		"20:05:44.300 [debug] QUERY OK db=0.4ms",
+		"INSERT INTO `users` (`name`) VALUES (?) [\"John\"]",
+	}
+	queries := parseEctoLogs(lines, false, nil)
+	assert.Len(t, queries, 2)
+
+	assert.Equal(t, "SELECT u0.`id`, u0.`name` FROM `users` AS u0 WHERE (u0.`id` = ?)", queries[0].SQL)
+	assert.Equal(t, []any{int64(1)}, queries[0].Bindings)
+	assert.Equal(t, 1.2, queries[0].Time)
+
+	assert.Equal(t, []any{"2024-12-13 20:05:44Z", nil}, queries[1].Bindings)
+	assert.Equal(t, 3.5, queries[1].Time)
+}
+
+func TestOptionsParse_Preset(t *testing.T) {
+	lines := []string{
+		"  User Load (0.4ms)  SELECT `users`.* FROM `users` WHERE `users`.`id` = ?  [[\"id\", 1]]",
+		"  User Load (0.9ms)  SELECT `users`.* FROM `users` WHERE `users`.`id` = ?  [[\"id\", 2]]",
+	}
+	queries, _, err := Options{Format: FormatRails}.parse(lines)
+	assert.Nil(t, err)
+	assert.Len(t, queries, 1)
+	assert.Equal(t, []any{int64(2)}, queries[0].Bindings)
+
+	_, _, err = Options{Format: "unknown"}.parse(lines)
+	assert.NotNil(t, err)
+}
+
+func TestOptionsParse_PresetBursts(t *testing.T) {
+	lines := make([]string, 0)
+	for i, at := range []string{"20:05:44,100", "20:05:44,200", "20:05:44,300", "20:15:00,100", "20:15:00,200"} {
+		lines = append(lines, fmt.Sprintf("[2024-12-13 %s] DEBUG (0.001) SELECT `orders`.`id` FROM `orders` WHERE `orders`.`user_id` = %%s; args=(%d,); alias=default", at, i+1))
+	}
+	queries, _, err := Options{Format: FormatDjango}.parse(lines)
+	assert.Nil(t, err)
+	assert.Len(t, queries, 1)
+	assert.Equal(t, time.Date(2024, 12, 13, 20, 15, 0, 200_000_000, time.UTC), queries[0].LoggedAt)
+	// The requests are 10 minutes apart so they are not one burst
+	assert.Equal(t, 0, queries[0].Burst.Count)
+
+	lines[3] = strings.Replace(lines[3], "20:15:00,100", "20:05:44,400", 1)
+	lines[4] = strings.Replace(lines[4], "20:15:00,200", "20:05:44,500", 1)
+	queries, _, err = Options{Format: FormatDjango}.parse(lines)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, queries[0].Burst.Count)
+}
@@ -0,0 +1,240 @@
+package explainer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+// maxEntryBytes is the size above which a log entry is reported and skipped instead of being explained
+const maxEntryBytes = 1024 * 1024
+
+var (
+	// statementStart finds where the SQL statement starts in a log line such as "[2024-12-13 20:05:44] local.INFO: select ..."
+	statementStart = regexp.MustCompile(`(?i)\b(select|insert|update|delete|replace|with)\b`)
+
+	// continuationWords can't start a statement so a line starting with them belongs to the previous statement
+	continuationWords = []string{
+		"from", "where", "and", "or", "not", "join", "inner", "left", "right", "cross", "straight_join", "natural",
+		"on", "using", "group", "order", "having", "limit", "offset", "union", "intersect", "except", "window",
+		"for", "lock", "into", "values", "set", "as", "when", "then", "else", "end", "case", "between", "in", "is",
+		"like", "asc", "desc", "by",
+	}
+
+	// openWords and openPuncts expect the statement to continue when they are the last token of a line
+	openWords  = append([]string{"select", "all", "distinct", "with"}, continuationWords...)
+	openPuncts = []string{",", ".", "=", "<", ">", "<=", ">=", "<>", "!=", "<=>", "+", "-", "/", "||", "&&"}
+)
+
+// entryReader joins the lines of a log into entries where every entry is either a full SQL statement or a line of other text
+type entryReader struct {
+	r       *bufio.Reader
+	entries []string
+
+	entry     strings.Builder
+	entryLine int
+	entrySize int
+	// sqlStart is the position of the statement in the entry, -1 if the entry doesn't contain one
+	sqlStart int
+	// depth is the number of open parentheses in the statement
+	depth int
+	// openFrom is the position of a string, quoted identifier or block comment that is not closed yet, -1 if there's none
+	openFrom int
+	// last is the last token of the statement
+	last sqllexer.Token
+}
+
+// readQueries reads the log entries of r
+//
+// A statement spread over multiple lines is joined into one entry. A line continues the statement of the previous lines if:
+//   - The previous lines end inside a string, a quoted identifier, a block comment or parentheses
+//   - The previous lines end with a keyword or an operator such as WHERE or =
+//   - It's indented
+//   - It starts with a keyword that can't start a statement such as FROM, WHERE or ORDER
+//
+// Lines of any length are read. Entries longer than [maxEntryBytes] are reported and skipped.
+func readQueries(r io.Reader) ([]string, error) {
+	er := newEntryReader()
+	er.r = bufio.NewReader(r)
+
+	for lineNo := 1; ; lineNo++ {
+		line, size, err := readLine(er.r)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("explainer.readQueries: line %d: %w", lineNo, err)
+		}
+		if errors.Is(err, io.EOF) && size == 0 {
+			break
+		}
+
+		er.add(line, size, lineNo)
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	er.flush()
+	return er.entries, nil
+}
+
+func newEntryReader() *entryReader {
+	return &entryReader{
+		entries:  make([]string, 0),
+		sqlStart: -1,
+		openFrom: -1,
+	}
+}
+
+// add adds the next line of the log. The current entry is finished first if the line doesn't continue it
+func (er *entryReader) add(line string, size, lineNo int) {
+	if er.entryLine == 0 || !er.continues(line) {
+		er.flush()
+		er.entryLine = lineNo
+	}
+	er.append(line, size)
+}
+
+// take returns the finished entries and removes them from the reader
+func (er *entryReader) take() []string {
+	entries := er.entries
+	er.entries = make([]string, 0)
+	return entries
+}
+
+// readLine reads a line of any length but only keeps the first [maxEntryBytes]+1 bytes of it
+// size is the full length of the line
+func readLine(r *bufio.Reader) (string, int, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			chunk = bytes.TrimSuffix(bytes.TrimSuffix(chunk, []byte("\n")), []byte("\r"))
+		}
+		size += len(chunk)
+		if keep := maxEntryBytes + 1 - len(line); keep > 0 {
+			line = append(line, chunk[:min(keep, len(chunk))]...)
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return string(line), size, err
+		}
+	}
+}
+
+// readLines reads the lines of r without joining them
+// Lines longer than [maxEntryBytes] are reported and skipped.
+func readLines(r io.Reader) ([]string, error) {
+	br := bufio.NewReader(r)
+	lines := make([]string, 0)
+	for lineNo := 1; ; lineNo++ {
+		line, size, err := readLine(br)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("explainer.readLines: line %d: %w", lineNo, err)
+		}
+		if size > maxEntryBytes {
+			log.Printf("line %d: skipping a line of %d bytes because it's longer than the limit of %d bytes: %.100s...\n", lineNo, size, maxEntryBytes, line)
+		} else if size != 0 || err == nil {
+			lines = append(lines, line)
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+	}
+}
+
+// continues reports whether the line belongs to the statement of the current entry
+func (er *entryReader) continues(line string) bool {
+	if er.sqlStart == -1 {
+		return false
+	}
+	if er.openFrom != -1 || er.depth > 0 {
+		return true
+	}
+	if strings.TrimSpace(line) == "" || er.last.Is(";") || er.last.Is("]") {
+		return false
+	}
+	if er.last.Kind == sqllexer.Punct && slices.Contains(openPuncts, er.last.Text) {
+		return true
+	}
+	if er.last.Kind == sqllexer.Word && slices.Contains(openWords, strings.ToLower(er.last.Text)) {
+		return true
+	}
+	if line[0] == ' ' || line[0] == '\t' || line[0] == ')' || line[0] == ',' {
+		return true
+	}
+	words := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '('
+	})
+	return len(words) != 0 && slices.Contains(continuationWords, strings.ToLower(words[0]))
+}
+
+// append adds a line to the current entry and updates the state of its statement
+func (er *entryReader) append(line string, size int) {
+	offset := 0
+	if er.entry.Len() != 0 {
+		er.entry.WriteByte('\n')
+		er.entrySize++
+		offset = er.entry.Len()
+	}
+	er.entrySize += size
+	if er.entrySize > maxEntryBytes {
+		// The rest of an oversized statement is not tracked so it ends here
+		er.depth, er.openFrom = 0, -1
+		return
+	}
+	er.entry.WriteString(line)
+
+	// Only the statement is tokenized so an apostrophe in the log prefix doesn't open a string
+	if er.sqlStart == -1 {
+		loc := statementStart.FindStringIndex(line)
+		if loc == nil {
+			return
+		}
+		er.sqlStart = offset + loc[0]
+		offset = er.sqlStart
+	}
+	if er.openFrom != -1 {
+		offset = er.openFrom
+	}
+
+	entry := er.entry.String()
+	tokens := sqllexer.TokenizeWithComments(entry[offset:])
+	er.openFrom = -1
+	for _, t := range tokens {
+		switch {
+		case t.Is("("):
+			er.depth++
+		case t.Is(")"):
+			er.depth = max(er.depth-1, 0)
+		}
+		if t.Kind != sqllexer.Comment {
+			er.last = t
+		}
+	}
+	if len(tokens) != 0 && tokens[len(tokens)-1].Unterminated() {
+		er.openFrom = offset + tokens[len(tokens)-1].Pos
+	}
+}
+
+// flush finishes the current entry
+func (er *entryReader) flush() {
+	switch {
+	case er.entryLine == 0:
+	case er.entrySize > maxEntryBytes:
+		log.Printf("line %d: skipping a log entry of %d bytes because it's longer than the limit of %d bytes: %.100s...\n", er.entryLine, er.entrySize, maxEntryBytes, er.entry.String())
+	default:
+		er.entries = append(er.entries, er.entry.String())
+	}
+
+	er.entry.Reset()
+	er.entryLine, er.entrySize = 0, 0
+	er.sqlStart, er.openFrom, er.depth = -1, -1, 0
+	er.last = sqllexer.Token{}
+}
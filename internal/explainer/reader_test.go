@@ -0,0 +1,63 @@
+package explainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReadQueries_MultiLine(t *testing.T) {
+	logs := strings.Join([]string{
+		"[2024-12-13 20:05:44] local.INFO: select *",
+		"from `page_views`",
+		"  where `id` = ?",
+		"  and `uri` in (",
+		"select uri from pages",
+		") [100]",
+		"[2024-12-13 20:05:45] local.INFO: User's dashboard loaded",
+		"select * from users where name = 'multi",
+		"line' /* a",
+		"comment */ and id =",
+		"1;",
+		"select * from `sites` {\"time\":1.2}",
+		"[2024-12-13 20:05:46] local.INFO: select 1",
+	}, "\n")
+
+	queries, err := readQueries(strings.NewReader(logs))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"[2024-12-13 20:05:44] local.INFO: select *\nfrom `page_views`\n  where `id` = ?\n  and `uri` in (\nselect uri from pages\n) [100]",
+		"[2024-12-13 20:05:45] local.INFO: User's dashboard loaded",
+		"select * from users where name = 'multi\nline' /* a\ncomment */ and id =\n1;",
+		"select * from `sites` {\"time\":1.2}",
+		"[2024-12-13 20:05:46] local.INFO: select 1",
+	}, queries)
+}
+
+func TestReadQueries_LongLines(t *testing.T) {
+	long := "select * from users where id in (" + strings.Repeat("1,", 50_000) + "1)"
+	oversized := "select * from users where id in (" + strings.Repeat("1,", maxEntryBytes) + "1)"
+	logs := strings.Join([]string{long, oversized, "select * from posts"}, "\n")
+
+	queries, err := readQueries(strings.NewReader(logs))
+	assert.Nil(t, err)
+	assert.Len(t, queries, 2)
+	assert.Equal(t, long, queries[0])
+	assert.Equal(t, "select * from posts", queries[1])
+}
+
+func TestEntryReader_Add(t *testing.T) {
+	er := newEntryReader()
+	er.add("select *", 8, 1)
+	er.add("from users", 10, 2)
+	er.add("  where id = 1", 14, 3)
+	assert.Empty(t, er.take())
+
+	// The next statement finishes the previous one
+	er.add("select 1", 8, 4)
+	assert.Equal(t, []string{"select *\nfrom users\n  where id = 1"}, er.take())
+	assert.Empty(t, er.take())
+
+	er.flush()
+	assert.Equal(t, []string{"select 1"}, er.take())
+}
@@ -0,0 +1,97 @@
+package explainer
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+// rewrittenQueryCode is the code of the note of an EXPLAIN that contains the query the way the optimizer rewrote it
+const rewrittenQueryCode = 1003
+
+var (
+	// whereSubquery finds IN (SELECT ...) and EXISTS (SELECT ...) subqueries
+	whereSubquery = regexp.MustCompile(`(?i)\b(?:in|exists)\s*\(\s*select\b`)
+
+	// semiJoin finds the semi-joins and anti-joins in a rewritten query such as: from `db`.`users` semi join (`db`.`orders`)
+	semiJoin = regexp.MustCompile(`(?i)\b(semi|anti) join\b`)
+
+	// rewrittenCast is a conversion the optimizer adds to a column such as cast(`db`.`users`.`phone` as double)
+	rewrittenCast = regexp.MustCompile("(?i)cast\\(`[^`]+`\\.`([^`]+)`\\.`([^`]+)` as ([\\w ()]+?)\\)")
+)
+
+// rewrittenSQL returns the query the optimizer rewrote from the notes of an EXPLAIN
+// It's empty if the notes don't contain it.
+func rewrittenSQL(warnings []ExplainWarning) string {
+	for _, w := range warnings {
+		if w.Code == rewrittenQueryCode {
+			return w.Message
+		}
+	}
+	return ""
+}
+
+// checkSemiJoin checks whether the optimizer turned the IN (SELECT ...) and EXISTS (SELECT ...) subqueries into joins
+//
+// A subquery that is converted into a semi-join (IN, EXISTS) or an anti-join (NOT IN, NOT EXISTS) is joined with
+// the outer query like any other table. One that isn't is a dependent subquery that runs once for every row of the outer query.
+func (r *Result) checkSemiJoin() {
+	if len(r.explain.RewrittenSQL) == 0 || !whereSubquery.MatchString(r.explain.Query.SQL) {
+		return
+	}
+
+	if m := semiJoin.FindStringSubmatch(r.explain.RewrittenSQL); m != nil {
+		r.semiJoinWarning = "MySQL converts the subquery into a " + strings.ToLower(m[1]) + "-join. It's joined with the outer query like a table instead of running once for every row."
+		return
+	}
+
+	dependent := slices.ContainsFunc(r.explain.Plan, func(row ExplainRow) bool {
+		return strings.EqualFold(row.SelectType.String, "dependent subquery")
+	})
+	if !dependent {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 1)
+	r.semiJoinWarning = "MySQL can't convert the subquery into a semi-join so it runs once for every row of the outer query. A subquery with LIMIT, GROUP BY, UNION or aggregate functions, or one in an OR condition can't be converted. Rewrite it as a JOIN or move the other conditions out of the subquery."
+}
+
+// checkRemovedConditions checks for WHERE clauses the optimizer found to be always true or always false
+//
+// For example:
+//
+// select * from users where id = 1 or 1 = 1
+//
+// The optimizer removes conditions that are always true, such as col IS NOT NULL on a NOT NULL column,
+// so a WHERE clause that is always true disappears from the rewritten query and the query reads every row.
+func (r *Result) checkRemovedConditions() {
+	for _, row := range r.explain.Plan {
+		extra := strings.TrimSpace(row.Extra.String)
+		// "Impossible WHERE noticed after reading const tables" depends on the data: the row found by the bindings
+		// doesn't match the other conditions. Other bindings can return rows.
+		if strings.HasPrefix(strings.ToLower(extra), "impossible where noticed after reading const tables") {
+			return
+		}
+		if strings.EqualFold(extra, "impossible where") {
+			r.removedConditionWarning = "The WHERE clause is always false so the query returns no rows without reading the table. Check the conditions and the bindings, for example a column compared with NULL using = instead of IS NULL is never true."
+			return
+		}
+	}
+
+	if len(r.explain.RewrittenSQL) == 0 {
+		return
+	}
+	// The rows of const tables are read while the query is optimized and their conditions are replaced with their values
+	constTable := slices.ContainsFunc(r.explain.Plan, func(row ExplainRow) bool {
+		return row.QueryType.String == "const" || row.QueryType.String == "system"
+	})
+	if constTable {
+		return
+	}
+	if hasWordAtTop(sqllexer.Tokenize(r.explain.Query.SQL), "where") && !hasWordAtTop(sqllexer.Tokenize(r.explain.RewrittenSQL), "where") {
+		r.grade = grade.Dec(r.grade, 0.5)
+		r.removedConditionWarning = "The optimizer removed the WHERE clause because it's always true, so the query reads every row. Conditions such as col IS NOT NULL on a NOT NULL column or OR 1 = 1 don't filter anything. Check that the query filters what it should."
+	}
+}
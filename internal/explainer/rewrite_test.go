@@ -0,0 +1,73 @@
+package explainer
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRewrittenSQL(t *testing.T) {
+	warnings := []ExplainWarning{
+		{Level: "Warning", Code: 1739, Message: "Cannot use ref access on index 'idx_phone' due to type or collation conversion on field 'phone'"},
+		{Level: "Note", Code: 1003, Message: "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users`"},
+	}
+	assert.Equal(t, "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users`", rewrittenSQL(warnings))
+	assert.Empty(t, rewrittenSQL(nil))
+}
+
+func TestCheckSemiJoin(t *testing.T) {
+	res := newResult(ExplainResult{
+		Query:        newQuery("select * from users where id in (select user_id from orders where total > ?)"),
+		RewrittenSQL: "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users` semi join (`app`.`orders`) where ((`app`.`users`.`id` = `app`.`orders`.`user_id`) and (`app`.`orders`.`total` > 100))",
+	})
+	res.checkSemiJoin()
+	assert.Contains(t, res.semiJoinWarning, "converts the subquery into a semi-join")
+	assert.Equal(t, float32(5), res.Grade())
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from users where id in (select user_id from orders order by total limit 10)"),
+		RewrittenSQL: "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users` where <in_optimizer>(`app`.`users`.`id`,<exists>(/* select#2 */ select 1 from `app`.`orders`))",
+		Plan: []ExplainRow{
+			{SelectType: sql.NullString{String: "PRIMARY", Valid: true}},
+			{SelectType: sql.NullString{String: "DEPENDENT SUBQUERY", Valid: true}},
+		},
+	})
+	res.checkSemiJoin()
+	assert.Contains(t, res.semiJoinWarning, "runs once for every row of the outer query")
+	assert.Equal(t, float32(4), res.Grade())
+}
+
+func TestCheckRemovedConditions(t *testing.T) {
+	res := newResult(ExplainResult{
+		Query:        newQuery("select * from users where email is not null"),
+		RewrittenSQL: "/* select#1 */ select `app`.`users`.`id` AS `id` from `app`.`users`",
+		Plan:         []ExplainRow{{QueryType: sql.NullString{String: "ALL", Valid: true}}},
+	})
+	res.checkRemovedConditions()
+	assert.Contains(t, res.removedConditionWarning, "removed the WHERE clause")
+	assert.Equal(t, float32(4.5), res.Grade())
+
+	res = newResult(ExplainResult{
+		Query: newQuery("select * from users where id = null"),
+		Plan:  []ExplainRow{{Extra: sql.NullString{String: "Impossible WHERE", Valid: true}}},
+	})
+	res.checkRemovedConditions()
+	assert.Contains(t, res.removedConditionWarning, "always false")
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from users where id = 1 and active = 1"),
+		RewrittenSQL: "/* select#1 */ select NULL AS `id` from `app`.`users` where multiple equal(1, NULL)",
+		Plan:         []ExplainRow{{Extra: sql.NullString{String: "Impossible WHERE noticed after reading const tables", Valid: true}}},
+	})
+	res.checkRemovedConditions()
+	assert.Empty(t, res.removedConditionWarning)
+	assert.Equal(t, float32(5), res.Grade())
+
+	res = newResult(ExplainResult{
+		Query:        newQuery("select * from users where id = 1"),
+		RewrittenSQL: "/* select#1 */ select '1' AS `id` from `app`.`users` where true",
+		Plan:         []ExplainRow{{QueryType: sql.NullString{String: "const", Valid: true}}},
+	})
+	res.checkRemovedConditions()
+	assert.Empty(t, res.removedConditionWarning)
+}
@@ -1,80 +1,240 @@
 package explainer
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmartinjoo/explainer/internal/platform"
 )
 
-func runExplainQueries(db *sql.DB, queries []Query) ([]ExplainResult, error) {
-	res := make([]ExplainResult, 0)
-	for i, q := range queries {
-		rows, err := db.Query(q.AsExplain(), q.Bindings...)
-		if err != nil && strings.Contains(err.Error(), "Too many connections") {
-			return res, newTooManyConnectionsError(i, q.SQL)
+// runExplainQueries runs the EXPLAIN of the queries on at most [Options.Concurrency] connections at a time
+//
+// The results are in the order of the queries. Queries that fail with a transient error such as a deadlock
+// are retried [Options.Retries] times, queries that still fail are left out and returned grouped by their error class.
+// If the database runs out of connections the remaining queries are not run and the results so far
+// are returned with a [TooManyConnectionsError]. If ctx is canceled the remaining queries are not run either.
+func runExplainQueries(ctx context.Context, db *sql.DB, queries []Query, opts Options) ([]ExplainResult, explainFailures, error) {
+	explains := make([]*ExplainResult, len(queries))
+	failures := make(explainFailures)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		connErr error
+	)
+
+	jobs := make(chan int)
+	for range max(opts.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				q := queries[i]
+				explain, attempts, err := explainWithRetry(ctx, db, q, opts)
+				// Queries canceled by ctx are not errors
+				if err != nil && ctx.Err() != nil {
+					continue
+				}
+				if err != nil {
+					qErr := newQueryError(q, err, attempts)
+					mu.Lock()
+					failures.add(qErr)
+					if qErr.class == errorTooManyConnections && connErr == nil {
+						connErr = newTooManyConnectionsError(i, q.SQL)
+					}
+					mu.Unlock()
+					continue
+				}
+				explains[i] = &explain
+			}
+		}()
+	}
+
+	for i := range queries {
+		mu.Lock()
+		stop := connErr != nil
+		mu.Unlock()
+		if stop || ctx.Err() != nil {
+			break
 		}
-		if err != nil {
-			qErr := newQueryError(q, err)
-			log.Println(qErr)
-			continue
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
 		}
-		defer rows.Close()
+	}
+	close(jobs)
+	wg.Wait()
 
-		if err = rows.Err(); err != nil {
-			qErr := newQueryError(q, err)
-			log.Println(qErr)
-			continue
+	res := make([]ExplainResult, 0, len(queries))
+	for _, e := range explains {
+		if e != nil {
+			res = append(res, *e)
+		}
+	}
+	return res, failures, connErr
+}
+
+// explainWithRetry runs the EXPLAIN of a query and runs it again with exponential backoff while it fails with a transient error
+// It returns the number of attempts.
+func explainWithRetry(ctx context.Context, db *sql.DB, q Query, opts Options) (ExplainResult, int, error) {
+	for attempts := 1; ; attempts++ {
+		explain, err := explainQuery(ctx, db, q)
+		if err == nil || attempts > opts.Retries || !classifyError(err).transient() {
+			return explain, attempts, err
+		}
+		select {
+		case <-ctx.Done():
+			return explain, attempts, err
+		case <-time.After(retryBackoff(opts.RetryBackoff, attempts)):
 		}
+	}
+}
 
-		if rows.Next() {
-			var explain ExplainResult
-			explain.Query = q
-
-			err = rows.Scan(
-				&explain.ID,
-				&explain.SelectType,
-				&explain.Table,
-				&explain.Partitions,
-				&explain.QueryType,
-				&explain.PossibleKeys,
-				&explain.Key,
-				&explain.KeyLen,
-				&explain.Ref,
-				&explain.NumberOfRows,
-				&explain.Filtered,
-				&explain.Extra,
-			)
-			if err != nil {
-				qErr := newQueryError(q, err)
-				log.Println(qErr)
-				continue
-			}
-			res = append(res, explain)
-		} else {
-			qErr := newQueryError(q, fmt.Errorf("EXPLAIN returned an empty row"))
-			log.Println(qErr)
+// explainQuery runs the EXPLAIN of a query and returns every row of its plan with the warnings MySQL reports about it
+//
+// SHOW WARNINGS returns the warnings of the previous statement of the same connection,
+// so both run on a connection of their own instead of any connection of the pool.
+func explainQuery(ctx context.Context, db *sql.DB, q Query) (ExplainResult, error) {
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return ExplainResult{}, err
+	}
+	defer conn.Close()
+
+	plan, err := queryPlan(ctx, conn, q)
+	if err != nil {
+		return ExplainResult{}, err
+	}
+	warnings, err := queryWarnings(ctx, conn)
+	if err != nil {
+		return ExplainResult{}, err
+	}
+	explain := newExplainResult(q, plan)
+	explain.Warnings = warnings
+	explain.RewrittenSQL = rewrittenSQL(warnings)
+	return explain, nil
+}
+
+// queryPlan runs the EXPLAIN of a query and returns its rows
+func queryPlan(ctx context.Context, conn *sql.Conn, q Query) ([]ExplainRow, error) {
+	rows, err := conn.QueryContext(ctx, q.AsExplain(), q.Bindings...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plan := make([]ExplainRow, 0)
+	for rows.Next() {
+		row, err := scanExplain(rows)
+		if err != nil {
+			return nil, err
+		}
+		// The first row of an INSERT ... SELECT is the table it inserts into, the SELECT is explained by the next ones
+		if len(plan) == 0 && strings.EqualFold(row.SelectType.String, "insert") {
 			continue
 		}
+		plan = append(plan, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("EXPLAIN returned an empty row")
+	}
+	return plan, nil
+}
+
+// queryWarnings returns the warnings of the previous statement of the connection
+func queryWarnings(ctx context.Context, conn *sql.Conn) ([]ExplainWarning, error) {
+	rows, err := conn.QueryContext(ctx, "show warnings")
+	if err != nil {
+		return nil, fmt.Errorf("show warnings: %w", err)
+	}
+	defer rows.Close()
+
+	warnings := make([]ExplainWarning, 0)
+	for rows.Next() {
+		var w ExplainWarning
+		if err := rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			return nil, fmt.Errorf("show warnings: %w", err)
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings, rows.Err()
+}
+
+// scanExplain scans the current row of an EXPLAIN
+func scanExplain(rows *sql.Rows) (ExplainRow, error) {
+	var row ExplainRow
+	err := rows.Scan(
+		&row.ID,
+		&row.SelectType,
+		&row.Table,
+		&row.Partitions,
+		&row.QueryType,
+		&row.PossibleKeys,
+		&row.Key,
+		&row.KeyLen,
+		&row.Ref,
+		&row.NumberOfRows,
+		&row.Filtered,
+		&row.Extra,
+	)
+	return row, err
+}
+
+// newExplainResult returns the EXPLAIN of a query from the rows of its plan
+func newExplainResult(q Query, plan []ExplainRow) ExplainResult {
+	first := plan[0]
+	return ExplainResult{
+		Query:        q,
+		ID:           int(first.ID.Int64),
+		SelectType:   first.SelectType,
+		Table:        first.Table,
+		Partitions:   first.Partitions,
+		QueryType:    first.QueryType,
+		PossibleKeys: first.PossibleKeys,
+		Key:          first.Key,
+		KeyLen:       first.KeyLen,
+		Ref:          first.Ref,
+		NumberOfRows: first.NumberOfRows,
+		Filtered:     first.Filtered,
+		Extra:        first.Extra,
+		Plan:         plan,
 	}
-	return res, nil
 }
 
 type QueryError struct {
 	sql      string
 	bindings []any
 	err      error
+	class    errorClass
+	attempts int
 }
 
 func (q QueryError) Error() string {
+	if q.attempts > 1 {
+		return fmt.Sprintf("query %s with bindings %v failed after %d attempts: %v", q.sql, q.bindings, q.attempts, q.err)
+	}
 	return fmt.Sprintf("query %s with bindings %v failed: %v", q.sql, q.bindings, q.err)
 }
 
-func newQueryError(q Query, err error) QueryError {
+func (q QueryError) Unwrap() error {
+	return q.err
+}
+
+func newQueryError(q Query, err error, attempts int) QueryError {
 	return QueryError{
 		sql:      q.SQL,
 		bindings: q.Bindings,
 		err:      err,
+		class:    classifyError(err),
+		attempts: attempts,
 	}
 }
 
@@ -91,5 +251,5 @@ func newTooManyConnectionsError(idx int, sql string) TooManyConnectionsError {
 }
 
 func (e TooManyConnectionsError) Error() string {
-	return fmt.Sprintf("database returned a 'Too many connections' error after %d queries. Please try again with a smaller log file or set a limit with the '--limit' option. last query: %s\n To increase the limit temporarily run: \"SET GLOBAL max_connections = 255;\"", e.idx, e.sql)
+	return fmt.Sprintf("database returned a 'Too many connections' error after %d queries. Please try again with a lower '--concurrency', more '--retries' or analyze fewer queries with the '--limit' option. last query: %s\n To increase the limit temporarily run: \"SET GLOBAL max_connections = 255;\"", e.idx, e.sql)
 }
@@ -0,0 +1,129 @@
+package explainer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunExplainQueries_KeepsOrder(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	patches := gomonkey.ApplyFunc(explainQuery, func(ctx context.Context, db *sql.DB, q Query) (ExplainResult, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		// Later queries finish first
+		time.Sleep(time.Duration('9'-q.SQL[len(q.SQL)-1]) * time.Millisecond)
+		if q.SQL == "select 3" {
+			return ExplainResult{}, errors.New("syntax error")
+		}
+		return ExplainResult{Query: q}, nil
+	})
+	defer patches.Reset()
+
+	queries := make([]Query, 0)
+	for i := range 10 {
+		queries = append(queries, newQuery(fmt.Sprintf("select %d", i)))
+	}
+	explains, failures, err := runExplainQueries(context.Background(), nil, queries, Options{Concurrency: 3})
+	assert.Nil(t, err)
+	assert.Len(t, explains, 9)
+	assert.Len(t, failures[errorOther], 1)
+	for i, e := range explains {
+		want := i
+		if i >= 3 {
+			want++
+		}
+		assert.Equal(t, fmt.Sprintf("select %d", want), e.Query.SQL)
+	}
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+}
+
+func TestRunExplainQueries_TooManyConnections(t *testing.T) {
+	var calls atomic.Int32
+	patches := gomonkey.ApplyFunc(explainQuery, func(ctx context.Context, db *sql.DB, q Query) (ExplainResult, error) {
+		calls.Add(1)
+		if q.SQL == "select 2" {
+			return ExplainResult{}, &mysql.MySQLError{Number: 1040, Message: "Too many connections"}
+		}
+		return ExplainResult{Query: q}, nil
+	})
+	defer patches.Reset()
+
+	queries := make([]Query, 0)
+	for i := range 100 {
+		queries = append(queries, newQuery(fmt.Sprintf("select %d", i)))
+	}
+	explains, _, err := runExplainQueries(context.Background(), nil, queries, Options{Concurrency: 1})
+	assert.ErrorAs(t, err, &TooManyConnectionsError{})
+	assert.Len(t, explains, 2)
+	assert.Less(t, calls.Load(), int32(100))
+}
+
+func TestRunExplainQueries_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	patches := gomonkey.ApplyFunc(explainQuery, func(ctx context.Context, db *sql.DB, q Query) (ExplainResult, error) {
+		if q.SQL == "select 2" {
+			cancel()
+		}
+		if ctx.Err() != nil {
+			return ExplainResult{}, ctx.Err()
+		}
+		return ExplainResult{Query: q}, nil
+	})
+	defer patches.Reset()
+
+	queries := make([]Query, 0)
+	for i := range 100 {
+		queries = append(queries, newQuery(fmt.Sprintf("select %d", i)))
+	}
+	explains, _, err := runExplainQueries(ctx, nil, queries, Options{Concurrency: 1})
+	assert.Nil(t, err)
+	assert.Len(t, explains, 2)
+}
+
+func TestRunExplainQueries_Retry(t *testing.T) {
+	var calls atomic.Int32
+	patches := gomonkey.ApplyFunc(explainQuery, func(ctx context.Context, db *sql.DB, q Query) (ExplainResult, error) {
+		n := calls.Add(1)
+		switch {
+		case q.SQL == "select 1" && n < 3:
+			return ExplainResult{}, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		case q.SQL == "select 2":
+			return ExplainResult{}, &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+		case q.SQL == "select 3":
+			return ExplainResult{}, &mysql.MySQLError{Number: 1146, Message: "Table 'analytics.orders' doesn't exist"}
+		}
+		return ExplainResult{Query: q}, nil
+	})
+	defer patches.Reset()
+
+	opts := Options{Concurrency: 1, Retries: 2, RetryBackoff: time.Millisecond}
+
+	explains, failures, err := runExplainQueries(context.Background(), nil, []Query{newQuery("select 1")}, opts)
+	assert.Nil(t, err)
+	assert.Len(t, explains, 1)
+	assert.Empty(t, failures)
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(10)
+	explains, failures, err = runExplainQueries(context.Background(), nil, []Query{newQuery("select 2"), newQuery("select 3")}, opts)
+	assert.Nil(t, err)
+	assert.Empty(t, explains)
+	assert.Equal(t, 2, failures.total())
+	assert.Equal(t, 3, failures[errorLockWait][0].attempts)
+	assert.Equal(t, 1, failures[errorUnknownTable][0].attempts)
+	assert.Contains(t, failures.String(), "lock wait timeout (1):\n  query select 2 with bindings [] failed after 3 attempts")
+}
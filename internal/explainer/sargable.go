@@ -0,0 +1,133 @@
+package explainer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+var (
+	// columnFunctionRewrites are the rewrites of comparisons on a column wrapped in a function that can use an index
+	// %[1]s is the column.
+	columnFunctionRewrites = map[string]string{
+		"date":           "a range on the column: %[1]s >= '2024-12-13' AND %[1]s < '2024-12-14'",
+		"year":           "a range on the column: %[1]s >= '2024-01-01' AND %[1]s < '2025-01-01'",
+		"month":          "a range on the column: %[1]s >= '2024-12-01' AND %[1]s < '2025-01-01'",
+		"date_format":    "a range on the column: %[1]s >= '2024-12-01' AND %[1]s < '2025-01-01'",
+		"unix_timestamp": "a comparison with the converted value: %[1]s = FROM_UNIXTIME(?)",
+		"lower":          "a comparison without the function: %[1]s = ?. Columns with a case-insensitive collation such as utf8mb4_0900_ai_ci already ignore the case",
+		"upper":          "a comparison without the function: %[1]s = ?. Columns with a case-insensitive collation such as utf8mb4_0900_ai_ci already ignore the case",
+		"trim":           "a comparison without the function: %[1]s = ? and store the values trimmed",
+		"ltrim":          "a comparison without the function: %[1]s = ? and store the values trimmed",
+		"rtrim":          "a comparison without the function: %[1]s = ? and store the values trimmed",
+		"left":           "a prefix match: %[1]s LIKE 'abc%%'",
+		"substring":      "a prefix match: %[1]s LIKE 'abc%%'",
+		"substr":         "a prefix match: %[1]s LIKE 'abc%%'",
+		"cast":           "a comparison with a value of the column's own type: %[1]s = ?. Convert the value instead of the column",
+		"convert":        "a comparison with a value of the column's own type: %[1]s = ?. Convert the value instead of the column",
+		"ifnull":         "(%[1]s = ? OR %[1]s IS NULL)",
+		"coalesce":       "(%[1]s = ? OR %[1]s IS NULL)",
+	}
+
+	// arithmeticOperators are the operators that make a column part of an arithmetic expression
+	arithmeticOperators = []string{"+", "-", "*", "/", "%", "div", "mod"}
+)
+
+// checkColumnFunctions checks for indexed columns that are wrapped in a function or an arithmetic expression in a comparison
+//
+// For example:
+//
+// select * from orders where date(created_at) = ?
+//
+// MySQL can only look up a value in an index if the column is compared as it is. DATE(created_at) has to be computed
+// for every row so the index on created_at can't be used.
+func (r *Result) checkColumnFunctions(ctx context.Context, s *schema) {
+	tokens := sqllexer.Tokenize(r.explain.Query.SQL)
+	tables := queryTables(tokens)
+	if len(tables) == 0 {
+		return
+	}
+
+	warnings := make([]string, 0)
+	for _, c := range comparisons(tokens) {
+		for _, side := range [][]sqllexer.Token{c.left, c.right} {
+			w, ok := columnFunctionWarning(ctx, s, tables, c, side)
+			if ok && !slices.Contains(warnings, w) {
+				warnings = append(warnings, w)
+			}
+		}
+	}
+	if len(warnings) == 0 {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 1)
+	r.columnFunctionWarning = strings.Join(warnings, " ")
+}
+
+// columnFunctionWarning returns the warning of one side of a comparison if it wraps an indexed column
+func columnFunctionWarning(ctx context.Context, s *schema, tables []tableRef, c comparison, side []sqllexer.Token) (string, bool) {
+	if isColumn(side) {
+		return "", false
+	}
+	function, arithmetic := wrapperOf(side)
+	if len(function) == 0 && !arithmetic {
+		return "", false
+	}
+
+	expr := expressionText(side)
+	for _, ref := range columnRefs(side) {
+		tableRef, ok := resolveTable(tables, ref)
+		if !ok {
+			continue
+		}
+		table, ok := s.table(ctx, tableRef.name)
+		if !ok {
+			continue
+		}
+		indexes := table.IndexesOf(ref.column)
+		if len(indexes) == 0 {
+			continue
+		}
+		if _, ok := table.FunctionalIndex(expr); ok {
+			continue
+		}
+
+		cmp := fmt.Sprintf("%s %s %s", expressionText(c.left), strings.ToUpper(c.op), expressionText(c.right))
+		functionalIndex := fmt.Sprintf("add a functional index (MySQL 8.0.13+): ALTER TABLE %s ADD INDEX ((%s)).", tableRef.name, expr)
+		if arithmetic {
+			return fmt.Sprintf("'%s' can't use the index %s of %s.%s because the column is part of an arithmetic expression. MySQL computes it for every row instead of looking up the index. Move the arithmetic to the other side of the comparison, for example %s + 1 = ? becomes %s = ? - 1, or %s", cmp, strings.Join(indexes, ", "), tableRef.name, ref.column, ref.column, ref.column, functionalIndex), true
+		}
+
+		rewrite := "a comparison on the column itself with the function applied to the value instead"
+		if tmpl, ok := columnFunctionRewrites[function]; ok {
+			rewrite = fmt.Sprintf(tmpl, ref.column)
+		}
+		return fmt.Sprintf("'%s' can't use the index %s of %s.%s because the column is wrapped in %s(). MySQL computes the function for every row instead of looking up the index. Rewrite it as %s or %s", cmp, strings.Join(indexes, ", "), tableRef.name, ref.column, strings.ToUpper(function), rewrite, functionalIndex), true
+	}
+	return "", false
+}
+
+// wrapperOf returns the lowercase name of the function an expression is a call of such as DATE(created_at)
+// or whether it's an arithmetic expression such as created_at + INTERVAL 1 DAY
+func wrapperOf(tokens []sqllexer.Token) (string, bool) {
+	if len(tokens) >= 3 && tokens[0].Kind == sqllexer.Word && tokens[1].Is("(") && closingParen(tokens, 1) == len(tokens)-1 {
+		return strings.ToLower(tokens[0].Text), false
+	}
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			depth--
+		// A sign such as -1 is not arithmetic
+		case depth == 0 && i > 0 && t.Is(arithmeticOperators...):
+			return "", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,64 @@
+package explainer
+
+import (
+	"context"
+	"github.com/mmartinjoo/explainer/internal/tableanalyzer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testSchema(t *testing.T, ddls ...string) *schema {
+	s := newSchema(nil)
+	for _, ddl := range ddls {
+		table, err := tableanalyzer.ParseTable(ddl)
+		assert.Nil(t, err)
+		s.add(table)
+	}
+	return s
+}
+
+func TestCheckColumnFunctions(t *testing.T) {
+	s := testSchema(t, `CREATE TABLE orders (
+		id bigint unsigned NOT NULL AUTO_INCREMENT,
+		user_id bigint unsigned NOT NULL,
+		status varchar(20) NOT NULL,
+		created_at timestamp NULL,
+		PRIMARY KEY (id),
+		KEY idx_created_at (created_at)
+	)`)
+
+	res := newResult(ExplainResult{Query: newQuery("select * from orders where DATE(created_at) = ? and lower(status) = 'paid'")})
+	res.checkColumnFunctions(context.Background(), s)
+	assert.Contains(t, res.columnFunctionWarning, "'DATE(created_at) = ?' can't use the index idx_created_at of orders.created_at because the column is wrapped in DATE().")
+	assert.Contains(t, res.columnFunctionWarning, "created_at >= '2024-12-13' AND created_at < '2024-12-14'")
+	assert.Contains(t, res.columnFunctionWarning, "ALTER TABLE orders ADD INDEX ((DATE(created_at)))")
+	// status is not indexed
+	assert.NotContains(t, res.columnFunctionWarning, "status")
+	assert.Equal(t, float32(4), res.Grade())
+
+	res = newResult(ExplainResult{Query: newQuery("select * from orders o where o.id + 1 = ?")})
+	res.checkColumnFunctions(context.Background(), s)
+	assert.Contains(t, res.columnFunctionWarning, "'id + 1 = ?' can't use the index PRIMARY of orders.id because the column is part of an arithmetic expression.")
+
+	res = newResult(ExplainResult{Query: newQuery("select * from orders where created_at >= ? and id = 1")})
+	res.checkColumnFunctions(context.Background(), s)
+	assert.Empty(t, res.columnFunctionWarning)
+}
+
+func TestCheckColumnFunctions_FunctionalIndex(t *testing.T) {
+	s := testSchema(t, `CREATE TABLE users (
+		id bigint unsigned NOT NULL AUTO_INCREMENT,
+		email varchar(255) NOT NULL,
+		PRIMARY KEY (id),
+		UNIQUE KEY users_email_unique (email),
+		KEY idx_lower_email ((lower(email)))
+	)`)
+
+	res := newResult(ExplainResult{Query: newQuery("select * from users u where LOWER(u.email) = ?")})
+	res.checkColumnFunctions(context.Background(), s)
+	assert.Empty(t, res.columnFunctionWarning)
+
+	res = newResult(ExplainResult{Query: newQuery("select * from users u where upper(u.email) = ?")})
+	res.checkColumnFunctions(context.Background(), s)
+	assert.Contains(t, res.columnFunctionWarning, "can't use the index users_email_unique of users.email because the column is wrapped in UPPER()")
+}
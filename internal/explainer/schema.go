@@ -0,0 +1,87 @@
+package explainer
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform"
+	"github.com/mmartinjoo/explainer/internal/tableanalyzer"
+)
+
+// schema looks up the columns and the indexes of the tables of the queries
+// Every table is queried once. Tables that can't be queried are reported once and left out of the checks that need them.
+type schema struct {
+	db *sql.DB
+	// tables is nil for a table that couldn't be queried
+	tables map[string]*tableanalyzer.Table
+	// depth is the value of optimizer_search_depth, 0 until it's queried
+	depth int
+}
+
+// defaultSearchDepth is the default value of optimizer_search_depth
+const defaultSearchDepth = 62
+
+func newSchema(db *sql.DB) *schema {
+	return &schema{
+		db:     db,
+		tables: make(map[string]*tableanalyzer.Table),
+	}
+}
+
+// table returns the definition of a table
+func (s *schema) table(ctx context.Context, name string) (*tableanalyzer.Table, bool) {
+	key := strings.ToLower(name)
+	if t, ok := s.tables[key]; ok {
+		return t, t != nil
+	}
+	if s.db == nil {
+		return nil, false
+	}
+
+	t, err := tableanalyzer.QueryTable(ctx, s.db, name)
+	if err != nil {
+		// Once ctx is canceled every query fails right away, those are not worth logging
+		if ctx.Err() == nil {
+			log.Printf("unable to look up the indexes of %s: %s", name, err)
+		}
+		s.tables[key] = nil
+		return nil, false
+	}
+	s.tables[key] = &t
+	return &t, true
+}
+
+// add adds a table definition that doesn't need to be queried
+func (s *schema) add(t tableanalyzer.Table) {
+	s.tables[strings.ToLower(t.Name())] = &t
+}
+
+// searchDepth returns the number of tables the optimizer looks ahead when it chooses the join order
+// 0 in optimizer_search_depth lets MySQL choose the depth, it uses 7 for joins of more than 7 tables.
+// The default is returned if the variable can't be queried.
+func (s *schema) searchDepth(ctx context.Context) int {
+	if s.depth != 0 {
+		return s.depth
+	}
+	s.depth = defaultSearchDepth
+	if s.db == nil {
+		return s.depth
+	}
+
+	qctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	var depth int
+	if err := s.db.QueryRowContext(qctx, "select @@optimizer_search_depth").Scan(&depth); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("unable to query optimizer_search_depth: %s", err)
+		}
+		return s.depth
+	}
+	if depth == 0 {
+		depth = 7
+	}
+	s.depth = depth
+	return s.depth
+}
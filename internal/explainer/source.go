@@ -0,0 +1,158 @@
+package explainer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// stdinPath is the path that reads the log from stdin, for example: kubectl logs app | myexplainer logs -
+const stdinPath = "-"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// readLogs reads the lines of every log file the paths refer to
+//
+// A path can be:
+//   - "-" to read stdin
+//   - A directory to read every file in it
+//   - A glob pattern such as storage/logs/laravel-*.log*
+//   - A regular file
+//
+// gzip and zstd compressed files are decompressed based on their content so rotated files such as laravel.log.1.gz can be read directly.
+//
+// Text logs are split into entries by [readQueries], JSON logs and the framework presets into lines.
+func readLogs(paths []string, format string) ([]string, error) {
+	files, err := expandLogPaths(paths)
+	if err != nil {
+		return nil, fmt.Errorf("explainer.readLogs: %w", err)
+	}
+
+	lines := make([]string, 0)
+	for _, path := range files {
+		r, err := openLog(path)
+		if err != nil {
+			return nil, fmt.Errorf("explainer.readLogs: %w", err)
+		}
+		read := readQueries
+		if format != "" && format != FormatText {
+			read = readLines
+		}
+		l, err := read(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("explainer.readLogs: %s: %w", path, err)
+		}
+		lines = append(lines, l...)
+	}
+	return lines, nil
+}
+
+// expandLogPaths turns directories and glob patterns into file paths
+// The files of a directory or a pattern are sorted by name and every file is returned only once
+func expandLogPaths(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		if path == stdinPath {
+			files = append(files, path)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, fmt.Errorf("explainer.expandLogPaths: %w", err)
+			}
+			for _, e := range entries {
+				if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		case err == nil:
+			files = append(files, path)
+		default:
+			matches, globErr := filepath.Glob(path)
+			if globErr != nil {
+				return nil, fmt.Errorf("explainer.expandLogPaths: %w", globErr)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("explainer.expandLogPaths: %w", err)
+			}
+			// Glob returns the matches in lexical order
+			files = append(files, matches...)
+		}
+	}
+
+	unique := make([]string, 0, len(files))
+	for _, f := range files {
+		if !slices.Contains(unique, f) {
+			unique = append(unique, f)
+		}
+	}
+	return unique, nil
+}
+
+// openLog opens a log file or stdin and decompresses it if it's gzip or zstd compressed
+func openLog(path string) (io.ReadCloser, error) {
+	var f io.ReadCloser = io.NopCloser(os.Stdin)
+	if path != stdinPath {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("explainer.openLog: %w", err)
+		}
+		f = file
+	}
+
+	br := bufio.NewReader(f)
+	// Peek returns fewer bytes for files shorter than the magic number which is fine
+	header, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("explainer.openLog: %s: %w", path, err)
+		}
+		return readCloser{Reader: gz, closers: []io.Closer{gz, f}}, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("explainer.openLog: %s: %w", path, err)
+		}
+		zrc := zr.IOReadCloser()
+		return readCloser{Reader: zrc, closers: []io.Closer{zrc, f}}, nil
+	default:
+		return readCloser{Reader: br, closers: []io.Closer{f}}, nil
+	}
+}
+
+// readCloser closes the decompressor and the underlying file together
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r readCloser) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
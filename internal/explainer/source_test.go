@@ -0,0 +1,62 @@
+package explainer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLogs(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "laravel-2024-12-13.log"), []byte("select * from `users`\n"))
+	writeLog(t, filepath.Join(dir, "laravel-2024-12-12.log.gz"), gzipped(t, "select * from `posts`\n"))
+	writeLog(t, filepath.Join(dir, "laravel-2024-12-11.log.zst"), zstdCompressed(t, "select * from `comments`"))
+
+	lines, err := readLogs([]string{filepath.Join(dir, "laravel-*.log*")}, FormatText)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"select * from `comments`", "select * from `posts`", "select * from `users`"}, lines)
+}
+
+func TestReadLogs_NotFound(t *testing.T) {
+	_, err := readLogs([]string{filepath.Join(t.TempDir(), "missing-*.log")}, FormatText)
+	assert.NotNil(t, err)
+}
+
+func TestExpandLogPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "b.log"), nil)
+	writeLog(t, filepath.Join(dir, "a.log"), nil)
+	writeLog(t, filepath.Join(dir, ".gitignore"), nil)
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "old"), 0755))
+
+	paths, err := expandLogPaths([]string{"-", dir, filepath.Join(dir, "a.log")})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"-", filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}, paths)
+}
+
+func writeLog(t *testing.T, path string, content []byte) {
+	assert.Nil(t, os.WriteFile(path, content, 0644))
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func zstdCompressed(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	assert.Nil(t, err)
+	_, err = w.Write([]byte(s))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
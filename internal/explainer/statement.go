@@ -0,0 +1,375 @@
+package explainer
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+// statementKind is the type of a SQL statement in a log entry
+type statementKind int
+
+const (
+	// statementNone means the log entry doesn't contain a SQL statement
+	statementNone statementKind = iota
+	// statementSelect is a SELECT, a WITH ... SELECT or a (SELECT ...) UNION (SELECT ...)
+	statementSelect
+	statementInsert
+	// statementInsertSelect is an INSERT ... SELECT
+	statementInsertSelect
+	statementReplace
+	statementUpdate
+	statementDelete
+	statementCall
+	statementSet
+	// statementDDL is a CREATE, ALTER, DROP, TRUNCATE or RENAME
+	statementDDL
+	// statementOther is SHOW, EXPLAIN, DESCRIBE, USE, LOCK and transaction control
+	statementOther
+)
+
+var (
+	// statementCandidate finds the positions in a log line where a statement may start
+	// Every candidate is classified from left to right and the first one that is a statement wins,
+	// so a keyword in the log prefix such as "Update failed:" doesn't hide the statement after it.
+	statementCandidate = regexp.MustCompile(`(?i)/\*|\((?:\s*\()*\s*(?:select|with)\b|\b(?:select|with|insert|update|delete|replace|call|set|create|alter|drop|truncate|rename|show|explain|describe|desc|begin|commit|rollback|start|use|lock|unlock)\b`)
+
+	// ddlObjects are the words that follow CREATE, ALTER, DROP and RENAME
+	ddlObjects = []string{
+		"table", "tables", "index", "view", "database", "schema", "unique", "fulltext", "spatial", "temporary", "trigger",
+		"procedure", "function", "event", "user", "or", "definer", "algorithm", "online", "if",
+	}
+
+	// showObjects are the words that follow SHOW
+	showObjects = []string{
+		"tables", "columns", "fields", "index", "indexes", "keys", "create", "variables", "status", "processlist", "full",
+		"databases", "schemas", "warnings", "errors", "engine", "engines", "grants", "global", "session", "table", "triggers",
+		"events", "binary", "master", "replica", "slave", "open", "plugins", "privileges", "profiles", "charset", "character", "collation",
+	}
+
+	// setTargets are the words that can follow SET without an assignment such as SET NAMES utf8mb4
+	setTargets = []string{"names", "character", "charset", "transaction", "session", "global", "local", "persist", "persist_only", "password", "role", "default"}
+)
+
+// String returns the name of the statement kind as it's printed in the skip report
+func (k statementKind) String() string {
+	switch k {
+	case statementSelect:
+		return "SELECT"
+	case statementInsert:
+		return "INSERT"
+	case statementInsertSelect:
+		return "INSERT ... SELECT"
+	case statementReplace:
+		return "REPLACE"
+	case statementUpdate:
+		return "UPDATE"
+	case statementDelete:
+		return "DELETE"
+	case statementCall:
+		return "CALL"
+	case statementSet:
+		return "SET"
+	case statementDDL:
+		return "DDL"
+	case statementOther:
+		return "other statement"
+	default:
+		return "no SQL statement"
+	}
+}
+
+// explainable reports whether statements of the kind are explained
+// UPDATE, DELETE and INSERT ... SELECT are only explained if writes is true. EXPLAIN doesn't execute them.
+func (k statementKind) explainable(writes bool) bool {
+	switch k {
+	case statementSelect:
+		return true
+	case statementUpdate, statementDelete, statementInsertSelect:
+		return writes
+	}
+	return false
+}
+
+// isWrite reports whether the statement changes data
+func (k statementKind) isWrite() bool {
+	return k != statementSelect && k != statementNone && k != statementOther
+}
+
+// locateStatement returns the position and the kind of the SQL statement in a log entry
+// It returns -1 and [statementNone] if the entry doesn't contain a statement.
+func locateStatement(entry string) (int, statementKind) {
+	for _, loc := range statementCandidate.FindAllStringIndex(entry, -1) {
+		if kind := classify(entry[loc[0]:]); kind != statementNone {
+			return loc[0], kind
+		}
+	}
+	return -1, statementNone
+}
+
+// classify returns the kind of the statement at the start of sql
+//
+// Comments are ignored and keywords are matched in any case. Keywords that can start a statement but are not
+// followed by what the statement needs (for example UPDATE without SET) don't count as a statement.
+func classify(sql string) statementKind {
+	return classifyTokens(sqllexer.Tokenize(sql))
+}
+
+func classifyTokens(tokens []sqllexer.Token) statementKind {
+	if len(tokens) == 0 {
+		return statementNone
+	}
+	first := tokens[0]
+	next := func(i int) sqllexer.Token {
+		if i < len(tokens) {
+			return tokens[i]
+		}
+		return sqllexer.Token{}
+	}
+
+	switch {
+	case first.Is("("):
+		i := 0
+		for next(i).Is("(") {
+			i++
+		}
+		if next(i).Is("select", "with") {
+			return statementSelect
+		}
+	case first.Is("select"):
+		if len(tokens) > 1 {
+			return statementSelect
+		}
+	case first.Is("with"):
+		return withStatement(tokens)
+	case first.Is("insert"), first.Is("replace"):
+		kind, ok := insertStatement(tokens)
+		if !ok {
+			return statementNone
+		}
+		if first.Is("replace") {
+			return statementReplace
+		}
+		return kind
+	case first.Is("update"):
+		if hasWordAtTop(tokens[1:], "set") {
+			return statementUpdate
+		}
+	case first.Is("delete"):
+		if hasWordAtTop(tokens[1:], "from") {
+			return statementDelete
+		}
+	case first.Is("call"):
+		if next(1).Kind == sqllexer.Word || next(1).Kind == sqllexer.QuotedIdent {
+			return statementCall
+		}
+	case first.Is("set"):
+		if next(1).Kind == sqllexer.Word && slices.Contains(setTargets, strings.ToLower(next(1).Text)) {
+			return statementSet
+		}
+		if slices.ContainsFunc(tokens, func(t sqllexer.Token) bool { return t.Is("=", ":=") }) {
+			return statementSet
+		}
+	case first.Is("create", "alter", "drop", "rename"):
+		if next(1).Kind == sqllexer.Word && slices.Contains(ddlObjects, strings.ToLower(next(1).Text)) {
+			return statementDDL
+		}
+	case first.Is("truncate"):
+		if next(1).Kind == sqllexer.Word || next(1).Kind == sqllexer.QuotedIdent {
+			return statementDDL
+		}
+	case first.Is("show"):
+		if next(1).Kind == sqllexer.Word && slices.Contains(showObjects, strings.ToLower(next(1).Text)) {
+			return statementOther
+		}
+	case first.Is("explain", "describe", "desc"):
+		if next(1).Is("select", "insert", "update", "delete", "replace", "with", "table", "format", "analyze", "extended", "partitions", "for") {
+			return statementOther
+		}
+		// DESCRIBE users or DESCRIBE users email
+		if n := statementLength(tokens); n == 2 || n == 3 {
+			return statementOther
+		}
+	case first.Is("begin", "commit", "rollback"):
+		if statementLength(tokens) <= 2 {
+			return statementOther
+		}
+	case first.Is("start"):
+		if next(1).Is("transaction") {
+			return statementOther
+		}
+	case first.Is("use"):
+		if statementLength(tokens) == 2 {
+			return statementOther
+		}
+	case first.Is("lock", "unlock"):
+		if next(1).Is("table", "tables", "instance") {
+			return statementOther
+		}
+	}
+	return statementNone
+}
+
+// withStatement returns the kind of the statement that follows the common table expressions of a WITH clause
+func withStatement(tokens []sqllexer.Token) statementKind {
+	i := 1
+	if i < len(tokens) && tokens[i].Is("recursive") {
+		i++
+	}
+	for i < len(tokens) {
+		// name [(columns)] AS (query)
+		if tokens[i].Kind != sqllexer.Word && tokens[i].Kind != sqllexer.QuotedIdent {
+			return statementNone
+		}
+		i++
+		if i < len(tokens) && tokens[i].Is("(") {
+			i = closingParen(tokens, i) + 1
+		}
+		if i >= len(tokens) || !tokens[i].Is("as") {
+			return statementNone
+		}
+		i++
+		if i >= len(tokens) || !tokens[i].Is("(") {
+			return statementNone
+		}
+		i = closingParen(tokens, i) + 1
+		if i < len(tokens) && tokens[i].Is(",") {
+			i++
+			continue
+		}
+		break
+	}
+	if i >= len(tokens) {
+		return statementNone
+	}
+	switch kind := classifyTokens(tokens[i:]); kind {
+	case statementSelect, statementUpdate, statementDelete, statementInsert, statementInsertSelect:
+		return kind
+	}
+	return statementNone
+}
+
+// insertStatement tells an INSERT ... VALUES or INSERT ... SET from an INSERT ... SELECT
+// ok is false if the statement has neither
+func insertStatement(tokens []sqllexer.Token) (statementKind, bool) {
+	depth := 0
+	for i := 1; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Is("("):
+			if depth == 0 && i+1 < len(tokens) && tokens[i+1].Is("select", "with") {
+				return statementInsertSelect, true
+			}
+			depth++
+		case t.Is(")"):
+			depth = max(depth-1, 0)
+		case depth == 0 && t.Is("values", "value", "set"):
+			return statementInsert, true
+		case depth == 0 && t.Is("select", "with", "table"):
+			return statementInsertSelect, true
+		}
+	}
+	return statementNone, false
+}
+
+// hasWordAtTop reports whether the tokens contain the keyword outside of parentheses
+func hasWordAtTop(tokens []sqllexer.Token, word string) bool {
+	return wordAtTop(tokens, word) != -1
+}
+
+// wordAtTop returns the index of the first keyword outside of parentheses or -1
+func wordAtTop(tokens []sqllexer.Token, word string) int {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			depth = max(depth-1, 0)
+		case depth == 0 && t.Kind == sqllexer.Word && t.Is(word):
+			return i
+		}
+	}
+	return -1
+}
+
+// closingParen returns the index of the parenthesis that closes the one at tokens[open] or the last index if it's not closed
+func closingParen(tokens []sqllexer.Token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].Is("("):
+			depth++
+		case tokens[i].Is(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// statementLength returns the number of tokens of a statement without the closing semicolon
+func statementLength(tokens []sqllexer.Token) int {
+	if len(tokens) != 0 && tokens[len(tokens)-1].Is(";") {
+		return len(tokens) - 1
+	}
+	return len(tokens)
+}
+
+// skipStats counts the log entries that are not explained by the reason they were skipped
+type skipStats map[string]int
+
+const (
+	// skipInvalidBindings is the reason of entries whose bindings can't be parsed or don't match the placeholders
+	skipInvalidBindings = "invalid bindings"
+	// skipMultipleStatements is the reason of entries with more than one statement. Only the first one would be explained
+	// and the others would be executed if the connection allowed multiple statements.
+	skipMultipleStatements = "multiple statements"
+)
+
+func (s skipStats) skip(reason string) {
+	if s != nil {
+		s[reason]++
+	}
+}
+
+// skipLine counts a log line that doesn't contain a query unless it's blank
+func (s skipStats) skipLine(line string) {
+	if len(strings.TrimSpace(line)) != 0 {
+		s.skip(statementNone.String())
+	}
+}
+
+func (s skipStats) total() int {
+	n := 0
+	for _, c := range s {
+		n += c
+	}
+	return n
+}
+
+// String returns the reasons ordered by their count such as: 12 no SQL statement, 3 INSERT, 1 invalid bindings
+func (s skipStats) String() string {
+	reasons := make([]string, 0, len(s))
+	for r := range s {
+		reasons = append(reasons, r)
+	}
+	slices.SortFunc(reasons, func(a, b string) int {
+		if c := cmp.Compare(s[b], s[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	parts := make([]string, 0, len(reasons))
+	for _, r := range reasons {
+		parts = append(parts, fmt.Sprintf("%d %s", s[r], r))
+	}
+	return strings.Join(parts, ", ")
+}
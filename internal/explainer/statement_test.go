@@ -0,0 +1,58 @@
+package explainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]statementKind{
+		"SELECT * FROM users": statementSelect,
+		"(SELECT id FROM users) UNION (SELECT id FROM admins)":                                        statementSelect,
+		"select id from users union all select id from admins":                                        statementSelect,
+		"WITH RECURSIVE tree (id) AS (SELECT 1 UNION ALL SELECT id + 1 FROM tree) SELECT * FROM tree": statementSelect,
+		"-- the latest orders\nSELECT * FROM orders":                                                  statementSelect,
+		"INSERT INTO users (name) VALUES ('select')":                                                  statementInsert,
+		"INSERT INTO archive (id, name) SELECT id, name FROM users":                                   statementInsertSelect,
+		"insert into archive (select * from users)":                                                   statementInsertSelect,
+		"INSERT INTO users SET name = 'John'":                                                         statementInsert,
+		"REPLACE INTO users (id, name) VALUES (1, 'John')":                                            statementReplace,
+		"UPDATE users SET name = 'John' WHERE id = 1":                                                 statementUpdate,
+		"DELETE FROM users WHERE id = 1":                                                              statementDelete,
+		"DELETE u FROM users u JOIN bans b ON b.user_id = u.id":                                       statementDelete,
+		"CALL refresh_stats(1)":                                                                       statementCall,
+		"SET NAMES utf8mb4":                                                                           statementSet,
+		"SET @total = 10":                                                                             statementSet,
+		"CREATE TABLE users (id int)":                                                                 statementDDL,
+		"ALTER TABLE users ADD INDEX idx_name (name)":                                                 statementDDL,
+		"TRUNCATE users":      statementDDL,
+		"SHOW TABLES":         statementOther,
+		"COMMIT":              statementOther,
+		"START TRANSACTION":   statementOther,
+		"Update failed":       statementNone,
+		"set for key users:1": statementNone,
+		"delete the cache":    statementNone,
+		"":                    statementNone,
+	}
+	for sql, kind := range tests {
+		assert.Equal(t, kind, classify(sql), sql)
+	}
+}
+
+func TestLocateStatement(t *testing.T) {
+	line := "[2024-12-13 20:05:44] local.INFO: it's done (0.4ms) select * from users"
+	start, kind := locateStatement(line)
+	assert.Equal(t, strings.Index(line, "select"), start)
+	assert.Equal(t, statementSelect, kind)
+
+	start, kind = locateStatement("User logged in")
+	assert.Equal(t, -1, start)
+	assert.Equal(t, statementNone, kind)
+}
+
+func TestSkipStatsString(t *testing.T) {
+	s := skipStats{"INSERT": 3, "no SQL statement": 12, skipInvalidBindings: 1, "DDL": 1}
+	assert.Equal(t, "12 no SQL statement, 3 INSERT, 1 DDL, 1 invalid bindings", s.String())
+	assert.Equal(t, 17, s.total())
+}
@@ -0,0 +1,90 @@
+package explainer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+// lockedRowsLimit is the number of locked rows above which a write is considered dangerous
+const lockedRowsLimit = 10_000
+
+// checkWrite runs the checks of UPDATE, DELETE and INSERT ... SELECT statements
+func (r *Result) checkWrite() {
+	kind := classify(r.explain.Query.SQL)
+	if !kind.isWrite() {
+		return
+	}
+	tokens := sqllexer.Tokenize(r.explain.Query.SQL)
+	r.checkWriteIndex(kind, tokens)
+	r.checkDeleteLimit(kind, tokens)
+	r.checkLockedRows(kind)
+}
+
+// checkWriteIndex checks whether the WHERE clause of a write can use an index
+//
+// InnoDB locks every row a statement scans, not only the ones it changes. An UPDATE or DELETE without
+// a usable index locks the whole table until the transaction ends.
+func (r *Result) checkWriteIndex(kind statementKind, tokens []sqllexer.Token) {
+	if kind != statementInsertSelect && !hasWordAtTop(tokens, "where") {
+		r.grade = grade.Dec(r.grade, 4)
+		r.writeIndexWarning = fmt.Sprintf("The %s has no WHERE clause. It changes every row of the table and locks all of them until the transaction ends.", kind)
+		return
+	}
+
+	if strings.ToLower(r.explain.QueryType.String) != "all" && r.explain.Key.Valid {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 3)
+	switch kind {
+	case statementInsertSelect:
+		r.writeIndexWarning = "No index can be used for the WHERE clause of the SELECT. It scans the whole source table and puts a shared lock on every row it reads, so other transactions can't change them until the INSERT finishes."
+	default:
+		r.writeIndexWarning = fmt.Sprintf("No index can be used for the WHERE clause of the %s. It scans the whole table and locks every row it reads, not only the ones it changes. Other transactions that write the table wait until the transaction ends. Add an index on the columns of the WHERE clause.", kind)
+	}
+}
+
+// checkDeleteLimit checks for single-table DELETE statements without a LIMIT
+func (r *Result) checkDeleteLimit(kind statementKind, tokens []sqllexer.Token) {
+	if kind != statementDelete || !singleTableDelete(tokens) || hasWordAtTop(tokens, "limit") {
+		return
+	}
+	r.grade = grade.Dec(r.grade, 0.5)
+	r.deleteLimitWarning = "The DELETE has no LIMIT. Deleting a large number of rows in one statement holds the locks for a long time, grows the undo log and delays replicas. Delete in batches, for example: DELETE ... LIMIT 1000 in a loop until no rows are affected."
+}
+
+// checkLockedRows estimates the number of rows a write locks based on the number of rows it examines
+func (r *Result) checkLockedRows(kind statementKind) {
+	rows := r.explain.NumberOfRows.Int64
+	if rows == 0 {
+		return
+	}
+	lock := "an exclusive"
+	if kind == statementInsertSelect {
+		lock = "a shared"
+	}
+	r.lockedRowsWarning = fmt.Sprintf("About %d rows would get %s lock while the statement runs.", rows, lock)
+	if rows > lockedRowsLimit {
+		r.grade = grade.Dec(r.grade, 1)
+		r.lockedRowsWarning += " Other transactions that need these rows wait until the transaction ends or time out."
+	}
+}
+
+// singleTableDelete reports whether a DELETE changes a single table, only those can have a LIMIT
+// DELETE t1 FROM t1 JOIN t2 ... and DELETE FROM t1 USING t1 JOIN t2 ... are multi-table deletes.
+func singleTableDelete(tokens []sqllexer.Token) bool {
+	i := 0
+	for i < len(tokens) && !tokens[i].Is("delete") {
+		i++
+	}
+	i++
+	for i < len(tokens) && tokens[i].Is("low_priority", "quick", "ignore") {
+		i++
+	}
+	if i >= len(tokens) || !tokens[i].Is("from") {
+		return false
+	}
+	return !hasWordAtTop(tokens[i:], "using") && !hasWordAtTop(tokens[i:], "join")
+}
@@ -0,0 +1,97 @@
+package explainer
+
+import (
+	"database/sql"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckWrite_UpdateWithoutIndex(t *testing.T) {
+	expl := ExplainResult{
+		Query:        newQuery("update orders set status = 'cancelled' where created_at < ?"),
+		SelectType:   sql.NullString{String: "UPDATE", Valid: true},
+		QueryType:    sql.NullString{String: "ALL", Valid: true},
+		NumberOfRows: sql.NullInt64{Int64: 250000, Valid: true},
+	}
+	res := newResult(expl)
+	res.checkWrite()
+
+	assert.Contains(t, res.writeIndexWarning, "No index can be used for the WHERE clause of the UPDATE")
+	assert.Empty(t, res.deleteLimitWarning)
+	assert.Contains(t, res.lockedRowsWarning, "About 250000 rows")
+	assert.Equal(t, float32(1), res.Grade())
+}
+
+func TestCheckWrite_DeleteWithIndex(t *testing.T) {
+	expl := ExplainResult{
+		Query:        newQuery("DELETE FROM sessions WHERE user_id = ?"),
+		SelectType:   sql.NullString{String: "DELETE", Valid: true},
+		QueryType:    sql.NullString{String: "range", Valid: true},
+		Key:          sql.NullString{String: "sessions_user_id_index", Valid: true},
+		NumberOfRows: sql.NullInt64{Int64: 12, Valid: true},
+	}
+	res := newResult(expl)
+	res.checkWrite()
+
+	assert.Empty(t, res.writeIndexWarning)
+	assert.NotEmpty(t, res.deleteLimitWarning)
+	assert.Equal(t, "About 12 rows would get an exclusive lock while the statement runs.", res.lockedRowsWarning)
+	assert.Equal(t, float32(4.5), res.Grade())
+}
+
+func TestCheckWrite_NoWhere(t *testing.T) {
+	res := newResult(ExplainResult{Query: newQuery("delete from cache limit 1000")})
+	res.checkWrite()
+
+	assert.Contains(t, res.writeIndexWarning, "has no WHERE clause")
+	assert.Empty(t, res.deleteLimitWarning)
+	assert.Equal(t, float32(1), res.Grade())
+}
+
+func TestCheckWrite_InsertSelect(t *testing.T) {
+	expl := ExplainResult{
+		Query:        newQuery("insert into archive (id) select id from orders where status = ?"),
+		QueryType:    sql.NullString{String: "ref", Valid: true},
+		Key:          sql.NullString{String: "orders_status_index", Valid: true},
+		NumberOfRows: sql.NullInt64{Int64: 40, Valid: true},
+	}
+	res := newResult(expl)
+	res.checkWrite()
+
+	assert.Empty(t, res.writeIndexWarning)
+	assert.Contains(t, res.lockedRowsWarning, "a shared lock")
+}
+
+func TestCheckWrite_Select(t *testing.T) {
+	res := newResult(ExplainResult{Query: newQuery("select * from orders")})
+	res.checkWrite()
+
+	assert.Empty(t, res.writeIndexWarning)
+	assert.Empty(t, res.lockedRowsWarning)
+}
+
+func TestSingleTableDelete(t *testing.T) {
+	for sql, single := range map[string]bool{
+		"DELETE FROM users WHERE id = 1":                               true,
+		"DELETE LOW_PRIORITY QUICK FROM users WHERE id = 1":            true,
+		"DELETE u FROM users u JOIN bans b ON b.user_id = u.id":        false,
+		"DELETE FROM u USING users u JOIN bans b ON b.id = u.id":       false,
+		"DELETE FROM users WHERE id IN (SELECT id FROM bans b JOIN x)": true,
+	} {
+		assert.Equal(t, single, singleTableDelete(sqllexer.Tokenize(sql)), sql)
+	}
+}
+
+func TestSelectStatements_IncludeWrites(t *testing.T) {
+	logs := []string{
+		"update users set name = ? where id = ? [\"John\", 1]",
+		"insert into archive select * from users",
+		"insert into users (name) values (?) [\"John\"]",
+		"select 1; delete from users",
+	}
+	skipped := make(skipStats)
+	queries := selectStatements(logs, true, skipped)
+	assert.Equal(t, logs[:2], queries)
+	assert.Equal(t, skipStats{"INSERT": 1, skipMultipleStatements: 1}, skipped)
+}
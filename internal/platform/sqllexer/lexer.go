@@ -0,0 +1,357 @@
+// Package sqllexer splits SQL text into tokens and statements
+//
+// It is not a full SQL parser. It only knows enough about MySQL syntax to tell identifiers, string literals,
+// numbers, comments and punctuation apart, so callers don't get confused by a keyword inside a string or a comment.
+package sqllexer
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Kind int
+
+const (
+	// Word is a keyword or an unquoted identifier
+	Word Kind = iota
+	// QuotedIdent is an identifier wrapped in backticks
+	QuotedIdent
+	// String is a literal wrapped in single or double quotes
+	String
+	Number
+	// Punct is a single punctuation character or an operator such as >=
+	Punct
+	Comment
+)
+
+type Token struct {
+	Kind Kind
+	Text string
+	// Pos is the byte offset of the token in the input
+	Pos int
+}
+
+// operators are the multi-character operators recognized as a single [Punct] token
+var operators = []string{"<=>", "->>", "->", ">=", "<=", "<>", "!=", "||", "&&", ":=", "<<", ">>"}
+
+// Tokenize returns every token of the input except whitespace and comments
+func Tokenize(sql string) []Token {
+	tokens := make([]Token, 0)
+	for _, t := range TokenizeWithComments(sql) {
+		if t.Kind == Comment {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// TokenizeWithComments returns every token of the input except whitespace
+func TokenizeWithComments(sql string) []Token {
+	tokens := make([]Token, 0)
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case isSpace(c):
+			i++
+		case isLineComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			tokens = append(tokens, Token{Kind: Comment, Text: sql[i : i+end], Pos: i})
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				end = len(sql) - i
+			} else {
+				end += 4
+			}
+			tokens = append(tokens, Token{Kind: Comment, Text: sql[i : i+end], Pos: i})
+			i += end
+		case c == '\'' || c == '"':
+			end := quotedEnd(sql, i)
+			tokens = append(tokens, Token{Kind: String, Text: sql[i:end], Pos: i})
+			i = end
+		case c == '`':
+			end := quotedEnd(sql, i)
+			tokens = append(tokens, Token{Kind: QuotedIdent, Text: sql[i:end], Pos: i})
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1]) && !precededByWord(tokens)):
+			kind, end := Number, numberEnd(sql, i)
+			// Identifiers can start with a digit, for example 1col
+			if end < len(sql) && isWordChar(sql[end]) {
+				kind = Word
+				for end < len(sql) && isWordChar(sql[end]) {
+					end++
+				}
+			}
+			tokens = append(tokens, Token{Kind: kind, Text: sql[i:end], Pos: i})
+			i = end
+		case isWordChar(c):
+			end := i
+			for end < len(sql) && isWordChar(sql[end]) {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: Word, Text: sql[i:end], Pos: i})
+			i = end
+		default:
+			op := string(c)
+			for _, o := range operators {
+				if strings.HasPrefix(sql[i:], o) {
+					op = o
+					break
+				}
+			}
+			tokens = append(tokens, Token{Kind: Punct, Text: op, Pos: i})
+			i += len(op)
+		}
+	}
+	return tokens
+}
+
+// SplitStatements splits the input on semicolons that are not part of a string, an identifier or a comment
+//
+// mysqldump's "DELIMITER ;;" lines are respected. Empty statements are omitted.
+func SplitStatements(sql string) []string {
+	statements := make([]string, 0)
+	delimiter := ";"
+	var stmt strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(stmt.String()); len(s) != 0 {
+			statements = append(statements, s)
+		}
+		stmt.Reset()
+	}
+
+	i := 0
+	for i < len(sql) {
+		// DELIMITER is a client command so it can only appear at the start of a line
+		if i == 0 || sql[i-1] == '\n' {
+			line := sql[i:]
+			if end := strings.IndexByte(line, '\n'); end != -1 {
+				line = line[:end]
+			}
+			if d, ok := delimiterCommand(line); ok {
+				flush()
+				delimiter = d
+				i += len(line)
+				continue
+			}
+		}
+
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(sql, i)
+			stmt.WriteString(sql[i:end])
+			i = end
+		case isLineComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				end = len(sql) - i
+			} else {
+				end += 4
+			}
+			stmt.WriteString(sql[i : i+end])
+			i += end
+		case strings.HasPrefix(sql[i:], delimiter):
+			flush()
+			i += len(delimiter)
+		default:
+			stmt.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return statements
+}
+
+// Value returns the text of a token without its surrounding quotes and with escape sequences resolved
+func (t Token) Value() string {
+	if t.Kind != String && t.Kind != QuotedIdent {
+		return t.Text
+	}
+	if len(t.Text) < 2 {
+		return t.Text
+	}
+
+	quote := t.Text[0]
+	body := t.Text[1:]
+	if body[len(body)-1] == quote {
+		body = body[:len(body)-1]
+	}
+
+	var v strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '\\' && quote != '`' && i+1 < len(body) {
+			i++
+			v.WriteByte(unescape(body[i]))
+			continue
+		}
+		if c == quote && i+1 < len(body) && body[i+1] == quote {
+			i++
+		}
+		v.WriteByte(c)
+	}
+	return v.String()
+}
+
+// Is reports whether the token is a [Word] or [Punct] matching one of the given texts case-insensitively
+func (t Token) Is(texts ...string) bool {
+	if t.Kind != Word && t.Kind != Punct {
+		return false
+	}
+	for _, text := range texts {
+		if strings.EqualFold(t.Text, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unterminated reports whether a string, a quoted identifier or a block comment reaches the end of the input without being closed
+//
+// It's used to tell that a statement continues on the next line.
+func (t Token) Unterminated() bool {
+	switch {
+	case t.Kind == String || t.Kind == QuotedIdent:
+		// A closed literal ends before the extra character, an unclosed one consumes it
+		return quotedEnd(t.Text+" ", 0) > len(t.Text)
+	case t.Kind == Comment && strings.HasPrefix(t.Text, "/*"):
+		return len(t.Text) < 4 || !strings.HasSuffix(t.Text, "*/")
+	default:
+		return false
+	}
+}
+
+// Name returns the identifier a [Word] or [QuotedIdent] token represents
+func (t Token) Name() string {
+	if t.Kind == QuotedIdent {
+		return t.Value()
+	}
+	return t.Text
+}
+
+// quotedEnd returns the index after the closing quote of the literal that starts at start
+//
+// Doubled quote characters and backslash escapes (except in identifiers) don't close the literal.
+// If the literal is never closed it returns the length of the input.
+func quotedEnd(sql string, start int) int {
+	quote := sql[start]
+	i := start + 1
+	for i < len(sql) {
+		c := sql[i]
+		if c == '\\' && quote != '`' {
+			i += 2
+			continue
+		}
+		if c == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(sql)
+}
+
+func numberEnd(sql string, start int) int {
+	i := start
+	if strings.HasPrefix(sql[i:], "0x") || strings.HasPrefix(sql[i:], "0X") {
+		i += 2
+		for i < len(sql) && isHexDigit(sql[i]) {
+			i++
+		}
+		return i
+	}
+	for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+		i++
+	}
+	// Exponent such as 1.5e10 or 2E-3
+	if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+		j := i + 1
+		if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+			j++
+		}
+		if j < len(sql) && isDigit(sql[j]) {
+			i = j
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+// precededByWord reports whether a dot follows an identifier (t.5col) instead of starting a number (.5)
+func precededByWord(tokens []Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.Kind == Word || last.Kind == QuotedIdent
+}
+
+// delimiterCommand returns the new delimiter if the line is a "DELIMITER ;;" command
+func delimiterCommand(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "DELIMITER") {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// isLineComment reports whether a "#" or "-- " comment starts at i
+func isLineComment(sql string, i int) bool {
+	if sql[i] == '#' {
+		return true
+	}
+	if !strings.HasPrefix(sql[i:], "--") {
+		return false
+	}
+	return i+2 == len(sql) || isSpace(sql[i+2])
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case '0':
+		return 0
+	default:
+		return c
+	}
+}
+
+func isSpace(c byte) bool {
+	return unicode.IsSpace(rune(c))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
@@ -0,0 +1,65 @@
+package sqllexer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("select `id`, 'it''s' from users /* comment */ where age >= 18.5 -- trailing\nand x = ?")
+
+	texts := make([]string, 0)
+	for _, tok := range tokens {
+		texts = append(texts, tok.Text)
+	}
+	assert.Equal(t, []string{"select", "`id`", ",", "'it''s'", "from", "users", "where", "age", ">=", "18.5", "and", "x", "=", "?"}, texts)
+	assert.Equal(t, QuotedIdent, tokens[1].Kind)
+	assert.Equal(t, String, tokens[3].Kind)
+	assert.Equal(t, Number, tokens[9].Kind)
+}
+
+func TestTokenizeWithComments(t *testing.T) {
+	tokens := TokenizeWithComments("/* hint */ select 1 # comment")
+	assert.Len(t, tokens, 4)
+	assert.Equal(t, Comment, tokens[0].Kind)
+	assert.Equal(t, Comment, tokens[3].Kind)
+}
+
+func TestValue(t *testing.T) {
+	assert.Equal(t, "it's", Token{Kind: String, Text: `'it''s'`}.Value())
+	assert.Equal(t, "Doe, \"John\"", Token{Kind: String, Text: `"Doe, \"John\""`}.Value())
+	assert.Equal(t, "user id", Token{Kind: QuotedIdent, Text: "`user id`"}.Value())
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := `
+		DROP TABLE IF EXISTS users; -- drop it;
+		CREATE TABLE users (name varchar(255) DEFAULT ';');
+		DELIMITER ;;
+		CREATE TRIGGER t BEFORE INSERT ON users FOR EACH ROW BEGIN SET NEW.name = 'x'; END ;;
+		DELIMITER ;
+		SELECT 1
+	`
+	statements := SplitStatements(sql)
+	assert.Len(t, statements, 4)
+	assert.Equal(t, "DROP TABLE IF EXISTS users", statements[0])
+	assert.Equal(t, "CREATE TABLE users (name varchar(255) DEFAULT ';')", statements[1])
+	assert.Contains(t, statements[2], "SET NEW.name = 'x'; END")
+	assert.Equal(t, "SELECT 1", statements[3])
+}
+
+func TestUnterminated(t *testing.T) {
+	last := func(sql string) Token {
+		tokens := TokenizeWithComments(sql)
+		return tokens[len(tokens)-1]
+	}
+	assert.False(t, last("select 'abc'").Unterminated())
+	assert.False(t, last("select 'it''s'").Unterminated())
+	assert.True(t, last("select 'abc").Unterminated())
+	assert.True(t, last("select 'it\\'").Unterminated())
+	assert.True(t, last("select `col").Unterminated())
+	assert.False(t, last("select 1 /* comment */").Unterminated())
+	assert.True(t, last("select 1 /* comment").Unterminated())
+	assert.False(t, last("select 1 -- comment").Unterminated())
+	assert.False(t, last("select 1").Unterminated())
+}
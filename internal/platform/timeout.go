@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"context"
+	"time"
+)
+
+// queryTimeoutKey is the context key of the timeout of database queries
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout returns a copy of ctx in which every database query has at most d to finish. 0 means no timeout
+func WithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, d)
+}
+
+// QueryContext returns the context of a single database query
+// It's canceled when ctx is canceled or when the query timeout of ctx is over.
+func QueryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok && d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,26 @@
+package platform
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQueryContext(t *testing.T) {
+	ctx, cancel := QueryContext(WithQueryTimeout(context.Background(), time.Second))
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+
+	ctx, cancel = QueryContext(context.Background())
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = QueryContext(WithQueryTimeout(parent, time.Minute))
+	defer cancel()
+	cancelParent()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,357 @@
+package tableanalyzer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+type alterKind int
+
+const (
+	alterOther alterKind = iota
+	alterAddColumn
+	alterDropColumn
+	alterModifyColumn
+	alterRenameColumn
+	alterColumnDefault
+	alterAddIndex
+	alterDropIndex
+	alterRenameIndex
+	alterAddPrimaryKey
+	alterDropPrimaryKey
+	alterAddForeignKey
+	alterDropForeignKey
+	alterConvertCharset
+	alterRenameTable
+	alterRebuild
+	alterAlgorithm
+	alterLock
+)
+
+type (
+	// AlterTable is an ALTER TABLE statement split into its operations
+	AlterTable struct {
+		table string
+		ops   []AlterOp
+	}
+
+	AlterOp struct {
+		kind alterKind
+		// text is the operation as it was written, for example "ADD INDEX idx (email)"
+		text string
+		// column is the new definition of an added or modified column
+		column Column
+		// name is the dropped column or index, or the new name of a renamed column, index or table
+		name string
+		// oldName is the previous name of a changed or renamed column or index
+		oldName string
+		// index holds the key parts of an added index or primary key
+		index []Index
+		fk    ForeignKey
+		// position is FIRST or AFTER for added and modified columns
+		position string
+	}
+)
+
+func isAlterTable(tokens []sqllexer.Token) bool {
+	p := newDDLParser(tokens)
+	if !p.accept("ALTER") {
+		return false
+	}
+	p.accept("ONLINE")
+	p.accept("IGNORE")
+	return p.accept("TABLE")
+}
+
+// parseAlterTable parses an ALTER TABLE statement into one [AlterOp] per comma-separated operation
+func parseAlterTable(stmt string) (AlterTable, error) {
+	tokens := sqllexer.Tokenize(stmt)
+	if !isAlterTable(tokens) {
+		return AlterTable{}, fmt.Errorf("analyzer.parseAlterTable: not an ALTER TABLE statement: %s", stmt)
+	}
+
+	p := newDDLParser(tokens)
+	p.accept("ALTER")
+	p.accept("ONLINE")
+	p.accept("IGNORE")
+	p.accept("TABLE")
+
+	var a AlterTable
+	a.table = p.qualifiedName()
+	if len(a.table) == 0 {
+		return a, fmt.Errorf("analyzer.parseAlterTable: missing table name")
+	}
+
+	// list stops at an unmatched closing parenthesis which can't appear in a valid statement
+	for _, item := range p.list() {
+		if len(item) == 0 {
+			continue
+		}
+		ops, err := parseAlterOp(stmt, item)
+		if err != nil {
+			return a, fmt.Errorf("analyzer.parseAlterTable: %s: %w", a.table, err)
+		}
+		a.ops = append(a.ops, ops...)
+	}
+	return a, nil
+}
+
+// parseAlterOp parses one operation of an ALTER TABLE statement
+// "ADD (a int, b int)" results in multiple operations
+func parseAlterOp(stmt string, tokens []sqllexer.Token) ([]AlterOp, error) {
+	op := AlterOp{text: tokensText(stmt, tokens)}
+	p := newDDLParser(tokens)
+
+	switch {
+	case p.accept("ADD"):
+		return parseAlterAdd(op, p)
+	case p.accept("DROP"):
+		switch {
+		case p.accept("PRIMARY"):
+			op.kind = alterDropPrimaryKey
+		case p.accept("INDEX", "KEY"):
+			op.kind = alterDropIndex
+			op.name = p.next().Name()
+		case p.accept("FOREIGN"):
+			p.accept("KEY")
+			op.kind = alterDropForeignKey
+			op.name = p.next().Name()
+		case p.accept("CHECK", "CONSTRAINT"):
+			op.kind = alterOther
+		default:
+			p.accept("COLUMN")
+			op.kind = alterDropColumn
+			op.name = p.next().Name()
+		}
+	case p.accept("MODIFY"):
+		p.accept("COLUMN")
+		op.kind = alterModifyColumn
+		op.column, op.position = parseAlterColumn(p)
+		op.oldName = op.column.name
+	case p.accept("CHANGE"):
+		p.accept("COLUMN")
+		op.kind = alterModifyColumn
+		op.oldName = p.next().Name()
+		op.column, op.position = parseAlterColumn(p)
+	case p.accept("RENAME"):
+		switch {
+		case p.accept("COLUMN"):
+			op.kind = alterRenameColumn
+		case p.accept("INDEX", "KEY"):
+			op.kind = alterRenameIndex
+		default:
+			p.accept("TO", "AS")
+			op.kind = alterRenameTable
+			op.name = p.qualifiedName()
+			return []AlterOp{op}, nil
+		}
+		op.oldName = p.next().Name()
+		p.accept("TO")
+		op.name = p.next().Name()
+	case p.accept("ALTER"):
+		if p.accept("INDEX", "CHECK", "CONSTRAINT") {
+			// Index visibility and constraint enforcement
+			op.kind = alterOther
+			break
+		}
+		p.accept("COLUMN")
+		op.name = p.next().Name()
+		op.kind = alterColumnDefault
+		if p.accept("SET") && p.accept("VISIBLE", "INVISIBLE") {
+			op.kind = alterOther
+		}
+	case p.accept("CONVERT"):
+		op.kind = alterConvertCharset
+		p.accept("TO")
+		p.accept("CHARACTER", "CHARSET")
+		p.accept("SET")
+		op.name = strings.ToLower(p.next().Name())
+	case p.accept("ALGORITHM"):
+		p.accept("=")
+		op.kind = alterAlgorithm
+		op.name = strings.ToUpper(p.next().Text)
+	case p.accept("LOCK"):
+		p.accept("=")
+		op.kind = alterLock
+		op.name = strings.ToUpper(p.next().Text)
+	case p.peek().Is("FORCE", "ENGINE", "ROW_FORMAT", "KEY_BLOCK_SIZE"):
+		op.kind = alterRebuild
+	default:
+		// Other table options such as changing the default charset only affect the metadata
+		op.kind = alterOther
+	}
+	return []AlterOp{op}, nil
+}
+
+// parseAlterAdd parses ADD COLUMN, ADD INDEX, ADD PRIMARY KEY, ADD FOREIGN KEY, etc
+func parseAlterAdd(op AlterOp, p *ddlParser) ([]AlterOp, error) {
+	p.accept("COLUMN")
+
+	// ADD (a int, b int) adds multiple columns at once
+	defs := [][]sqllexer.Token{p.tokens[p.pos:]}
+	if p.accept("(") {
+		defs = p.list()
+	}
+
+	// The definition is parsed the same way as in CREATE TABLE then turned into operations
+	var scratch Table
+	for _, def := range defs {
+		if err := scratch.parseDefinition(def); err != nil {
+			return nil, err
+		}
+	}
+
+	ops := make([]AlterOp, 0)
+	for _, c := range scratch.columns {
+		colOp := op
+		colOp.kind = alterAddColumn
+		colOp.column = c
+		colOp.position = columnPosition(defs[len(defs)-1])
+		ops = append(ops, colOp)
+	}
+	for _, idx := range scratch.groupedIndexes() {
+		idxOp := op
+		idxOp.kind = alterAddIndex
+		if idx[0].keyName == "PRIMARY" {
+			idxOp.kind = alterAddPrimaryKey
+		}
+		idxOp.name = idx[0].keyName
+		idxOp.index = idx
+		ops = append(ops, idxOp)
+	}
+	for _, fk := range scratch.foreignKeys {
+		fkOp := op
+		fkOp.kind = alterAddForeignKey
+		fkOp.name = fk.name
+		fkOp.fk = fk
+		ops = append(ops, fkOp)
+	}
+	if len(ops) == 0 {
+		op.kind = alterOther
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// parseAlterColumn parses the column definition of MODIFY and CHANGE
+func parseAlterColumn(p *ddlParser) (Column, string) {
+	def := p.tokens[p.pos:]
+	var scratch Table
+	if err := scratch.parseColumn(p); err != nil || len(scratch.columns) == 0 {
+		return Column{}, ""
+	}
+	return scratch.columns[0], columnPosition(def)
+}
+
+// columnPosition returns "FIRST" or "AFTER <column>" if the column definition has a position
+func columnPosition(def []sqllexer.Token) string {
+	for i, t := range def {
+		if t.Is("FIRST") {
+			return "FIRST"
+		}
+		if t.Is("AFTER") && i+1 < len(def) {
+			return "AFTER " + def[i+1].Name()
+		}
+	}
+	return ""
+}
+
+// tokensText returns the part of stmt the tokens were read from
+func tokensText(stmt string, tokens []sqllexer.Token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	last := tokens[len(tokens)-1]
+	return stmt[tokens[0].Pos : last.Pos+len(last.Text)]
+}
+
+// apply changes the table definition the same way MySQL would execute the ALTER TABLE statement
+func (t *Table) apply(a AlterTable) {
+	for _, op := range a.ops {
+		switch op.kind {
+		case alterAddColumn:
+			t.columns = append(t.columns, op.column)
+		case alterDropColumn:
+			t.dropColumn(op.name)
+		case alterModifyColumn:
+			for i, c := range t.columns {
+				if strings.EqualFold(c.name, op.oldName) {
+					t.columns[i] = op.column
+				}
+			}
+			t.renameIndexedColumn(op.oldName, op.column.name)
+		case alterRenameColumn:
+			for i, c := range t.columns {
+				if strings.EqualFold(c.name, op.oldName) {
+					t.columns[i].name = op.name
+				}
+			}
+			t.renameIndexedColumn(op.oldName, op.name)
+		case alterAddIndex, alterAddPrimaryKey:
+			if len(op.index) == 0 {
+				continue
+			}
+			parts := make([]keyPart, 0)
+			for _, idx := range op.index {
+				parts = append(parts, keyPart{column: idx.column, subPart: idx.subPart})
+			}
+			name := op.name
+			if op.kind == alterAddIndex && slices.ContainsFunc(t.indexes, func(idx Index) bool { return idx.keyName == name }) {
+				name = t.uniqueIndexName(name)
+			}
+			t.addIndex(name, op.index[0].indexType, op.index[0].unique, parts)
+		case alterDropIndex:
+			t.dropIndex(op.name)
+		case alterDropPrimaryKey:
+			t.dropIndex("PRIMARY")
+		case alterRenameIndex:
+			for i, idx := range t.indexes {
+				if idx.keyName == op.oldName {
+					t.indexes[i].keyName = op.name
+				}
+			}
+		case alterAddForeignKey:
+			t.foreignKeys = append(t.foreignKeys, op.fk)
+			t.addForeignKeyIndexes()
+		case alterDropForeignKey:
+			t.foreignKeys = slices.DeleteFunc(t.foreignKeys, func(fk ForeignKey) bool {
+				return fk.name == op.name
+			})
+		case alterConvertCharset:
+			for i, c := range t.columns {
+				if c.isString() {
+					t.columns[i].charset = op.name
+				}
+			}
+		case alterRenameTable:
+			t.name = op.name
+		}
+	}
+}
+
+// dropColumn removes a column and removes it from every index like MySQL does
+func (t *Table) dropColumn(name string) {
+	t.columns = slices.DeleteFunc(t.columns, func(c Column) bool {
+		return strings.EqualFold(c.name, name)
+	})
+	t.indexes = slices.DeleteFunc(t.indexes, func(idx Index) bool {
+		return strings.EqualFold(idx.column, name)
+	})
+}
+
+func (t *Table) dropIndex(name string) {
+	t.indexes = slices.DeleteFunc(t.indexes, func(idx Index) bool {
+		return idx.keyName == name
+	})
+}
+
+func (t *Table) renameIndexedColumn(oldName, newName string) {
+	for i, idx := range t.indexes {
+		if strings.EqualFold(idx.column, oldName) {
+			t.indexes[i].column = newName
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tableanalyzer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAlterTable(t *testing.T) {
+	a, err := parseAlterTable("ALTER TABLE `users` ADD COLUMN `nickname` varchar(64) AFTER `email`, ADD INDEX `users_nickname_index` (`nickname`), DROP COLUMN bio, RENAME INDEX a TO b, ALGORITHM=INPLACE, LOCK=NONE")
+	assert.Nil(t, err)
+	assert.Equal(t, "users", a.table)
+	assert.Len(t, a.ops, 6)
+
+	assert.Equal(t, alterAddColumn, a.ops[0].kind)
+	assert.Equal(t, Column{name: "nickname", dataType: "varchar(64)"}, a.ops[0].column)
+	assert.Equal(t, "AFTER email", a.ops[0].position)
+	assert.Equal(t, "ADD COLUMN `nickname` varchar(64) AFTER `email`", a.ops[0].text)
+
+	assert.Equal(t, alterAddIndex, a.ops[1].kind)
+	assert.Equal(t, "users_nickname_index", a.ops[1].name)
+
+	assert.Equal(t, alterDropColumn, a.ops[2].kind)
+	assert.Equal(t, "bio", a.ops[2].name)
+
+	assert.Equal(t, alterRenameIndex, a.ops[3].kind)
+	assert.Equal(t, "a", a.ops[3].oldName)
+	assert.Equal(t, "b", a.ops[3].name)
+
+	assert.Equal(t, alterAlgorithm, a.ops[4].kind)
+	assert.Equal(t, "INPLACE", a.ops[4].name)
+	assert.Equal(t, alterLock, a.ops[5].kind)
+	assert.Equal(t, "NONE", a.ops[5].name)
+}
+
+func TestParseAlterTable_NotAlter(t *testing.T) {
+	_, err := parseAlterTable("select * from users")
+	assert.NotNil(t, err)
+}
+
+func TestApplyAlterTable(t *testing.T) {
+	table := Table{
+		name: "users",
+		columns: []Column{
+			{name: "id", dataType: "bigint unsigned"},
+			{name: "email", dataType: "varchar(255)"},
+		},
+		indexes: []Index{
+			{keyName: "PRIMARY", indexType: "BTREE", seq: 1, column: "id", unique: true},
+			{keyName: "users_email_index", indexType: "BTREE", seq: 1, column: "email"},
+		},
+	}
+	a, err := parseAlterTable("alter table users change email email_address varchar(191), add team_id bigint unsigned, add constraint fk_team foreign key (team_id) references teams (id)")
+	assert.Nil(t, err)
+	table.apply(a)
+
+	assert.Equal(t, []Column{
+		{name: "id", dataType: "bigint unsigned"},
+		{name: "email_address", dataType: "varchar(191)"},
+		{name: "team_id", dataType: "bigint unsigned"},
+	}, table.columns)
+	assert.Equal(t, "email_address", table.indexes[1].column)
+	assert.Len(t, table.foreignKeys, 1)
+	assert.True(t, table.hasIndexStartingWith([]string{"team_id"}))
+}
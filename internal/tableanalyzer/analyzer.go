@@ -4,7 +4,7 @@
 //
 // db, _ := sql.Open("mysql", "<connectionString>")
 //
-//	if err := tableanalyzer.Analyze(db, "./queries.log"); err != nil {
+//	if err := tableanalyzer.Analyze(context.Background(), db, "page_views"); err != nil {
 //	    log.Fatal(err)
 //	}
 //
@@ -13,21 +13,28 @@
 package tableanalyzer
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/mmartinjoo/explainer/internal/platform/grade"
 	"log"
-	"slices"
+	"os"
 	"strings"
 
 	"github.com/mmartinjoo/explainer/internal/platform"
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
 )
 
 type (
 	Result struct {
 		compositeIndexWarnings    []string
-		stringBasedIndexWarning   string
+		prefixLengthWarnings      []string
 		tooLongTextColumnsWarning string
+		stringIndexLengthWarnings []string
+		largeTextColumnWarnings   []string
+		redundantIndexWarnings    []string
+		foreignKeyIndexWarnings   []string
 		grade                     float32
 	}
 
@@ -35,6 +42,7 @@ type (
 		name     string
 		dataType string
 		key      string
+		charset  string
 	}
 )
 
@@ -44,10 +52,10 @@ func newResult() Result {
 	}
 }
 
-func Analyze(db *sql.DB, table string) error {
+func Analyze(ctx context.Context, db *sql.DB, table string) error {
 	log.Printf("Analyzing %s...\n", table)
 
-	res, err := check(db, table)
+	res, err := check(ctx, db, table)
 	if err != nil {
 		return fmt.Errorf("tableanalyzer.Analyze: %w", err)
 	}
@@ -56,28 +64,139 @@ func Analyze(db *sql.DB, table string) error {
 	return nil
 }
 
-func check(db *sql.DB, table string) (Result, error) {
+// AnalyzeDDL parses the CREATE TABLE statements in a SQL file and analyzes every table without connecting to a database
+func AnalyzeDDL(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("tableanalyzer.AnalyzeDDL: %w", err)
+	}
+	defer f.Close()
+
+	tables, err := parseDDL(f)
+	if err != nil {
+		return fmt.Errorf("tableanalyzer.AnalyzeDDL: %w", err)
+	}
+	if len(tables) == 0 {
+		log.Printf("No CREATE TABLE statements found in %s\n", path)
+		return nil
+	}
+
+	for _, t := range tables {
+		log.Printf("Analyzing %s...\n", t.name)
+		res := checkSchema(t)
+		platform.PrintResults(&res)
+	}
+	return nil
+}
+
+// AnalyzeMigrations applies the Laravel and SQL migrations of a directory in order and analyzes the resulting tables
+// without connecting to a database
+func AnalyzeMigrations(dir string) error {
+	tables, err := parseMigrations(dir)
+	if err != nil {
+		return fmt.Errorf("tableanalyzer.AnalyzeMigrations: %w", err)
+	}
+	if len(tables) == 0 {
+		log.Printf("No schema changes found in %s\n", dir)
+		return nil
+	}
+
+	for _, t := range tables {
+		log.Printf("Analyzing %s...\n", t.name)
+		res := checkSchema(t)
+		platform.PrintResults(&res)
+	}
+	return nil
+}
+
+// AnalyzeAlter tells how MySQL executes the ALTER TABLE statements of a SQL file or a single statement
+// and how risky they are based on the size of the table
+func AnalyzeAlter(ctx context.Context, db *sql.DB, fileOrStatement string) error {
+	src := fileOrStatement
+	if b, err := os.ReadFile(fileOrStatement); err == nil {
+		src = string(b)
+	}
+
+	version, err := queryVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("tableanalyzer.AnalyzeAlter: %w", err)
+	}
+
+	found := false
+	for _, stmt := range sqllexer.SplitStatements(src) {
+		if !isAlterTable(sqllexer.Tokenize(stmt)) {
+			continue
+		}
+		found = true
+
+		a, err := parseAlterTable(stmt)
+		if err != nil {
+			return fmt.Errorf("tableanalyzer.AnalyzeAlter: %w", err)
+		}
+		size, err := queryTableSize(ctx, db, a.table)
+		if err != nil {
+			if errors.Is(err, errEmptyResults) {
+				return fmt.Errorf("tableanalyzer.AnalyzeAlter: table %s not found", a.table)
+			}
+			return fmt.Errorf("tableanalyzer.AnalyzeAlter: %w", err)
+		}
+		cols, err := queryColumns(ctx, db, a.table)
+		if err != nil {
+			return fmt.Errorf("tableanalyzer.AnalyzeAlter: %w", err)
+		}
+
+		log.Printf("Analyzing ALTER TABLE %s...\n", a.table)
+		res := assessAlter(stmt, a, size, cols, version)
+		platform.PrintResults(&res)
+	}
+	if !found {
+		log.Println("No ALTER TABLE statements found")
+	}
+	return nil
+}
+
+func check(ctx context.Context, db *sql.DB, table string) (Result, error) {
 	res := newResult()
-	if err := res.checkCompositeIndexes(db, table); err != nil {
+	if err := res.checkCompositeIndexes(ctx, db, table); err != nil {
+		return res, fmt.Errorf("tableanalyzer.check: %w", err)
+	}
+	if err := res.checkPrefixLengths(ctx, db, table); err != nil {
 		return res, fmt.Errorf("tableanalyzer.check: %w", err)
 	}
-	if err := res.checkStringIndexes(db, table); err != nil {
+	if err := res.checkTooLongTextColumns(ctx, db, table); err != nil {
 		return res, fmt.Errorf("tableanalyzer.check: %w", err)
 	}
-	if err := res.checkTooLongTextColumns(db, table); err != nil {
+	if err := res.checkDefinition(ctx, db, table); err != nil {
 		return res, fmt.Errorf("tableanalyzer.check: %w", err)
 	}
 	return res, nil
 }
 
+// checkDefinition runs the checks of [checkSchema] that apply to a live table: redundant indexes and foreign keys without an index
+// The string index lengths are covered by checkPrefixLengths which measures the data.
+func (r *Result) checkDefinition(ctx context.Context, db *sql.DB, table string) error {
+	columns, err := queryColumns(ctx, db, table)
+	if err != nil {
+		return fmt.Errorf("analyzer.checkDefinition: %w", err)
+	}
+	indexes, err := queryIndexes(ctx, db, table)
+	if err != nil {
+		return fmt.Errorf("analyzer.checkDefinition: %w", err)
+	}
+	t := Table{name: table, columns: columns, indexes: indexes}
+	r.checkRedundantIndexes(t)
+	r.checkForeignKeyIndexes(t)
+	return nil
+}
+
 // checkTooLongTextColumns checks if a column is too long compared the data it stores
 //
 // For example:
 //   - If a column is mediumtext (can store up to 16m characters)
 //   - But the longest string is 5000 characters
 //   - It will mark this as a warning
-func (r *Result) checkTooLongTextColumns(db *sql.DB, table string) error {
-	cols, err := queryTooLongTextColumns(db, table)
+func (r *Result) checkTooLongTextColumns(ctx context.Context, db *sql.DB, table string) error {
+	cols, err := queryTooLongTextColumns(ctx, db, table)
 	if err != nil {
 		return fmt.Errorf("analyzer.checkTooLongTextColumns: %w", err)
 	}
@@ -94,44 +213,79 @@ func (r *Result) checkTooLongTextColumns(db *sql.DB, table string) error {
 	return nil
 }
 
-// checkStringIndexes checks if varchar, text, mediumtext, etc columns are being used in indexes
-func (r *Result) checkStringIndexes(db *sql.DB, table string) error {
-	stringCols, err := queryStringColumns(db, table)
+// checkPrefixLengths recommends prefix lengths for varchar, text, mediumtext, etc columns that are being used in indexes
+//
+// For example:
+//   - email is a varchar(255) column in a non-unique index
+//   - LEFT(email, 16) has 97% of the distinct values of the full column
+//   - It will recommend indexing email(16) instead of all 255 characters
+func (r *Result) checkPrefixLengths(ctx context.Context, db *sql.DB, table string) error {
+	stringCols, err := queryStringColumns(ctx, db, table)
 	if err != nil {
-		return fmt.Errorf("abalyzer.checkStringIndexes: querying columns: %w", err)
+		return fmt.Errorf("abalyzer.checkPrefixLengths: querying columns: %w", err)
 	}
 
-	indexes, err := queryIndexes(db, table)
+	indexes, err := queryIndexes(ctx, db, table)
 	if err != nil {
-		return fmt.Errorf("abalyzer.checkStringIndexes: querying indexes: %w", err)
+		return fmt.Errorf("abalyzer.checkPrefixLengths: querying indexes: %w", err)
 	}
 
-	colsInIndex := make([]string, 0)
+	// A column can be part of multiple indexes but it's enough to measure it once
+	selectivities := make(map[string]PrefixSelectivity)
 	for _, col := range stringCols {
 		for _, idx := range indexes {
-			if idx.column == col.name && idx.indexType != "FULLTEXT" {
-				colsInIndex = append(colsInIndex, col.name)
+			// A prefix would change the meaning of a unique index
+			if idx.column != col.name || idx.indexType == "FULLTEXT" || idx.unique {
+				continue
+			}
+
+			indexedLen := col.length()
+			if idx.subPart > 0 {
+				indexedLen = idx.subPart
 			}
+			lengths := candidatePrefixLengths(indexedLen)
+			if len(lengths) == 0 {
+				continue
+			}
+
+			sel, ok := selectivities[col.name]
+			if !ok {
+				sel, err = queryPrefixSelectivity(ctx, db, table, col, candidatePrefixLengths(col.length()))
+				if err != nil {
+					if errors.Is(err, errEmptyResults) {
+						continue
+					}
+					return fmt.Errorf("abalyzer.checkPrefixLengths: %w", err)
+				}
+				selectivities[col.name] = sel
+			}
+
+			prefixLen, ratio, ok := recommendPrefixLength(sel)
+			if !ok || prefixLen >= indexedLen {
+				continue
+			}
+
+			// The values are shorter than the prefix on average so the index doesn't get smaller
+			saving := indexSizeSaving(col, sel.rows, sel.avgLength, indexedLen, prefixLen)
+			if saving == 0 {
+				continue
+			}
+			r.prefixLengthWarnings = append(r.prefixLengthWarnings, fmt.Sprintf(
+				"'%s' indexes %d characters of %s but LEFT(%s, %d) reaches %.1f%% of the full column selectivity. Index it as %s(%d) to save about %s\n",
+				idx.keyName, indexedLen, col.name, col.name, prefixLen, ratio*100, col.name, prefixLen, formatBytes(saving),
+			))
 		}
 	}
 
-	colsInIndex = slices.Compact(colsInIndex)
-	if len(colsInIndex) > 0 {
-		var msg strings.Builder
-		msg.WriteString("The following string-based columns (varchar, text, mediumtext, etc) are being part of non-FULLTEXT indexes. ")
-		msg.WriteString("It is usually a better idea to use a FULLTEXT index for columns like these because they are optimized for string data. On top of that, MySQL can only index the first 4KB of a text column so in case of a longer column it is only a partial index.\n")
-		for _, v := range colsInIndex {
-			msg.WriteString(fmt.Sprintf("- %s\n", v))
-		}
-		r.stringBasedIndexWarning = msg.String()
+	if len(r.prefixLengthWarnings) != 0 {
 		r.grade = grade.Dec(r.grade, 0.5)
 	}
 	return nil
 }
 
 // checkCompositeIndexes checks if columns are in the right order based on their cardinality
-func (r *Result) checkCompositeIndexes(db *sql.DB, table string) error {
-	indexes, err := queryIndexes(db, table)
+func (r *Result) checkCompositeIndexes(ctx context.Context, db *sql.DB, table string) error {
+	indexes, err := queryIndexes(ctx, db, table)
 	if err != nil {
 		return fmt.Errorf("analyzer.checkCompositeIndexes: %w", err)
 	}
@@ -145,12 +299,12 @@ func (r *Result) checkCompositeIndexes(db *sql.DB, table string) error {
 		if !ok {
 			var optimalColOrder []string
 			for _, v := range optimalIdx {
-				optimalColOrder = append(optimalColOrder, v.column)
+				optimalColOrder = append(optimalColOrder, v.part())
 			}
 
 			var actualColOrder []string
 			for _, v := range compIdx {
-				actualColOrder = append(actualColOrder, v.column)
+				actualColOrder = append(actualColOrder, v.part())
 			}
 
 			var msg strings.Builder
@@ -182,10 +336,12 @@ func (r *Result) String() string {
 			str.WriteString(fmt.Sprintf("- %s", v))
 		}
 	}
-	if len(r.stringBasedIndexWarning) != 0 {
+	if len(r.prefixLengthWarnings) != 0 {
 		hasProblems = true
-		str.WriteString("String-based index problems:\n")
-		str.WriteString(r.stringBasedIndexWarning)
+		str.WriteString("Index prefix lengths:\n")
+		for _, v := range r.prefixLengthWarnings {
+			str.WriteString(fmt.Sprintf("- %s", v))
+		}
 	}
 	if len(r.tooLongTextColumnsWarning) != 0 {
 		hasProblems = true
@@ -193,6 +349,35 @@ func (r *Result) String() string {
 		str.WriteString(r.tooLongTextColumnsWarning)
 	}
 
+	if len(r.stringIndexLengthWarnings) != 0 {
+		hasProblems = true
+		str.WriteString("String index lengths:\n")
+		for _, v := range r.stringIndexLengthWarnings {
+			str.WriteString(fmt.Sprintf("- %s", v))
+		}
+	}
+	if len(r.largeTextColumnWarnings) != 0 {
+		hasProblems = true
+		str.WriteString("Large text columns:\n")
+		for _, v := range r.largeTextColumnWarnings {
+			str.WriteString(fmt.Sprintf("- %s", v))
+		}
+	}
+	if len(r.redundantIndexWarnings) != 0 {
+		hasProblems = true
+		str.WriteString("Redundant indexes:\n")
+		for _, v := range r.redundantIndexWarnings {
+			str.WriteString(fmt.Sprintf("- %s", v))
+		}
+	}
+	if len(r.foreignKeyIndexWarnings) != 0 {
+		hasProblems = true
+		str.WriteString("Unindexed foreign keys:\n")
+		for _, v := range r.foreignKeyIndexWarnings {
+			str.WriteString(fmt.Sprintf("- %s", v))
+		}
+	}
+
 	if !hasProblems {
 		str.WriteString("No problems found")
 	}
@@ -1,6 +1,7 @@
 package tableanalyzer
 
 import (
+	"context"
 	"database/sql"
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/stretchr/testify/assert"
@@ -11,7 +12,7 @@ func TestCheckTooLongTextColumns(t *testing.T) {
 	db := &sql.DB{}
 	res := newResult()
 
-	patches := gomonkey.ApplyFunc(queryTooLongTextColumns, func(db *sql.DB, table string) ([]TooLongTextColumn, error) {
+	patches := gomonkey.ApplyFunc(queryTooLongTextColumns, func(ctx context.Context, db *sql.DB, table string) ([]TooLongTextColumn, error) {
 		return []TooLongTextColumn{
 			{col: Column{
 				name:     "c1",
@@ -22,24 +23,24 @@ func TestCheckTooLongTextColumns(t *testing.T) {
 	})
 	defer patches.Reset()
 
-	err := res.checkTooLongTextColumns(db, "table")
+	err := res.checkTooLongTextColumns(context.Background(), db, "table")
 	assert.Nil(t, err)
 	assert.Equal(t, float32(4.75), res.grade)
 	assert.NotNil(t, res.tooLongTextColumnsWarning)
 }
 
-func TestCheckStringIndexes(t *testing.T) {
+func TestCheckPrefixLengths(t *testing.T) {
 	db := &sql.DB{}
 	res := newResult()
 
-	patches := gomonkey.ApplyFunc(queryStringColumns, func(db *sql.DB, table string) ([]Column, error) {
+	patches := gomonkey.ApplyFunc(queryStringColumns, func(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
 		return []Column{
-			{name: "c1", dataType: "varchar(255)", key: "idx1"},
-			{name: "c2", dataType: "mediumtext", key: "idx2"},
-			{name: "c3", dataType: "text", key: "idx3"},
+			{name: "c1", dataType: "varchar(255)", key: "MUL", charset: "utf8mb4"},
+			{name: "c2", dataType: "varchar(255)", key: "UNI", charset: "utf8mb4"},
+			{name: "c3", dataType: "text", key: "MUL", charset: "utf8mb4"},
 		}, nil
 	})
-	patches = gomonkey.ApplyFunc(queryIndexes, func(db *sql.DB, table string) ([]Index, error) {
+	patches.ApplyFunc(queryIndexes, func(ctx context.Context, db *sql.DB, table string) ([]Index, error) {
 		return []Index{
 			{
 				keyName:     "idx1",
@@ -54,6 +55,7 @@ func TestCheckStringIndexes(t *testing.T) {
 				seq:         1,
 				column:      "c2",
 				cardinality: 10,
+				unique:      true,
 			},
 			{
 				keyName:     "idx3",
@@ -64,22 +66,34 @@ func TestCheckStringIndexes(t *testing.T) {
 			},
 		}, nil
 	})
+	patches.ApplyFunc(queryPrefixSelectivity, func(ctx context.Context, db *sql.DB, table string, col Column, lengths []int64) (PrefixSelectivity, error) {
+		return PrefixSelectivity{
+			col:       col,
+			rows:      1000,
+			distinct:  100,
+			avgLength: 40,
+			prefixes: []PrefixDistinct{
+				{length: 4, distinct: 20},
+				{length: 8, distinct: 80},
+				{length: 12, distinct: 96},
+				{length: 16, distinct: 100},
+			},
+		}, nil
+	})
 	defer patches.Reset()
 
-	err := res.checkStringIndexes(db, "table")
+	err := res.checkPrefixLengths(context.Background(), db, "table")
 	assert.Nil(t, err)
 	assert.Equal(t, float32(4.5), res.grade)
-	assert.NotNil(t, res.stringBasedIndexWarning)
-	assert.Contains(t, res.stringBasedIndexWarning, "c1")
-	assert.Contains(t, res.stringBasedIndexWarning, "c2")
-	assert.NotContains(t, res.stringBasedIndexWarning, "c3")
+	assert.Len(t, res.prefixLengthWarnings, 1)
+	assert.Contains(t, res.prefixLengthWarnings[0], "c1(12)")
 }
 
 func TestCheckCompositeIndexes(t *testing.T) {
 	db := &sql.DB{}
 	res := newResult()
 
-	patches := gomonkey.ApplyFunc(queryIndexes, func(db *sql.DB, table string) ([]Index, error) {
+	patches := gomonkey.ApplyFunc(queryIndexes, func(ctx context.Context, db *sql.DB, table string) ([]Index, error) {
 		return []Index{
 			{
 				keyName:     "idx1",
@@ -106,8 +120,37 @@ func TestCheckCompositeIndexes(t *testing.T) {
 	})
 	defer patches.Reset()
 
-	err := res.checkCompositeIndexes(db, "table")
+	err := res.checkCompositeIndexes(context.Background(), db, "table")
 	assert.Nil(t, err)
 	assert.Equal(t, float32(3), res.grade)
 	assert.NotNil(t, res.compositeIndexWarnings)
 }
+
+func TestCheckDefinition(t *testing.T) {
+	db := &sql.DB{}
+	res := newResult()
+
+	patches := gomonkey.ApplyFunc(queryColumns, func(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+		return []Column{
+			{name: "id", dataType: "bigint unsigned"},
+			{name: "user_id", dataType: "bigint unsigned"},
+			{name: "status", dataType: "varchar(20)"},
+		}, nil
+	})
+	patches.ApplyFunc(queryIndexes, func(ctx context.Context, db *sql.DB, table string) ([]Index, error) {
+		return []Index{
+			{keyName: "PRIMARY", indexType: "BTREE", seq: 1, column: "id", unique: true},
+			{keyName: "idx_status", indexType: "BTREE", seq: 1, column: "status"},
+			{keyName: "idx_status_id", indexType: "BTREE", seq: 1, column: "status"},
+			{keyName: "idx_status_id", indexType: "BTREE", seq: 2, column: "id"},
+		}, nil
+	})
+	defer patches.Reset()
+
+	err := res.checkDefinition(context.Background(), db, "orders")
+	assert.Nil(t, err)
+	assert.Len(t, res.redundantIndexWarnings, 1)
+	assert.Contains(t, res.redundantIndexWarnings[0], "'idx_status'")
+	assert.Len(t, res.foreignKeyIndexWarnings, 1)
+	assert.Contains(t, res.foreignKeyIndexWarnings[0], "user_id")
+}
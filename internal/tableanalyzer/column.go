@@ -0,0 +1,73 @@
+package tableanalyzer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// textLengths are the maximum number of characters text columns can store
+var textLengths = map[string]int64{
+	"tinytext":   255,
+	"text":       65535,
+	"mediumtext": 16777215,
+	"longtext":   4294967295,
+}
+
+// length returns the maximum number of characters the column can store
+//
+// For example:
+//   - varchar(255) returns 255
+//   - mediumtext returns 16777215
+//   - int returns 0
+func (c Column) length() int64 {
+	if l, ok := textLengths[c.dataType]; ok {
+		return l
+	}
+
+	start := strings.Index(c.dataType, "(")
+	end := strings.Index(c.dataType, ")")
+	if start == -1 || end < start {
+		return 0
+	}
+	l, err := strconv.ParseInt(c.dataType[start+1:end], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return l
+}
+
+// isString reports whether the column stores character data
+func (c Column) isString() bool {
+	for _, t := range []string{"char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set"} {
+		if c.dataType == t || strings.HasPrefix(c.dataType, t+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// isText reports whether the column is one of the TEXT types that can only be indexed with a prefix
+func (c Column) isText() bool {
+	_, ok := textLengths[c.dataType]
+	return ok
+}
+
+// bytesPerChar returns the maximum number of bytes a character takes in the column's charset
+func (c Column) bytesPerChar() int64 {
+	switch c.charset {
+	case "latin1", "ascii", "binary":
+		return 1
+	case "ucs2":
+		return 2
+	case "utf8", "utf8mb3":
+		return 3
+	default:
+		return 4
+	}
+}
+
+// charsetOf returns the charset of a collation such as utf8mb4_unicode_ci
+func charsetOf(collation string) string {
+	charset, _, _ := strings.Cut(collation, "_")
+	return charset
+}
@@ -0,0 +1,496 @@
+package tableanalyzer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+var errNoTableBody = errors.New("table has no column definitions")
+
+type (
+	// Table is the definition of a table parsed from DDL statements instead of a live database
+	Table struct {
+		name        string
+		columns     []Column
+		indexes     []Index
+		foreignKeys []ForeignKey
+	}
+
+	ForeignKey struct {
+		name       string
+		columns    []string
+		refTable   string
+		refColumns []string
+	}
+
+	// ddlParser walks the tokens of a single DDL statement
+	ddlParser struct {
+		tokens []sqllexer.Token
+		pos    int
+	}
+)
+
+// parseDDL parses the CREATE TABLE statements from a SQL file such as the output of "mysqldump --no-data"
+// Every other statement is ignored
+func parseDDL(r io.Reader) ([]Table, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("analyzer.parseDDL: reading: %w", err)
+	}
+
+	tables := make([]Table, 0)
+	for _, stmt := range sqllexer.SplitStatements(string(b)) {
+		tokens := sqllexer.Tokenize(stmt)
+		if !isCreateTable(tokens) {
+			continue
+		}
+		t, err := parseCreateTable(tokens)
+		if errors.Is(err, errNoTableBody) {
+			// CREATE TABLE ... LIKE and CREATE TABLE ... AS SELECT don't define columns
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("analyzer.parseDDL: %w", err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func isCreateTable(tokens []sqllexer.Token) bool {
+	p := newDDLParser(tokens)
+	if !p.accept("CREATE") {
+		return false
+	}
+	p.accept("TEMPORARY")
+	return p.accept("TABLE")
+}
+
+// parseCreateTable parses a CREATE TABLE statement:
+//   - Column definitions with their type and charset
+//   - PRIMARY KEY, KEY, INDEX, UNIQUE, FULLTEXT and SPATIAL indexes
+//   - FOREIGN KEY constraints
+//   - The default charset of the table
+func parseCreateTable(tokens []sqllexer.Token) (Table, error) {
+	p := newDDLParser(tokens)
+	p.accept("CREATE")
+	p.accept("TEMPORARY")
+	p.accept("TABLE")
+	if p.accept("IF") {
+		p.accept("NOT")
+		p.accept("EXISTS")
+	}
+
+	var t Table
+	t.name = p.qualifiedName()
+	if len(t.name) == 0 {
+		return t, fmt.Errorf("analyzer.parseCreateTable: missing table name")
+	}
+	if !p.accept("(") {
+		return t, errNoTableBody
+	}
+
+	for _, def := range p.list() {
+		if err := t.parseDefinition(def); err != nil {
+			return t, fmt.Errorf("analyzer.parseCreateTable: %s: %w", t.name, err)
+		}
+	}
+
+	defaultCharset := p.tableCharset()
+	for i, c := range t.columns {
+		if c.isString() && len(c.charset) == 0 {
+			t.columns[i].charset = defaultCharset
+		}
+	}
+	t.addForeignKeyIndexes()
+	return t, nil
+}
+
+// parseDefinition parses one comma-separated item of a CREATE TABLE body
+func (t *Table) parseDefinition(tokens []sqllexer.Token) error {
+	p := newDDLParser(tokens)
+	if p.done() {
+		return nil
+	}
+
+	var constraintName string
+	if p.accept("CONSTRAINT") {
+		if !p.peek().Is("PRIMARY", "UNIQUE", "FOREIGN", "CHECK") {
+			constraintName = p.next().Name()
+		}
+	}
+
+	switch {
+	case p.accept("PRIMARY"):
+		p.accept("KEY")
+		t.addIndex("PRIMARY", "BTREE", true, p.keyParts())
+	case p.peek().Is("KEY", "INDEX"):
+		p.next()
+		name := p.indexName()
+		t.addIndex(name, p.indexType("BTREE"), false, p.keyParts())
+	case p.accept("UNIQUE"):
+		p.accept("KEY", "INDEX")
+		name := p.indexName()
+		if len(name) == 0 {
+			name = constraintName
+		}
+		t.addIndex(name, p.indexType("BTREE"), true, p.keyParts())
+	case p.peek().Is("FULLTEXT", "SPATIAL"):
+		indexType := strings.ToUpper(p.next().Text)
+		p.accept("KEY", "INDEX")
+		name := p.indexName()
+		t.addIndex(name, indexType, false, p.keyParts())
+	case p.accept("FOREIGN"):
+		p.accept("KEY")
+		fk := ForeignKey{name: constraintName}
+		if name := p.indexName(); len(fk.name) == 0 {
+			fk.name = name
+		}
+		for _, part := range p.keyParts() {
+			fk.columns = append(fk.columns, part.column)
+		}
+		if !p.accept("REFERENCES") {
+			return fmt.Errorf("foreign key %s: missing REFERENCES", fk.name)
+		}
+		fk.refTable = p.qualifiedName()
+		for _, part := range p.keyParts() {
+			fk.refColumns = append(fk.refColumns, part.column)
+		}
+		t.foreignKeys = append(t.foreignKeys, fk)
+	case p.accept("CHECK"):
+		// CHECK constraints don't affect performance
+	default:
+		return t.parseColumn(p)
+	}
+	return nil
+}
+
+// parseColumn parses a column definition such as "email varchar(255) CHARACTER SET utf8mb4 NOT NULL UNIQUE"
+func (t *Table) parseColumn(p *ddlParser) error {
+	name := p.next().Name()
+	if p.done() {
+		return fmt.Errorf("column %s: missing data type", name)
+	}
+
+	c := Column{name: name, dataType: p.dataType()}
+	for !p.done() {
+		switch {
+		case p.accept("CHARACTER"):
+			p.accept("SET")
+			c.charset = strings.ToLower(p.next().Name())
+		case p.accept("CHARSET"):
+			c.charset = strings.ToLower(p.next().Name())
+		case p.accept("COLLATE"):
+			collation := p.next().Name()
+			if len(c.charset) == 0 {
+				c.charset = charsetOf(strings.ToLower(collation))
+			}
+		case p.accept("PRIMARY"):
+			p.accept("KEY")
+			t.addIndex("PRIMARY", "BTREE", true, []keyPart{{column: name}})
+		case p.accept("UNIQUE"):
+			p.accept("KEY")
+			t.addIndex("", "BTREE", true, []keyPart{{column: name}})
+		case p.accept("KEY"):
+			// A single KEY in a column definition means PRIMARY KEY
+			t.addIndex("PRIMARY", "BTREE", true, []keyPart{{column: name}})
+		case p.accept("("):
+			// Expressions in DEFAULT (...) or GENERATED ALWAYS AS (...)
+			p.skipParens()
+		default:
+			p.next()
+		}
+	}
+	t.columns = append(t.columns, c)
+	return nil
+}
+
+type keyPart struct {
+	column  string
+	subPart int64
+	// expression is the expression of a functional key part such as lower(email). column is empty then
+	expression string
+}
+
+// addIndex adds one [Index] per key part the same way SHOW INDEX returns them
+// Unnamed indexes are named after their first column like MySQL does
+func (t *Table) addIndex(name string, indexType string, unique bool, parts []keyPart) {
+	if len(parts) == 0 {
+		return
+	}
+	if len(name) == 0 && len(parts[0].column) == 0 {
+		name = t.uniqueIndexName("functional_index")
+	} else if len(name) == 0 {
+		name = t.uniqueIndexName(parts[0].column)
+	}
+	for i, part := range parts {
+		t.indexes = append(t.indexes, Index{
+			keyName:    name,
+			indexType:  indexType,
+			seq:        int64(i + 1),
+			column:     part.column,
+			unique:     unique,
+			subPart:    part.subPart,
+			expression: part.expression,
+		})
+	}
+}
+
+func (t *Table) uniqueIndexName(base string) string {
+	name := base
+	for i := 2; slices.ContainsFunc(t.indexes, func(idx Index) bool { return idx.keyName == name }); i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	return name
+}
+
+// addForeignKeyIndexes adds the index InnoDB creates for a foreign key if no index starts with its columns
+func (t *Table) addForeignKeyIndexes() {
+	for _, fk := range t.foreignKeys {
+		if t.hasIndexStartingWith(fk.columns) {
+			continue
+		}
+		parts := make([]keyPart, 0)
+		for _, c := range fk.columns {
+			parts = append(parts, keyPart{column: c})
+		}
+		t.addIndex(fk.name, "BTREE", false, parts)
+		for i := len(t.indexes) - len(parts); i < len(t.indexes); i++ {
+			t.indexes[i].implicit = true
+		}
+	}
+}
+
+// asWritten returns the table without the indexes InnoDB creates for foreign keys
+func (t Table) asWritten() Table {
+	t.indexes = slices.DeleteFunc(slices.Clone(t.indexes), func(idx Index) bool {
+		return idx.implicit
+	})
+	return t
+}
+
+// hasIndexStartingWith reports whether an index has the given columns as its leftmost columns
+func (t *Table) hasIndexStartingWith(columns []string) bool {
+	for _, idx := range t.groupedIndexes() {
+		if len(idx) < len(columns) {
+			continue
+		}
+		matches := true
+		for i, c := range columns {
+			if idx[i].column != c {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// groupedIndexes returns the indexes with their columns ordered by sequence in the order they were defined
+func (t *Table) groupedIndexes() []CompositeIndex {
+	res := make([]CompositeIndex, 0)
+	positions := make(map[string]int)
+	for _, idx := range t.indexes {
+		pos, ok := positions[idx.keyName]
+		if !ok {
+			pos = len(res)
+			positions[idx.keyName] = pos
+			res = append(res, CompositeIndex{})
+		}
+		res[pos] = append(res[pos], idx)
+	}
+	for _, idx := range res {
+		slices.SortFunc(idx, func(a, b Index) int {
+			return int(a.seq) - int(b.seq)
+		})
+	}
+	return res
+}
+
+func (t *Table) column(name string) (Column, bool) {
+	for _, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+func newDDLParser(tokens []sqllexer.Token) *ddlParser {
+	return &ddlParser{tokens: tokens}
+}
+
+func (p *ddlParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *ddlParser) peek() sqllexer.Token {
+	if p.done() {
+		return sqllexer.Token{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *ddlParser) next() sqllexer.Token {
+	t := p.peek()
+	if !p.done() {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it matches one of the given keywords or punctuation
+func (p *ddlParser) accept(texts ...string) bool {
+	if p.peek().Is(texts...) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// qualifiedName parses "table", "`table`" or "db.table" and returns the table name
+func (p *ddlParser) qualifiedName() string {
+	name := p.next().Name()
+	for p.accept(".") {
+		name = p.next().Name()
+	}
+	return name
+}
+
+// list consumes a parenthesized, comma-separated list whose opening parenthesis has already been consumed
+// Brackets and braces are treated like parentheses so the same code can walk PHP arrays and closures
+func (p *ddlParser) list() [][]sqllexer.Token {
+	items := make([][]sqllexer.Token, 0)
+	item := make([]sqllexer.Token, 0)
+	depth := 0
+	for !p.done() {
+		t := p.next()
+		switch {
+		case t.Is("(", "[", "{"):
+			depth++
+		case t.Is(")", "]", "}") && depth == 0:
+			return append(items, item)
+		case t.Is(")", "]", "}"):
+			depth--
+		case t.Is(",") && depth == 0:
+			items = append(items, item)
+			item = make([]sqllexer.Token, 0)
+			continue
+		}
+		item = append(item, t)
+	}
+	return append(items, item)
+}
+
+// skipParens consumes tokens up to the parenthesis that closes an already consumed one
+func (p *ddlParser) skipParens() {
+	p.list()
+}
+
+// indexName returns the name of an index if the next token is not the key part list or USING
+func (p *ddlParser) indexName() string {
+	if p.done() || p.peek().Is("(", "USING") {
+		return ""
+	}
+	return p.next().Name()
+}
+
+// indexType parses "USING BTREE" or "USING HASH" before the key parts
+func (p *ddlParser) indexType(def string) string {
+	if p.accept("USING") {
+		return strings.ToUpper(p.next().Text)
+	}
+	return def
+}
+
+// keyParts parses a key part list such as "(email(20), created_at DESC)"
+// Functional key parts such as "((lower(email)))" have no column, only an expression
+func (p *ddlParser) keyParts() []keyPart {
+	if !p.accept("(") {
+		return nil
+	}
+	parts := make([]keyPart, 0)
+	for _, item := range p.list() {
+		if len(item) == 0 {
+			continue
+		}
+		if item[0].Is("(") {
+			end := len(item) - 1
+			for end > 0 && !item[end].Is(")") {
+				end--
+			}
+			texts := make([]string, 0, end)
+			for _, t := range item[1:end] {
+				texts = append(texts, t.Text)
+			}
+			parts = append(parts, keyPart{expression: strings.Join(texts, " ")})
+			continue
+		}
+		part := keyPart{column: item[0].Name()}
+		if len(item) >= 4 && item[1].Is("(") && item[2].Kind == sqllexer.Number {
+			fmt.Sscanf(item[2].Text, "%d", &part.subPart)
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// dataType parses a data type the same way SHOW COLUMNS displays it, for example "varchar(255)" or "int unsigned"
+func (p *ddlParser) dataType() string {
+	dataType := strings.ToLower(p.next().Text)
+	switch dataType {
+	case "integer":
+		dataType = "int"
+	case "bool", "boolean":
+		dataType = "tinyint(1)"
+	}
+
+	if p.accept("(") {
+		args := make([]string, 0)
+		for _, item := range p.list() {
+			var arg strings.Builder
+			for _, t := range item {
+				arg.WriteString(t.Text)
+			}
+			args = append(args, arg.String())
+		}
+		dataType += "(" + strings.Join(args, ",") + ")"
+	}
+	for p.peek().Is("UNSIGNED", "ZEROFILL") {
+		dataType += " " + strings.ToLower(p.next().Text)
+	}
+	return dataType
+}
+
+// tableCharset parses the table options after the column definitions and returns the default charset
+func (p *ddlParser) tableCharset() string {
+	var charset, collation string
+	for !p.done() {
+		switch {
+		case p.accept("CHARACTER"):
+			p.accept("SET")
+			p.accept("=")
+			charset = strings.ToLower(p.next().Name())
+		case p.accept("CHARSET"):
+			p.accept("=")
+			charset = strings.ToLower(p.next().Name())
+		case p.accept("COLLATE"):
+			p.accept("=")
+			collation = strings.ToLower(p.next().Name())
+		default:
+			p.next()
+		}
+	}
+	if len(charset) == 0 && len(collation) != 0 {
+		return charsetOf(collation)
+	}
+	return charset
+}
@@ -0,0 +1,67 @@
+package tableanalyzer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const mysqldump = "" +
+	"-- MySQL dump 10.13\n" +
+	"/*!40101 SET @saved_cs_client     = @@character_set_client */;\n" +
+	"DROP TABLE IF EXISTS `orders`;\n" +
+	"CREATE TABLE `orders` (\n" +
+	"  `id` bigint unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `user_id` bigint unsigned NOT NULL,\n" +
+	"  `status` varchar(32) CHARACTER SET latin1 NOT NULL DEFAULT 'new',\n" +
+	"  `note` text,\n" +
+	"  `total` decimal(10, 2) NOT NULL,\n" +
+	"  `created_at` timestamp NULL DEFAULT (now()),\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  KEY `orders_status_created_at_index` (`status`,`created_at`),\n" +
+	"  UNIQUE KEY `orders_note_unique` (`note`(100)),\n" +
+	"  FULLTEXT KEY (`note`),\n" +
+	"  CONSTRAINT `orders_user_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n" +
+	"CREATE TABLE copy LIKE orders;\n"
+
+func TestParseDDL(t *testing.T) {
+	tables, err := parseDDL(strings.NewReader(mysqldump))
+	assert.Nil(t, err)
+	assert.Len(t, tables, 1)
+
+	orders := tables[0]
+	assert.Equal(t, "orders", orders.name)
+	assert.Equal(t, []Column{
+		{name: "id", dataType: "bigint unsigned"},
+		{name: "user_id", dataType: "bigint unsigned"},
+		{name: "status", dataType: "varchar(32)", charset: "latin1"},
+		{name: "note", dataType: "text", charset: "utf8mb4"},
+		{name: "total", dataType: "decimal(10,2)"},
+		{name: "created_at", dataType: "timestamp"},
+	}, orders.columns)
+
+	assert.Equal(t, []Index{
+		{keyName: "PRIMARY", indexType: "BTREE", seq: 1, column: "id", unique: true},
+		{keyName: "orders_status_created_at_index", indexType: "BTREE", seq: 1, column: "status"},
+		{keyName: "orders_status_created_at_index", indexType: "BTREE", seq: 2, column: "created_at"},
+		{keyName: "orders_note_unique", indexType: "BTREE", seq: 1, column: "note", unique: true, subPart: 100},
+		{keyName: "note", indexType: "FULLTEXT", seq: 1, column: "note"},
+		{keyName: "orders_user_id_foreign", indexType: "BTREE", seq: 1, column: "user_id", implicit: true},
+	}, orders.indexes)
+
+	assert.Equal(t, []ForeignKey{
+		{name: "orders_user_id_foreign", columns: []string{"user_id"}, refTable: "users", refColumns: []string{"id"}},
+	}, orders.foreignKeys)
+}
+
+func TestParseDDL_InlineKeys(t *testing.T) {
+	tables, err := parseDDL(strings.NewReader("create table if not exists app.users (id int primary key, email varchar(255) unique, nickname varchar(64) unique)"))
+	assert.Nil(t, err)
+	assert.Len(t, tables, 1)
+	assert.Equal(t, "users", tables[0].name)
+	assert.Len(t, tables[0].indexes, 3)
+	assert.Equal(t, "PRIMARY", tables[0].indexes[0].keyName)
+	assert.Equal(t, "email", tables[0].indexes[1].keyName)
+	assert.Equal(t, "nickname", tables[0].indexes[2].keyName)
+}
@@ -9,11 +9,26 @@ type (
 		seq         int64
 		column      string
 		cardinality int64
+		unique      bool
+		// subPart is the number of indexed characters if only a prefix of the column is indexed
+		subPart int64
+		// expression is the expression of a functional key part such as lower(`email`). column is empty then
+		expression string
+		// implicit is true for the index InnoDB creates for a foreign key that no declared index starts with
+		implicit bool
 	}
 	CompositeIndexes map[string][]Index
 	CompositeIndex   []Index
 )
 
+// part returns the column of the key part or the expression of a functional key part in parentheses
+func (idx Index) part() string {
+	if len(idx.expression) != 0 {
+		return "(" + idx.expression + ")"
+	}
+	return idx.column
+}
+
 func findCompositeIndexes(indexes []Index) (CompositeIndexes, error) {
 	hmap := make(CompositeIndexes)
 	for _, idx := range indexes {
@@ -0,0 +1,115 @@
+package tableanalyzer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// QueryTable returns the columns and the indexes of a table in the database
+// Other packages use it to cross-reference the columns of a query with the indexes of its tables.
+func QueryTable(ctx context.Context, db *sql.DB, name string) (Table, error) {
+	columns, err := queryColumns(ctx, db, name)
+	if err != nil {
+		return Table{}, fmt.Errorf("analyzer.QueryTable: %w", err)
+	}
+	indexes, err := queryIndexes(ctx, db, name)
+	if err != nil {
+		return Table{}, fmt.Errorf("analyzer.QueryTable: %w", err)
+	}
+	return Table{name: name, columns: columns, indexes: indexes}, nil
+}
+
+// ParseTable parses a single CREATE TABLE statement
+func ParseTable(ddl string) (Table, error) {
+	tables, err := parseDDL(strings.NewReader(ddl))
+	if err != nil {
+		return Table{}, fmt.Errorf("analyzer.ParseTable: %w", err)
+	}
+	if len(tables) != 1 {
+		return Table{}, fmt.Errorf("analyzer.ParseTable: expected one CREATE TABLE statement, found %d", len(tables))
+	}
+	return tables[0], nil
+}
+
+func (t *Table) Name() string {
+	return t.name
+}
+
+// IndexesOf returns the names of the indexes that contain the column in the order they were defined
+func (t *Table) IndexesOf(column string) []string {
+	names := make([]string, 0)
+	for _, idx := range t.groupedIndexes() {
+		for _, part := range idx {
+			if strings.EqualFold(part.column, column) {
+				names = append(names, part.keyName)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// IndexColumns returns the key parts of an index in order. Functional key parts are returned as their expression in parentheses
+func (t *Table) IndexColumns(name string) []string {
+	for _, idx := range t.groupedIndexes() {
+		if strings.EqualFold(idx[0].keyName, name) {
+			return indexColumns(idx)
+		}
+	}
+	return nil
+}
+
+// IndexStartingWith returns the name of the first index that has the given columns as its leftmost columns
+// The primary key is part of every secondary index in InnoDB so it's treated as the last columns of every other index.
+func (t *Table) IndexStartingWith(columns []string) (string, bool) {
+	primary := t.IndexColumns("PRIMARY")
+	for _, idx := range t.groupedIndexes() {
+		parts := indexColumns(idx)
+		if !strings.EqualFold(idx[0].keyName, "PRIMARY") {
+			parts = append(parts, primary...)
+		}
+		if len(parts) < len(columns) {
+			continue
+		}
+		matches := true
+		for i, c := range columns {
+			if !strings.EqualFold(parts[i], c) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return idx[0].keyName, true
+		}
+	}
+	return "", false
+}
+
+// ColumnType returns the data type of a column the way SHOW COLUMNS displays it such as varchar(255) or int unsigned
+func (t *Table) ColumnType(column string) (string, bool) {
+	c, ok := t.column(column)
+	return c.dataType, ok
+}
+
+// FunctionalIndex returns the name of the index that has the expression as a key part such as LOWER(email)
+// Expressions are compared without whitespace, backticks and case.
+func (t *Table) FunctionalIndex(expr string) (string, bool) {
+	for _, idx := range t.indexes {
+		if len(idx.expression) != 0 && normalizeExpression(idx.expression) == normalizeExpression(expr) {
+			return idx.keyName, true
+		}
+	}
+	return "", false
+}
+
+// normalizeExpression removes whitespace and backticks from an expression and lowercases it
+func normalizeExpression(expr string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if r == '`' || r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, expr))
+}
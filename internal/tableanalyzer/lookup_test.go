@@ -0,0 +1,40 @@
+package tableanalyzer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	table, err := ParseTable(`CREATE TABLE users (
+		id bigint unsigned NOT NULL AUTO_INCREMENT,
+		email varchar(255) NOT NULL,
+		created_at timestamp NULL,
+		PRIMARY KEY (id),
+		KEY idx_email_created (email, created_at),
+		KEY ((lower(email)))
+	)`)
+	assert.Nil(t, err)
+	assert.Equal(t, "users", table.Name())
+	assert.Equal(t, []string{"idx_email_created"}, table.IndexesOf("EMAIL"))
+	assert.Empty(t, table.IndexesOf("name"))
+
+	assert.Equal(t, []string{"email", "created_at"}, table.IndexColumns("idx_email_created"))
+	assert.Equal(t, []string{"id"}, table.IndexColumns("primary"))
+	assert.Nil(t, table.IndexColumns("idx_name"))
+
+	name, ok := table.IndexStartingWith([]string{"email", "created_at", "id"})
+	assert.True(t, ok)
+	assert.Equal(t, "idx_email_created", name)
+	_, ok = table.IndexStartingWith([]string{"created_at"})
+	assert.False(t, ok)
+
+	name, ok = table.FunctionalIndex("LOWER(`email`)")
+	assert.True(t, ok)
+	assert.Equal(t, "functional_index", name)
+	_, ok = table.FunctionalIndex("upper(email)")
+	assert.False(t, ok)
+
+	_, err = ParseTable("select 1")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,723 @@
+package tableanalyzer
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+)
+
+type (
+	// schema is the set of table definitions built up by applying migrations in order
+	//
+	// Tables that are only altered by the migrations but created elsewhere contain the changes only
+	schema struct {
+		tables []*Table
+	}
+
+	// methodCall is a single "->name(args)" call of a Blueprint statement such as $table->string('email')->unique()
+	methodCall struct {
+		name string
+		args []phpArg
+	}
+
+	// phpArg is a scalar or an array argument of a PHP method call
+	phpArg struct {
+		values []string
+		array  bool
+	}
+)
+
+// blueprintColumnTypes maps the Blueprint column methods that take only a column name to the MySQL type Laravel creates
+var blueprintColumnTypes = map[string]string{
+	"bigInteger":            "bigint",
+	"unsignedBigInteger":    "bigint unsigned",
+	"foreignId":             "bigint unsigned",
+	"integer":               "int",
+	"unsignedInteger":       "int unsigned",
+	"mediumInteger":         "mediumint",
+	"unsignedMediumInteger": "mediumint unsigned",
+	"smallInteger":          "smallint",
+	"unsignedSmallInteger":  "smallint unsigned",
+	"tinyInteger":           "tinyint",
+	"unsignedTinyInteger":   "tinyint unsigned",
+	"boolean":               "tinyint(1)",
+	"tinyText":              "tinytext",
+	"text":                  "text",
+	"mediumText":            "mediumtext",
+	"longText":              "longtext",
+	"json":                  "json",
+	"jsonb":                 "json",
+	"binary":                "blob",
+	"date":                  "date",
+	"dateTime":              "datetime",
+	"dateTimeTz":            "datetime",
+	"time":                  "time",
+	"timeTz":                "time",
+	"timestamp":             "timestamp",
+	"timestampTz":           "timestamp",
+	"year":                  "year",
+	"float":                 "double",
+	"double":                "double",
+	"uuid":                  "char(36)",
+	"foreignUuid":           "char(36)",
+	"ulid":                  "char(26)",
+	"foreignUlid":           "char(26)",
+	"ipAddress":             "varchar(45)",
+	"macAddress":            "varchar(17)",
+	"geometry":              "geometry",
+	"point":                 "point",
+}
+
+// blueprintIncrements maps the auto-incrementing primary key methods to their MySQL type
+var blueprintIncrements = map[string]string{
+	"id":                "bigint unsigned",
+	"bigIncrements":     "bigint unsigned",
+	"increments":        "int unsigned",
+	"mediumIncrements":  "mediumint unsigned",
+	"smallIncrements":   "smallint unsigned",
+	"tinyIncrements":    "tinyint unsigned",
+	"integerIncrements": "int unsigned",
+}
+
+// laravelCharset is the default charset of Laravel's MySQL connection
+const laravelCharset = "utf8mb4"
+
+// parseMigrations reads Laravel (*.php) and raw SQL (*.sql) migrations from a directory in file name order
+// and applies them one after the other to build the resulting table definitions
+func parseMigrations(dir string) ([]Table, error) {
+	s := &schema{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(path)
+		if d.IsDir() || (ext != ".php" && ext != ".sql") {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if ext == ".php" {
+			err = s.applyLaravelMigration(string(src))
+		} else {
+			err = s.applySQLMigration(string(src))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("analyzer.parseMigrations: %w", err)
+	}
+
+	tables := make([]Table, 0)
+	for _, t := range s.tables {
+		tables = append(tables, *t)
+	}
+	return tables, nil
+}
+
+// table returns the definition of a table and creates an empty one if the table is unknown
+func (s *schema) table(name string) *Table {
+	for _, t := range s.tables {
+		if t.name == name {
+			return t
+		}
+	}
+	t := &Table{name: name}
+	s.tables = append(s.tables, t)
+	return t
+}
+
+func (s *schema) drop(name string) {
+	s.tables = slices.DeleteFunc(s.tables, func(t *Table) bool {
+		return t.name == name
+	})
+}
+
+// applySQLMigration applies the CREATE TABLE, ALTER TABLE, CREATE INDEX, DROP INDEX and DROP TABLE statements of a SQL file
+func (s *schema) applySQLMigration(src string) error {
+	for _, stmt := range sqllexer.SplitStatements(src) {
+		tokens := sqllexer.Tokenize(stmt)
+		p := newDDLParser(tokens)
+		switch {
+		case isCreateTable(tokens):
+			t, err := parseCreateTable(tokens)
+			if err != nil {
+				return err
+			}
+			s.drop(t.name)
+			s.tables = append(s.tables, &t)
+		case isAlterTable(tokens):
+			a, err := parseAlterTable(stmt)
+			if err != nil {
+				return err
+			}
+			s.table(a.table).apply(a)
+		case p.accept("CREATE"):
+			unique := p.accept("UNIQUE")
+			indexType := "BTREE"
+			if p.peek().Is("FULLTEXT", "SPATIAL") {
+				indexType = strings.ToUpper(p.next().Text)
+			}
+			if !p.accept("INDEX") {
+				continue
+			}
+			name := p.next().Name()
+			if !p.accept("ON") {
+				continue
+			}
+			t := s.table(p.qualifiedName())
+			t.addIndex(name, indexType, unique, p.keyParts())
+		case p.accept("DROP"):
+			switch {
+			case p.accept("TABLE"):
+				if p.accept("IF") {
+					p.accept("EXISTS")
+				}
+				s.drop(p.qualifiedName())
+			case p.accept("INDEX"):
+				name := p.next().Name()
+				if p.accept("ON") {
+					s.table(p.qualifiedName()).dropIndex(name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyLaravelMigration applies the Schema::create, Schema::table, Schema::rename and Schema::drop calls of the up() method
+func (s *schema) applyLaravelMigration(src string) error {
+	tokens := sqllexer.Tokenize(stripPHPComments(src))
+	if body, ok := functionBody(tokens, "up"); ok {
+		tokens = body
+	}
+
+	for i := 0; i+3 < len(tokens); i++ {
+		if !tokens[i].Is("Schema") || !tokens[i+1].Is(":") || !tokens[i+2].Is(":") {
+			continue
+		}
+
+		// Skip Schema::connection('mysql')-> and similar calls before the actual method
+		j := i + 3
+		for j+1 < len(tokens) && !tokens[j].Is("create", "table", "drop", "dropIfExists", "rename") {
+			j++
+		}
+		if j+1 >= len(tokens) || !tokens[j+1].Is("(") {
+			continue
+		}
+		method := tokens[j].Text
+
+		p := newDDLParser(tokens[j+2:])
+		args := p.list()
+		i = j + 2 + p.pos - 1
+		if len(args) == 0 || len(args[0]) == 0 {
+			continue
+		}
+		name := phpValue(args[0])
+		if len(name.values) == 0 {
+			log.Printf("skipping Schema::%s(): the table name is an empty array\n", method)
+			continue
+		}
+		table := name.values[0]
+
+		switch method {
+		case "drop", "dropIfExists":
+			s.drop(table)
+		case "rename":
+			if len(args) > 1 {
+				if to := phpValue(args[1]); len(to.values) != 0 {
+					s.table(table).name = to.values[0]
+				}
+			}
+		case "create", "table":
+			if method == "create" {
+				s.drop(table)
+			}
+			if len(args) < 2 {
+				continue
+			}
+			t := s.table(table)
+			variable, statements := closureStatements(args[1])
+			for _, stmt := range statements {
+				calls := blueprintCalls(variable, stmt)
+				if len(calls) == 0 {
+					continue
+				}
+				// $table->index([]) has no columns, a mistake MySQL would reject
+				if c, ok := emptyArrayCall(calls); ok {
+					log.Printf("skipping %s->%s() on %s: an argument is an empty array\n", variable, c.name, table)
+					continue
+				}
+				if err := t.applyBlueprint(calls); err != nil {
+					return fmt.Errorf("Schema::%s('%s'): %w", method, table, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// emptyArrayCall returns the first method call of a Blueprint statement that has an empty array argument
+func emptyArrayCall(calls []methodCall) (methodCall, bool) {
+	for _, c := range calls {
+		if slices.ContainsFunc(c.args, func(a phpArg) bool { return a.array && len(a.values) == 0 }) {
+			return c, true
+		}
+	}
+	return methodCall{}, false
+}
+
+// applyBlueprint applies a single Blueprint statement such as $table->string('email')->unique()
+func (t *Table) applyBlueprint(calls []methodCall) error {
+	first := calls[0]
+	cols := t.blueprintColumns(first)
+	if len(cols) == 0 {
+		return t.applyBlueprintCommand(calls)
+	}
+
+	change := slices.ContainsFunc(calls, func(c methodCall) bool { return c.name == "change" })
+	for _, c := range cols {
+		if change {
+			t.dropColumnDefinition(c.name)
+		}
+		t.columns = append(t.columns, c)
+	}
+
+	// Modifiers like ->unique() only apply to the first column of methods creating multiple columns
+	col := cols[0].name
+	for _, c := range calls[1:] {
+		switch c.name {
+		case "primary":
+			t.addIndex("PRIMARY", "BTREE", true, []keyPart{{column: col}})
+		case "index", "unique", "fullText", "spatialIndex":
+			t.addBlueprintIndex(c.name, []string{col}, c.arg(0))
+		case "charset":
+			t.setCharset(col, c.arg(0))
+		case "collation":
+			t.setCharset(col, charsetOf(c.arg(0)))
+		case "constrained":
+			refTable := c.arg(0)
+			if len(refTable) == 0 {
+				refTable = guessTableName(col)
+			}
+			refColumn := c.arg(1)
+			if len(refColumn) == 0 {
+				refColumn = "id"
+			}
+			t.addForeignKey(laravelIndexName(t.name, []string{col}, "foreign"), []string{col}, refTable, []string{refColumn})
+		case "references":
+			t.addForeignKey(laravelIndexName(t.name, []string{col}, "foreign"), []string{col}, chainArg(calls, "on"), c.argValues(0))
+		}
+	}
+	return nil
+}
+
+// applyBlueprintCommand applies Blueprint methods that don't create columns such as $table->index(['a', 'b'])
+func (t *Table) applyBlueprintCommand(calls []methodCall) error {
+	c := calls[0]
+	switch c.name {
+	case "primary":
+		t.dropIndex("PRIMARY")
+		t.addIndex("PRIMARY", "BTREE", true, columnParts(c.argValues(0)))
+	case "index", "unique", "fullText", "spatialIndex":
+		t.addBlueprintIndex(c.name, c.argValues(0), c.arg(1))
+	case "foreign":
+		cols := c.argValues(0)
+		name := c.arg(1)
+		if len(name) == 0 {
+			name = laravelIndexName(t.name, cols, "foreign")
+		}
+		refColumns := []string{"id"}
+		for _, next := range calls[1:] {
+			if next.name == "references" {
+				refColumns = next.argValues(0)
+			}
+		}
+		t.addForeignKey(name, cols, chainArg(calls, "on"), refColumns)
+	case "dropColumn", "dropColumns":
+		for _, arg := range c.args {
+			for _, col := range arg.values {
+				t.dropColumn(col)
+			}
+		}
+	case "dropIndex", "dropUnique", "dropFullText", "dropSpatialIndex", "dropPrimary":
+		suffix := map[string]string{"dropIndex": "index", "dropUnique": "unique", "dropFullText": "fulltext", "dropSpatialIndex": "spatialindex"}[c.name]
+		t.dropIndex(t.blueprintIndexName(c, suffix))
+		if c.name == "dropPrimary" {
+			t.dropIndex("PRIMARY")
+		}
+	case "dropForeign":
+		name := t.blueprintIndexName(c, "foreign")
+		t.foreignKeys = slices.DeleteFunc(t.foreignKeys, func(fk ForeignKey) bool {
+			return fk.name == name
+		})
+	case "dropConstrainedForeignId":
+		col := c.arg(0)
+		name := laravelIndexName(t.name, []string{col}, "foreign")
+		t.foreignKeys = slices.DeleteFunc(t.foreignKeys, func(fk ForeignKey) bool {
+			return fk.name == name
+		})
+		t.dropColumn(col)
+	case "renameColumn":
+		t.apply(AlterTable{ops: []AlterOp{{kind: alterRenameColumn, oldName: c.arg(0), name: c.arg(1)}}})
+	case "renameIndex":
+		t.apply(AlterTable{ops: []AlterOp{{kind: alterRenameIndex, oldName: c.arg(0), name: c.arg(1)}}})
+	case "dropTimestamps", "dropTimestampsTz":
+		t.dropColumn("created_at")
+		t.dropColumn("updated_at")
+	case "dropSoftDeletes", "dropSoftDeletesTz":
+		t.dropColumn(c.argOr(0, "deleted_at"))
+	case "dropRememberToken":
+		t.dropColumn("remember_token")
+	case "dropMorphs":
+		t.dropColumn(c.arg(0) + "_type")
+		t.dropColumn(c.arg(0) + "_id")
+	case "rename":
+		t.name = c.arg(0)
+	}
+	return nil
+}
+
+// blueprintColumns returns the columns a Blueprint method creates or nil if it's not a column method
+func (t *Table) blueprintColumns(c methodCall) []Column {
+	name := c.arg(0)
+	if dataType, ok := blueprintIncrements[c.name]; ok {
+		if len(name) == 0 {
+			name = "id"
+		}
+		t.addIndex("PRIMARY", "BTREE", true, []keyPart{{column: name}})
+		return []Column{{name: name, dataType: dataType}}
+	}
+	if dataType, ok := blueprintColumnTypes[c.name]; ok {
+		return []Column{newBlueprintColumn(name, dataType)}
+	}
+
+	switch c.name {
+	case "string":
+		return []Column{newBlueprintColumn(name, fmt.Sprintf("varchar(%s)", c.argOr(1, "255")))}
+	case "char":
+		return []Column{newBlueprintColumn(name, fmt.Sprintf("char(%s)", c.argOr(1, "255")))}
+	case "decimal", "unsignedDecimal":
+		dataType := fmt.Sprintf("decimal(%s,%s)", c.argOr(1, "8"), c.argOr(2, "2"))
+		if c.name == "unsignedDecimal" {
+			dataType += " unsigned"
+		}
+		return []Column{newBlueprintColumn(name, dataType)}
+	case "enum", "set":
+		values := make([]string, 0)
+		if len(c.args) > 1 {
+			for _, v := range c.args[1].values {
+				values = append(values, "'"+v+"'")
+			}
+		}
+		return []Column{newBlueprintColumn(name, fmt.Sprintf("%s(%s)", c.name, strings.Join(values, ",")))}
+	case "foreignIdFor":
+		// foreignIdFor(User::class) creates user_id
+		model, _, _ := strings.Cut(name, "::")
+		if i := strings.LastIndex(model, "\\"); i != -1 {
+			model = model[i+1:]
+		}
+		return []Column{newBlueprintColumn(c.argOr(1, snakeCase(model)+"_id"), "bigint unsigned")}
+	case "timestamps", "timestampsTz", "nullableTimestamps":
+		return []Column{newBlueprintColumn("created_at", "timestamp"), newBlueprintColumn("updated_at", "timestamp")}
+	case "softDeletes", "softDeletesTz":
+		return []Column{newBlueprintColumn(c.argOr(0, "deleted_at"), "timestamp")}
+	case "rememberToken":
+		return []Column{newBlueprintColumn("remember_token", "varchar(100)")}
+	case "morphs", "nullableMorphs", "uuidMorphs", "nullableUuidMorphs", "ulidMorphs", "nullableUlidMorphs":
+		idType := "bigint unsigned"
+		if strings.Contains(c.name, "uuid") || strings.Contains(c.name, "Uuid") {
+			idType = "char(36)"
+		}
+		if strings.Contains(c.name, "ulid") || strings.Contains(c.name, "Ulid") {
+			idType = "char(26)"
+		}
+		typeCol := newBlueprintColumn(name+"_type", "varchar(255)")
+		idCol := newBlueprintColumn(name+"_id", idType)
+		indexName := c.arg(1)
+		if len(indexName) == 0 {
+			indexName = laravelIndexName(t.name, []string{typeCol.name, idCol.name}, "index")
+		}
+		t.addIndex(indexName, "BTREE", false, columnParts([]string{typeCol.name, idCol.name}))
+		return []Column{typeCol, idCol}
+	}
+	return nil
+}
+
+// addBlueprintIndex adds an index the way Laravel names it if no name is given, for example users_email_unique
+func (t *Table) addBlueprintIndex(method string, cols []string, name string) {
+	suffix := map[string]string{"index": "index", "unique": "unique", "fullText": "fulltext", "spatialIndex": "spatialindex"}[method]
+	indexType := map[string]string{"fullText": "FULLTEXT", "spatialIndex": "SPATIAL"}[method]
+	if len(indexType) == 0 {
+		indexType = "BTREE"
+	}
+	if len(name) == 0 {
+		name = laravelIndexName(t.name, cols, suffix)
+	}
+	t.addIndex(name, indexType, method == "unique", columnParts(cols))
+}
+
+// blueprintIndexName returns the index name from dropIndex('name') or dropIndex(['col1', 'col2'])
+func (t *Table) blueprintIndexName(c methodCall, suffix string) string {
+	if len(c.args) > 0 && c.args[0].array {
+		return laravelIndexName(t.name, c.args[0].values, suffix)
+	}
+	return c.arg(0)
+}
+
+func (t *Table) addForeignKey(name string, cols []string, refTable string, refColumns []string) {
+	t.foreignKeys = append(t.foreignKeys, ForeignKey{name: name, columns: cols, refTable: refTable, refColumns: refColumns})
+	t.addForeignKeyIndexes()
+}
+
+func (t *Table) setCharset(col, charset string) {
+	for i, c := range t.columns {
+		if c.name == col {
+			t.columns[i].charset = charset
+		}
+	}
+}
+
+// dropColumnDefinition removes a column without touching the indexes, used when a column is redefined with ->change()
+func (t *Table) dropColumnDefinition(name string) {
+	t.columns = slices.DeleteFunc(t.columns, func(c Column) bool {
+		return c.name == name
+	})
+}
+
+func newBlueprintColumn(name, dataType string) Column {
+	c := Column{name: name, dataType: dataType}
+	if c.isString() {
+		c.charset = laravelCharset
+	}
+	return c
+}
+
+// laravelIndexName returns the name Laravel generates for an index, for example users_email_unique
+func laravelIndexName(table string, cols []string, suffix string) string {
+	name := strings.ToLower(table + "_" + strings.Join(cols, "_") + "_" + suffix)
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// guessTableName returns the table ->constrained() references without arguments, for example users for user_id
+func guessTableName(col string) string {
+	name := strings.TrimSuffix(col, "_id")
+	if strings.HasSuffix(name, "y") && !strings.HasSuffix(name, "ey") {
+		return strings.TrimSuffix(name, "y") + "ies"
+	}
+	if strings.HasSuffix(name, "s") {
+		return name + "es"
+	}
+	return name + "s"
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func columnParts(cols []string) []keyPart {
+	parts := make([]keyPart, 0)
+	for _, c := range cols {
+		parts = append(parts, keyPart{column: c})
+	}
+	return parts
+}
+
+// chainArg returns the first argument of a method in the call chain, for example "users" from ->on('users')
+func chainArg(calls []methodCall, method string) string {
+	for _, c := range calls {
+		if c.name == method {
+			return c.arg(0)
+		}
+	}
+	return ""
+}
+
+// arg returns the i-th argument as a string or an empty string if it's missing
+func (c methodCall) arg(i int) string {
+	return c.argOr(i, "")
+}
+
+func (c methodCall) argOr(i int, def string) string {
+	if i >= len(c.args) || len(c.args[i].values) == 0 || c.args[i].values[0] == "null" {
+		return def
+	}
+	return c.args[i].values[0]
+}
+
+// argValues returns the values of an array argument or a single scalar argument as a slice
+func (c methodCall) argValues(i int) []string {
+	if i >= len(c.args) {
+		return nil
+	}
+	return c.args[i].values
+}
+
+// functionBody returns the tokens between the braces of a PHP method such as "public function up(): void { ... }"
+func functionBody(tokens []sqllexer.Token, name string) ([]sqllexer.Token, bool) {
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i].Is("function") || !tokens[i+1].Is(name) {
+			continue
+		}
+		for j := i + 2; j < len(tokens); j++ {
+			if tokens[j].Is("{") {
+				return bracedBlock(tokens[j+1:]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// bracedBlock returns the tokens up to the brace that closes an already consumed opening brace
+func bracedBlock(tokens []sqllexer.Token) []sqllexer.Token {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.Is("{"):
+			depth++
+		case t.Is("}") && depth == 0:
+			return tokens[:i]
+		case t.Is("}"):
+			depth--
+		}
+	}
+	return tokens
+}
+
+// closureStatements returns the Blueprint variable name and the statements of a "function (Blueprint $table) { ... }" closure
+func closureStatements(tokens []sqllexer.Token) (string, [][]sqllexer.Token) {
+	variable := ""
+	for i, t := range tokens {
+		if len(variable) == 0 && strings.HasPrefix(t.Text, "$") {
+			variable = t.Text
+		}
+		if t.Is("{") {
+			return variable, splitPHPStatements(bracedBlock(tokens[i+1:]))
+		}
+	}
+	return variable, nil
+}
+
+// splitPHPStatements splits tokens on semicolons that are not nested in parentheses, brackets or braces
+func splitPHPStatements(tokens []sqllexer.Token) [][]sqllexer.Token {
+	statements := make([][]sqllexer.Token, 0)
+	start, depth := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.Is("(", "[", "{"):
+			depth++
+		case t.Is(")", "]", "}"):
+			depth--
+		case t.Is(";") && depth == 0:
+			statements = append(statements, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(statements, tokens[start:])
+}
+
+// blueprintCalls parses "$table->string('email', 100)->unique()" into its method calls
+func blueprintCalls(variable string, stmt []sqllexer.Token) []methodCall {
+	if len(stmt) < 2 || stmt[0].Text != variable {
+		return nil
+	}
+
+	calls := make([]methodCall, 0)
+	p := newDDLParser(stmt[1:])
+	for p.accept("->") {
+		c := methodCall{name: p.next().Text}
+		if p.accept("(") {
+			for _, arg := range p.list() {
+				if len(arg) != 0 {
+					c.args = append(c.args, phpValue(arg))
+				}
+			}
+		}
+		calls = append(calls, c)
+	}
+	return calls
+}
+
+// phpValue converts the tokens of an argument such as 'email', 100, ['a', 'b'] or User::class
+func phpValue(tokens []sqllexer.Token) phpArg {
+	// Named arguments such as length: 100
+	if len(tokens) > 2 && tokens[0].Kind == sqllexer.Word && tokens[1].Is(":") && !tokens[2].Is(":") {
+		tokens = tokens[2:]
+	}
+
+	if len(tokens) > 0 && tokens[0].Is("[") {
+		arg := phpArg{array: true}
+		p := newDDLParser(tokens[1:])
+		for _, item := range p.list() {
+			if len(item) != 0 {
+				arg.values = append(arg.values, phpScalar(item))
+			}
+		}
+		return arg
+	}
+	return phpArg{values: []string{phpScalar(tokens)}}
+}
+
+func phpScalar(tokens []sqllexer.Token) string {
+	if len(tokens) == 1 && tokens[0].Kind == sqllexer.String {
+		return tokens[0].Value()
+	}
+	var v strings.Builder
+	for _, t := range tokens {
+		v.WriteString(t.Text)
+	}
+	return v.String()
+}
+
+// stripPHPComments replaces "//", "#" and "/* */" comments with spaces so apostrophes in comments don't start strings
+func stripPHPComments(src string) string {
+	b := []byte(src)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '\'' || b[i] == '"':
+			quote := b[i]
+			for i++; i < len(b) && b[i] != quote; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+		case b[i] == '#' || (b[i] == '/' && i+1 < len(b) && b[i+1] == '/'):
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			for ; i < len(b) && !(b[i] == '*' && i+1 < len(b) && b[i+1] == '/'); i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+			if i+1 < len(b) {
+				b[i], b[i+1] = ' ', ' '
+				i++
+			}
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,110 @@
+package tableanalyzer
+
+import (
+	"github.com/mmartinjoo/explainer/internal/platform/sqllexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMigrations(t *testing.T) {
+	tables, err := parseMigrations("./testdata/migrations")
+	assert.Nil(t, err)
+	assert.Len(t, tables, 2)
+
+	users := tables[0]
+	assert.Equal(t, "users", users.name)
+	assert.Equal(t, []Column{
+		{name: "id", dataType: "bigint unsigned"},
+		{name: "name", dataType: "varchar(255)", charset: "utf8mb4"},
+		{name: "email", dataType: "varchar(255)", charset: "utf8mb4"},
+		{name: "country", dataType: "varchar(2)", charset: "utf8mb4"},
+		{name: "remember_token", dataType: "varchar(100)", charset: "utf8mb4"},
+		{name: "created_at", dataType: "timestamp"},
+		{name: "updated_at", dataType: "timestamp"},
+	}, users.columns)
+	assert.Equal(t, []Index{
+		{keyName: "PRIMARY", indexType: "BTREE", seq: 1, column: "id", unique: true},
+		{keyName: "users_email_unique", indexType: "BTREE", seq: 1, column: "email", unique: true},
+	}, users.indexes)
+
+	orders := tables[1]
+	assert.Equal(t, "orders", orders.name)
+	col, ok := orders.column("user_id")
+	assert.True(t, ok)
+	assert.Equal(t, "bigint unsigned", col.dataType)
+	assert.Equal(t, []ForeignKey{
+		{name: "orders_user_id_foreign", columns: []string{"user_id"}, refTable: "users", refColumns: []string{"id"}},
+	}, orders.foreignKeys)
+	assert.True(t, orders.hasIndexStartingWith([]string{"user_id"}))
+	assert.False(t, orders.hasIndexStartingWith([]string{"coupon_id"}))
+	assert.True(t, orders.hasIndexStartingWith([]string{"reference"}))
+}
+
+func TestCheckSchema_Migrations(t *testing.T) {
+	tables, err := parseMigrations("./testdata/migrations")
+	assert.Nil(t, err)
+
+	res := checkSchema(tables[1])
+	assert.Len(t, res.foreignKeyIndexWarnings, 2)
+	assert.Contains(t, res.foreignKeyIndexWarnings[0], "coupon_id")
+	assert.Contains(t, res.foreignKeyIndexWarnings[1], "InnoDB creates one implicitly")
+	assert.Len(t, res.redundantIndexWarnings, 1)
+	assert.Contains(t, res.redundantIndexWarnings[0], "orders_status_index")
+	assert.Len(t, res.stringIndexLengthWarnings, 1)
+	assert.Contains(t, res.stringIndexLengthWarnings[0], "orders_reference_index")
+}
+
+func TestBlueprintCalls(t *testing.T) {
+	calls := blueprintCalls("$table", sqllexer.Tokenize("$table->enum('status', ['new', 'paid'])->default('new')"))
+	assert.Equal(t, []methodCall{
+		{name: "enum", args: []phpArg{{values: []string{"status"}}, {values: []string{"new", "paid"}, array: true}}},
+		{name: "default", args: []phpArg{{values: []string{"new"}}}},
+	}, calls)
+}
+
+func TestStripPHPComments(t *testing.T) {
+	src := "$a = 'it''s'; // don't\n/* it's */ $b = \"#\";"
+	stripped := stripPHPComments(src)
+	assert.Len(t, stripped, len(src))
+	assert.NotContains(t, stripped, "don't")
+	assert.NotContains(t, stripped, "it's */")
+	assert.Contains(t, stripped, "$a = 'it''s';")
+	assert.Contains(t, stripped, "$b = \"#\";")
+}
+
+func TestGuessTableName(t *testing.T) {
+	assert.Equal(t, "users", guessTableName("user_id"))
+	assert.Equal(t, "categories", guessTableName("category_id"))
+	assert.Equal(t, "addresses", guessTableName("address_id"))
+}
+
+func TestLaravelIndexName(t *testing.T) {
+	assert.Equal(t, "orders_status_created_at_index", laravelIndexName("orders", []string{"status", "created_at"}, "index"))
+}
+
+func TestApplyLaravelMigration_EmptyArrays(t *testing.T) {
+	s := &schema{}
+	err := s.applyLaravelMigration(`<?php
+return new class extends Migration {
+    public function up(): void
+    {
+        Schema::table('orders', function (Blueprint $table) {
+            $table->index([]);
+            $table->unique([], 'orders_empty_unique');
+            $table->primary([]);
+            $table->foreign([])->references('id')->on('users');
+            $table->dropIndex([]);
+            $table->string('reference')->index();
+        });
+        Schema::create([], function (Blueprint $table) {
+            $table->id();
+        });
+        Schema::rename('orders', []);
+    }
+};`)
+	assert.Nil(t, err)
+	assert.Len(t, s.tables, 1)
+	assert.Equal(t, "orders", s.tables[0].name)
+	assert.Equal(t, []Index{{keyName: "orders_reference_index", indexType: "BTREE", seq: 1, column: "reference"}}, s.tables[0].indexes)
+	assert.Empty(t, s.tables[0].foreignKeys)
+}
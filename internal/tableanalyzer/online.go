@@ -0,0 +1,416 @@
+package tableanalyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+)
+
+type algorithm int
+
+// The algorithms are ordered by cost so the most expensive operation determines the algorithm of a statement
+const (
+	algorithmInstant algorithm = iota
+	algorithmInplace
+	algorithmCopy
+)
+
+const (
+	// rebuildBytesPerSecond is a conservative estimate of how fast InnoDB copies a table on a busy server
+	rebuildBytesPerSecond = 32 * 1024 * 1024
+	// indexBuildBytesPerSecond is a conservative estimate of how fast InnoDB scans a table to build a secondary index
+	indexBuildBytesPerSecond = 64 * 1024 * 1024
+	// onlineSchemaChangeRows and onlineSchemaChangeBytes are the table sizes above which a blocking or rebuilding
+	// ALTER TABLE should be run with gh-ost or pt-online-schema-change
+	onlineSchemaChangeRows  = 1_000_000
+	onlineSchemaChangeBytes = 1024 * 1024 * 1024
+)
+
+type (
+	TableSize struct {
+		rows        int64
+		dataLength  int64
+		indexLength int64
+	}
+
+	mysqlVersion struct {
+		major int
+		minor int
+		patch int
+	}
+
+	// opAssessment describes how MySQL executes a single operation of an ALTER TABLE statement
+	opAssessment struct {
+		op           AlterOp
+		algorithm    algorithm
+		rebuild      bool
+		blocksWrites bool
+		note         string
+	}
+
+	AlterResult struct {
+		stmt         string
+		table        string
+		size         TableSize
+		ops          []opAssessment
+		algorithm    algorithm
+		rebuild      bool
+		blocksWrites bool
+		duration     time.Duration
+		diskUsage    int64
+		warnings     []string
+		recommended  string
+		grade        float32
+	}
+)
+
+func parseAlgorithm(s string) (algorithm, bool) {
+	switch s {
+	case "INSTANT":
+		return algorithmInstant, true
+	case "INPLACE":
+		return algorithmInplace, true
+	case "COPY":
+		return algorithmCopy, true
+	default:
+		return algorithmInstant, false
+	}
+}
+
+func (a algorithm) String() string {
+	switch a {
+	case algorithmInstant:
+		return "INSTANT"
+	case algorithmInplace:
+		return "INPLACE"
+	default:
+		return "COPY"
+	}
+}
+
+// parseVersion parses the output of SELECT VERSION() such as "8.0.35" or "8.0.35-0ubuntu0.22.04.1"
+func parseVersion(v string) mysqlVersion {
+	var res mysqlVersion
+	parts := strings.SplitN(v, ".", 3)
+	nums := []*int{&res.major, &res.minor, &res.patch}
+	for i, p := range parts {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		*nums[i], _ = strconv.Atoi(p[:end])
+	}
+	return res
+}
+
+func (v mysqlVersion) atLeast(major, minor, patch int) bool {
+	if v.major != major {
+		return v.major > major
+	}
+	if v.minor != minor {
+		return v.minor > minor
+	}
+	return v.patch >= patch
+}
+
+// assessAlter decides how MySQL executes an ALTER TABLE statement based on the online DDL rules of MySQL 8
+// and estimates its duration and disk usage based on the size of the table
+func assessAlter(stmt string, a AlterTable, size TableSize, cols []Column, version mysqlVersion) AlterResult {
+	res := AlterResult{
+		stmt:  stmt,
+		table: a.table,
+		size:  size,
+		grade: grade.MaxGrade,
+	}
+
+	var requestedAlgorithm, requestedLock string
+	dropsPrimaryKey, addsPrimaryKey := false, false
+	for _, op := range a.ops {
+		switch op.kind {
+		case alterAlgorithm:
+			requestedAlgorithm = op.name
+			continue
+		case alterLock:
+			requestedLock = op.name
+			continue
+		case alterAddIndex:
+			// Malformed input or a key part the parser doesn't read leaves the index without columns
+			if len(op.index) == 0 {
+				res.warnings = append(res.warnings, fmt.Sprintf("'%s' could not be parsed so it's left out of the assessment.", op.text))
+				continue
+			}
+		case alterDropPrimaryKey:
+			dropsPrimaryKey = true
+		case alterAddPrimaryKey:
+			addsPrimaryKey = true
+		}
+		res.ops = append(res.ops, assessOp(op, cols, version))
+	}
+
+	// Dropping the primary key only needs a copy if no new primary key is added in the same statement
+	if dropsPrimaryKey && addsPrimaryKey {
+		for i, o := range res.ops {
+			if o.op.kind == alterDropPrimaryKey {
+				res.ops[i] = opAssessment{op: o.op, algorithm: algorithmInplace, rebuild: true, note: "The primary key is replaced in the same statement so the table is rebuilt in place."}
+			}
+		}
+	}
+
+	tableBytes := size.dataLength + size.indexLength
+	for _, o := range res.ops {
+		res.algorithm = max(res.algorithm, o.algorithm)
+		res.rebuild = res.rebuild || o.rebuild
+		res.blocksWrites = res.blocksWrites || o.blocksWrites
+
+		if o.algorithm == algorithmInplace && !o.rebuild && (o.op.kind == alterAddIndex || o.op.kind == alterAddPrimaryKey) {
+			indexBytes := size.rows * indexEntryBytes(o.op.index, cols)
+			res.diskUsage += indexBytes
+			res.duration += bytesDuration(size.dataLength, indexBuildBytesPerSecond)
+		}
+	}
+	if res.rebuild || res.algorithm == algorithmCopy {
+		// A rebuild writes a full copy of the table next to the original one
+		res.diskUsage = tableBytes
+		res.duration = bytesDuration(tableBytes, rebuildBytesPerSecond)
+	}
+
+	// ALGORITHM is the least efficient algorithm MySQL may use, anything cheaper is accepted
+	if requested, ok := parseAlgorithm(requestedAlgorithm); ok && requested < res.algorithm {
+		res.warnings = append(res.warnings, fmt.Sprintf("The statement requests ALGORITHM=%s but at least one operation needs %s. MySQL will reject the statement.", requestedAlgorithm, res.algorithm))
+	}
+	if requestedLock == "NONE" && res.blocksWrites {
+		res.warnings = append(res.warnings, "The statement requests LOCK=NONE but at least one operation blocks concurrent writes. MySQL will reject the statement.")
+	}
+
+	big := size.rows >= onlineSchemaChangeRows || tableBytes >= onlineSchemaChangeBytes
+	switch {
+	case res.algorithm == algorithmCopy || res.blocksWrites:
+		res.grade = grade.Dec(res.grade, 3)
+	case res.rebuild:
+		res.grade = grade.Dec(res.grade, 1)
+	case res.algorithm == algorithmInplace:
+		res.grade = grade.Dec(res.grade, 0.5)
+	}
+	if big && (res.algorithm == algorithmCopy || res.blocksWrites || res.rebuild) {
+		res.grade = grade.Dec(res.grade, 1)
+		res.recommended = fmt.Sprintf("'%s' is large enough that this change will block writes or cause replication lag for about %s. Run it with gh-ost or pt-online-schema-change: they copy the table in small chunks in the background, keep it in sync and swap the tables at the end with a short lock.", a.table, formatDuration(res.duration))
+	}
+	return res
+}
+
+// assessOp decides the algorithm, the rebuild and the locking of a single operation based on the MySQL 8 online DDL table
+func assessOp(op AlterOp, cols []Column, version mysqlVersion) opAssessment {
+	res := opAssessment{op: op}
+	text := strings.ToUpper(op.text)
+
+	switch op.kind {
+	case alterAddColumn:
+		switch {
+		case strings.Contains(text, "AUTO_INCREMENT"):
+			res.algorithm, res.rebuild, res.blocksWrites = algorithmInplace, true, true
+			res.note = "Adding an AUTO_INCREMENT column rebuilds the table and blocks concurrent writes."
+		case strings.Contains(text, " STORED"):
+			res.algorithm, res.rebuild, res.blocksWrites = algorithmCopy, true, true
+			res.note = "Adding a STORED generated column copies the table."
+		case version.atLeast(8, 0, 29), version.atLeast(8, 0, 12) && len(op.position) == 0:
+			res.algorithm = algorithmInstant
+		default:
+			res.algorithm, res.rebuild = algorithmInplace, true
+			res.note = "This MySQL version can only add columns instantly as the last column (8.0.12+) or anywhere from 8.0.29."
+		}
+	case alterDropColumn:
+		res.algorithm, res.rebuild = algorithmInplace, true
+		if version.atLeast(8, 0, 29) {
+			res.algorithm, res.rebuild = algorithmInstant, false
+		}
+	case alterRenameColumn:
+		res.algorithm = algorithmInplace
+		if version.atLeast(8, 0, 28) {
+			res.algorithm = algorithmInstant
+		}
+	case alterModifyColumn:
+		res = assessModifyColumn(op, cols, version)
+	case alterColumnDefault, alterRenameIndex, alterRenameTable, alterOther:
+		res.algorithm = algorithmInstant
+	case alterAddIndex:
+		res.algorithm = algorithmInplace
+		if len(op.index) == 0 {
+			res.note = "The key parts of the index could not be parsed."
+			break
+		}
+		switch op.index[0].indexType {
+		case "FULLTEXT":
+			res.rebuild, res.blocksWrites = true, true
+			res.note = "Adding a FULLTEXT index blocks concurrent writes. The first FULLTEXT index also rebuilds the table."
+		case "SPATIAL":
+			res.blocksWrites = true
+			res.note = "Adding a SPATIAL index blocks concurrent writes."
+		}
+	case alterDropIndex, alterDropForeignKey:
+		res.algorithm = algorithmInplace
+	case alterAddPrimaryKey:
+		res.algorithm, res.rebuild = algorithmInplace, true
+		res.note = "The clustered index is rebuilt which rewrites the whole table."
+	case alterDropPrimaryKey:
+		res.algorithm, res.rebuild, res.blocksWrites = algorithmCopy, true, true
+		res.note = "Dropping the primary key without adding a new one copies the table."
+	case alterAddForeignKey:
+		res.algorithm, res.rebuild, res.blocksWrites = algorithmCopy, true, true
+		res.note = "Adding a foreign key copies the table unless foreign_key_checks is disabled. With SET foreign_key_checks = 0 it runs INPLACE but existing rows are not validated."
+	case alterConvertCharset:
+		res.algorithm, res.rebuild, res.blocksWrites = algorithmCopy, true, true
+		res.note = "Converting the charset rewrites every string column."
+	case alterRebuild:
+		res.algorithm, res.rebuild = algorithmInplace, true
+	}
+	return res
+}
+
+// assessModifyColumn decides how MODIFY and CHANGE COLUMN are executed based on the current definition of the column
+func assessModifyColumn(op AlterOp, cols []Column, version mysqlVersion) opAssessment {
+	res := opAssessment{op: op}
+
+	var current Column
+	found := false
+	for _, c := range cols {
+		if strings.EqualFold(c.name, op.oldName) {
+			current, found = c, true
+		}
+	}
+
+	newCol := op.column
+	if found && len(newCol.charset) == 0 {
+		newCol.charset = current.charset
+	}
+
+	switch {
+	case !found:
+		res.algorithm, res.rebuild, res.blocksWrites = algorithmCopy, true, true
+		res.note = fmt.Sprintf("The current definition of %s is unknown so changing its data type is assumed. That copies the table.", op.oldName)
+	case current.dataType == newCol.dataType && current.charset == newCol.charset && len(op.position) == 0:
+		// Only the name or the attributes change
+		res.algorithm, res.rebuild = algorithmInplace, true
+		res.note = "Changing NULL/NOT NULL rebuilds the table in place. Renaming the column or changing its default or comment only changes the metadata."
+		if current.name != newCol.name && version.atLeast(8, 0, 28) {
+			res.note = "Renaming a column only changes the metadata but changing NULL/NOT NULL in the same statement rebuilds the table in place."
+		}
+	case current.dataType == newCol.dataType && current.charset == newCol.charset:
+		res.algorithm, res.rebuild = algorithmInplace, true
+		res.note = "Reordering columns rebuilds the table in place."
+	case isVarcharExtension(current, newCol):
+		res.algorithm = algorithmInplace
+		res.note = "Extending a VARCHAR column is done in place without a rebuild as long as its length prefix stays 1 or 2 bytes."
+	default:
+		res.algorithm, res.rebuild, res.blocksWrites = algorithmCopy, true, true
+		res.note = fmt.Sprintf("Changing the data type from %s to %s copies the table and blocks concurrent writes.", current.dataType, newCol.dataType)
+	}
+	return res
+}
+
+// isVarcharExtension reports whether a VARCHAR column is extended without changing the number of length bytes
+// Values up to 255 bytes store their length in 1 byte, longer ones in 2 bytes
+func isVarcharExtension(current, newCol Column) bool {
+	if !strings.HasPrefix(current.dataType, "varchar(") || !strings.HasPrefix(newCol.dataType, "varchar(") || current.charset != newCol.charset {
+		return false
+	}
+	if newCol.length() < current.length() {
+		return false
+	}
+	currentBytes := current.length() * current.bytesPerChar()
+	newBytes := newCol.length() * newCol.bytesPerChar()
+	return (currentBytes <= 255) == (newBytes <= 255)
+}
+
+// indexEntryBytes estimates the size of one entry in a new secondary index: the key parts plus the primary key
+func indexEntryBytes(index []Index, cols []Column) int64 {
+	const primaryKeyBytes = 8
+	size := int64(primaryKeyBytes)
+	for _, part := range index {
+		size += keyPartBytes(part, cols)
+	}
+	return size
+}
+
+func keyPartBytes(part Index, cols []Column) int64 {
+	for _, c := range cols {
+		if !strings.EqualFold(c.name, part.column) || !c.isString() {
+			continue
+		}
+		chars := c.length()
+		if part.subPart > 0 {
+			chars = part.subPart
+		}
+		return chars * c.bytesPerChar()
+	}
+	// Numeric and temporal columns take at most 8 bytes
+	return 8
+}
+
+func bytesDuration(bytes, bytesPerSecond int64) time.Duration {
+	return time.Duration(float64(bytes) / float64(bytesPerSecond) * float64(time.Second))
+}
+
+// formatDuration formats an estimate without false precision such as 3m20s or 2h15m
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return "less than a second"
+	case d < time.Hour:
+		return d.Round(time.Second).String()
+	default:
+		return d.Round(time.Minute).String()
+	}
+}
+
+func (r *AlterResult) Grade() float32 {
+	return r.grade
+}
+
+func (r *AlterResult) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Statement: %s\n", r.stmt))
+	str.WriteString(fmt.Sprintf("grade: %0.2f/%0.2f\n", r.grade, grade.MaxGrade))
+	str.WriteString(fmt.Sprintf("Table: %s (about %d rows, %s data, %s indexes)\n", r.table, r.size.rows, formatBytes(r.size.dataLength), formatBytes(r.size.indexLength)))
+	str.WriteString(fmt.Sprintf("Algorithm: %s\n", r.algorithm))
+
+	str.WriteString("Operations:\n")
+	for _, o := range r.ops {
+		details := o.algorithm.String()
+		if o.rebuild {
+			details += ", rebuilds the table"
+		}
+		if o.blocksWrites {
+			details += ", blocks writes"
+		}
+		str.WriteString(fmt.Sprintf("- %s: %s", o.op.text, details))
+		if len(o.note) != 0 {
+			str.WriteString(". " + o.note)
+		}
+		str.WriteString("\n")
+	}
+
+	str.WriteString("Locking: ")
+	switch {
+	case r.blocksWrites:
+		str.WriteString("The statement holds a lock that blocks every write to the table until it finishes. Reads are allowed.\n")
+	case r.algorithm == algorithmInstant:
+		str.WriteString("The statement only needs a brief exclusive metadata lock. It still waits for open transactions on the table and every new query waits behind it.\n")
+	default:
+		str.WriteString("Reads and writes are allowed while it runs but it takes a brief exclusive metadata lock at the start and at the end. It waits for open transactions on the table and every new query waits behind it.\n")
+	}
+
+	if r.algorithm != algorithmInstant {
+		str.WriteString(fmt.Sprintf("Estimated duration: %s\n", formatDuration(r.duration)))
+		str.WriteString(fmt.Sprintf("Estimated extra disk usage: %s\n", formatBytes(r.diskUsage)))
+	}
+	for _, w := range r.warnings {
+		str.WriteString(fmt.Sprintf("Warning: %s\n", w))
+	}
+	if len(r.recommended) != 0 {
+		str.WriteString(fmt.Sprintf("Recommendation: %s\n", r.recommended))
+	}
+	return str.String()
+}
@@ -0,0 +1,102 @@
+package tableanalyzer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseVersion(t *testing.T) {
+	assert.Equal(t, mysqlVersion{8, 0, 35}, parseVersion("8.0.35"))
+	assert.Equal(t, mysqlVersion{8, 0, 35}, parseVersion("8.0.35-0ubuntu0.22.04.1"))
+	assert.Equal(t, mysqlVersion{5, 7, 44}, parseVersion("5.7.44-log"))
+
+	assert.True(t, parseVersion("8.0.29").atLeast(8, 0, 29))
+	assert.True(t, parseVersion("8.4.0").atLeast(8, 0, 29))
+	assert.False(t, parseVersion("8.0.28").atLeast(8, 0, 29))
+}
+
+func TestAssessAlter(t *testing.T) {
+	cols := []Column{
+		{name: "id", dataType: "bigint unsigned"},
+		{name: "email", dataType: "varchar(50)", charset: "utf8mb4"},
+		{name: "bio", dataType: "text", charset: "utf8mb4"},
+	}
+	small := TableSize{rows: 1000, dataLength: 1024 * 1024, indexLength: 512 * 1024}
+	large := TableSize{rows: 50_000_000, dataLength: 20 * 1024 * 1024 * 1024, indexLength: 5 * 1024 * 1024 * 1024}
+	v8 := parseVersion("8.0.35")
+
+	tests := []struct {
+		name         string
+		stmt         string
+		size         TableSize
+		version      mysqlVersion
+		algorithm    algorithm
+		rebuild      bool
+		blocksWrites bool
+		recommended  bool
+		warnings     int
+	}{
+		{"add column", "ALTER TABLE users ADD COLUMN age int", large, v8, algorithmInstant, false, false, false, 0},
+		{"add column after on old version", "ALTER TABLE users ADD COLUMN age int AFTER id", large, parseVersion("8.0.20"), algorithmInplace, true, false, true, 0},
+		{"add index", "ALTER TABLE users ADD INDEX idx_email (email)", small, v8, algorithmInplace, false, false, false, 0},
+		{"change data type", "ALTER TABLE users MODIFY bio varchar(500)", large, v8, algorithmCopy, true, true, true, 0},
+		{"extend varchar", "ALTER TABLE users MODIFY email varchar(60)", large, v8, algorithmInplace, false, false, false, 0},
+		{"add foreign key", "ALTER TABLE users ADD CONSTRAINT fk FOREIGN KEY (id) REFERENCES accounts (id)", small, v8, algorithmCopy, true, true, false, 0},
+		{"rejected algorithm", "ALTER TABLE users ADD INDEX idx_email (email), ALGORITHM=INSTANT", small, v8, algorithmInplace, false, false, false, 1},
+		{"replace primary key", "ALTER TABLE users DROP PRIMARY KEY, ADD PRIMARY KEY (id, email)", small, v8, algorithmInplace, true, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseAlterTable(tt.stmt)
+			assert.Nil(t, err)
+
+			res := assessAlter(tt.stmt, a, tt.size, cols, tt.version)
+			assert.Equal(t, tt.algorithm, res.algorithm)
+			assert.Equal(t, tt.rebuild, res.rebuild)
+			assert.Equal(t, tt.blocksWrites, res.blocksWrites)
+			assert.Equal(t, tt.recommended, len(res.recommended) != 0)
+			assert.Len(t, res.warnings, tt.warnings)
+		})
+	}
+}
+
+func TestAssessAlterEstimates(t *testing.T) {
+	cols := []Column{{name: "id", dataType: "bigint unsigned"}}
+	size := TableSize{rows: 1000, dataLength: 320 * 1024 * 1024, indexLength: 0}
+	a, err := parseAlterTable("ALTER TABLE users FORCE")
+	assert.Nil(t, err)
+
+	res := assessAlter("ALTER TABLE users FORCE", a, size, cols, parseVersion("8.0.35"))
+	assert.Equal(t, 10*time.Second, res.duration)
+	assert.Equal(t, int64(320*1024*1024), res.diskUsage)
+}
+
+func TestAssessAlter_IndexWithoutKeyParts(t *testing.T) {
+	stmt := "ALTER TABLE users ADD INDEX idx_email ((lower(email)))"
+	a := AlterTable{table: "users", ops: []AlterOp{{kind: alterAddIndex, text: "ADD INDEX idx_email ((lower(email)))", name: "idx_email"}}}
+
+	res := assessAlter(stmt, a, TableSize{rows: 1000}, nil, parseVersion("8.0.35"))
+	assert.Empty(t, res.ops)
+	assert.Equal(t, []string{"'ADD INDEX idx_email ((lower(email)))' could not be parsed so it's left out of the assessment."}, res.warnings)
+	assert.Equal(t, "The key parts of the index could not be parsed.", assessOp(a.ops[0], nil, parseVersion("8.0.35")).note)
+
+	var table Table
+	table.apply(a)
+	assert.Empty(t, table.indexes)
+}
+
+func TestIsVarcharExtension(t *testing.T) {
+	current := Column{dataType: "varchar(50)", charset: "utf8mb4"}
+	assert.True(t, isVarcharExtension(current, Column{dataType: "varchar(60)", charset: "utf8mb4"}))
+	// 200 * 4 bytes needs a 2 byte length prefix
+	assert.False(t, isVarcharExtension(current, Column{dataType: "varchar(200)", charset: "utf8mb4"}))
+	assert.False(t, isVarcharExtension(current, Column{dataType: "varchar(40)", charset: "utf8mb4"}))
+	assert.False(t, isVarcharExtension(current, Column{dataType: "text", charset: "utf8mb4"}))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "less than a second", formatDuration(200*time.Millisecond))
+	assert.Equal(t, "3m20s", formatDuration(200*time.Second+300*time.Millisecond))
+	assert.Equal(t, "2h15m0s", formatDuration(2*time.Hour+15*time.Minute+10*time.Second))
+}
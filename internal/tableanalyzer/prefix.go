@@ -0,0 +1,122 @@
+package tableanalyzer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform"
+)
+
+// prefixLengths are the candidate prefix lengths measured for a string column in increasing order
+var prefixLengths = []int64{4, 8, 12, 16, 20, 24, 32, 48, 64, 96, 128, 192, 255}
+
+// prefixSelectivityThreshold is the ratio of the full column selectivity a prefix needs to reach
+const prefixSelectivityThreshold = 0.95
+
+type (
+	PrefixSelectivity struct {
+		col      Column
+		rows     int64
+		distinct int64
+		// avgLength is the average number of characters of the values. InnoDB stores the actual length of a value in the index
+		avgLength float64
+		prefixes  []PrefixDistinct
+	}
+
+	PrefixDistinct struct {
+		length   int64
+		distinct int64
+	}
+)
+
+// candidatePrefixLengths returns the prefix lengths that are shorter than the currently indexed length
+func candidatePrefixLengths(indexedLen int64) []int64 {
+	lengths := make([]int64, 0)
+	for _, l := range prefixLengths {
+		if l < indexedLen {
+			lengths = append(lengths, l)
+		}
+	}
+	return lengths
+}
+
+// queryPrefixSelectivity counts the distinct values of a column and of LEFT(col, n) for every given length in one query
+// It also returns the average length of the values.
+func queryPrefixSelectivity(ctx context.Context, db *sql.DB, table string, col Column, lengths []int64) (PrefixSelectivity, error) {
+	var q strings.Builder
+	q.WriteString(fmt.Sprintf("select count(*), count(distinct %s), coalesce(avg(char_length(%s)), 0)", col.name, col.name))
+	for _, l := range lengths {
+		q.WriteString(fmt.Sprintf(", count(distinct left(%s, %d))", col.name, l))
+	}
+	q.WriteString(fmt.Sprintf(" from %s", table))
+
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, q.String())
+	if err != nil {
+		return PrefixSelectivity{}, fmt.Errorf("analyzer.queryPrefixSelectivity: exeuting query: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return PrefixSelectivity{}, errEmptyResults
+	}
+
+	var avgLength float64
+	counts := make([]int64, len(lengths)+2)
+	countPtrs := []interface{}{&counts[0], &counts[1], &avgLength}
+	for i := range lengths {
+		countPtrs = append(countPtrs, &counts[i+2])
+	}
+	if err := rows.Scan(countPtrs...); err != nil {
+		return PrefixSelectivity{}, fmt.Errorf("analyzer.queryPrefixSelectivity: scanning counts: %w", err)
+	}
+
+	res := PrefixSelectivity{
+		col:       col,
+		rows:      counts[0],
+		distinct:  counts[1],
+		avgLength: avgLength,
+	}
+	for i, l := range lengths {
+		res.prefixes = append(res.prefixes, PrefixDistinct{length: l, distinct: counts[i+2]})
+	}
+	return res, nil
+}
+
+// recommendPrefixLength returns the shortest prefix length that reaches [prefixSelectivityThreshold] of the full column selectivity
+func recommendPrefixLength(s PrefixSelectivity) (length int64, ratio float64, ok bool) {
+	if s.distinct == 0 {
+		return 0, 0, false
+	}
+	for _, p := range s.prefixes {
+		ratio := float64(p.distinct) / float64(s.distinct)
+		if ratio >= prefixSelectivityThreshold {
+			return p.length, ratio, true
+		}
+	}
+	return 0, 0, false
+}
+
+// indexSizeSaving estimates how many bytes the index shrinks if indexedLen characters are replaced by prefixLen characters
+// Index entries store the actual value so only the characters of an average value beyond the prefix are saved.
+func indexSizeSaving(col Column, rows int64, avgLength float64, indexedLen, prefixLen int64) int64 {
+	saved := max(min(avgLength, float64(indexedLen))-float64(prefixLen), 0)
+	return int64(saved * float64(col.bytesPerChar()*rows))
+}
+
+// formatBytes formats a size in a human-readable form such as 12.4 MB
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
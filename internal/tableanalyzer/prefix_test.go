@@ -0,0 +1,74 @@
+package tableanalyzer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRecommendPrefixLength(t *testing.T) {
+	sel := PrefixSelectivity{
+		rows:     1000,
+		distinct: 100,
+		prefixes: []PrefixDistinct{
+			{length: 4, distinct: 20},
+			{length: 8, distinct: 95},
+			{length: 12, distinct: 100},
+		},
+	}
+
+	length, ratio, ok := recommendPrefixLength(sel)
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), length)
+	assert.Equal(t, 0.95, ratio)
+}
+
+func TestRecommendPrefixLength_NotSelectiveEnough(t *testing.T) {
+	sel := PrefixSelectivity{
+		rows:     1000,
+		distinct: 100,
+		prefixes: []PrefixDistinct{
+			{length: 4, distinct: 20},
+			{length: 8, distinct: 90},
+		},
+	}
+
+	_, _, ok := recommendPrefixLength(sel)
+	assert.False(t, ok)
+}
+
+func TestRecommendPrefixLength_EmptyTable(t *testing.T) {
+	_, _, ok := recommendPrefixLength(PrefixSelectivity{})
+	assert.False(t, ok)
+}
+
+func TestCandidatePrefixLengths(t *testing.T) {
+	assert.Equal(t, []int64{4, 8, 12, 16, 20, 24}, candidatePrefixLengths(32))
+	assert.Empty(t, candidatePrefixLengths(4))
+}
+
+func TestIndexSizeSaving(t *testing.T) {
+	col := Column{name: "email", dataType: "varchar(255)", charset: "utf8mb4"}
+	// Short values save only the characters beyond the prefix
+	assert.Equal(t, int64((30-16)*4*1000), indexSizeSaving(col, 1000, 30, 255, 16))
+	// The index stores at most the indexed length
+	assert.Equal(t, int64((64-16)*4*1000), indexSizeSaving(col, 1000, 120, 64, 16))
+	// Values shorter than the prefix save nothing
+	assert.Equal(t, int64(0), indexSizeSaving(col, 1000, 10, 255, 16))
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KB", formatBytes(1536))
+	assert.Equal(t, "12.0 MB", formatBytes(12*1024*1024))
+}
+
+func TestColumnLength(t *testing.T) {
+	assert.Equal(t, int64(255), Column{dataType: "varchar(255)"}.length())
+	assert.Equal(t, int64(16777215), Column{dataType: "mediumtext"}.length())
+	assert.Equal(t, int64(0), Column{dataType: "int"}.length())
+}
+
+func TestCharsetOf(t *testing.T) {
+	assert.Equal(t, "utf8mb4", charsetOf("utf8mb4_unicode_ci"))
+	assert.Equal(t, "latin1", charsetOf("latin1_swedish_ci"))
+}
@@ -1,6 +1,7 @@
 package tableanalyzer
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -24,16 +25,40 @@ func newTooLongTextColumn(col Column, maxLen int) TooLongTextColumn {
 }
 
 // queryStringColumns returns varchar, mediumtext, text, etc columns from a table
-func queryStringColumns(db *sql.DB, table string) ([]Column, error) {
-	rows, err := db.Query(fmt.Sprintf("show columns from %s", table))
+func queryStringColumns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	all, err := queryColumns(ctx, db, table)
 	if err != nil {
-		return nil, fmt.Errorf("analyzer.queryStringColumns: exeuting query: %w", err)
+		return nil, fmt.Errorf("analyzer.queryStringColumns: %w", err)
+	}
+
+	columns := make([]Column, 0)
+	for _, column := range all {
+		if strings.Contains(column.dataType, "varchar") {
+			columns = append(columns, column)
+			continue
+		}
+
+		if slices.Contains([]string{"tinytext", "text", "mediumtext", "longtext"}, column.dataType) {
+			columns = append(columns, column)
+			continue
+		}
+	}
+	return columns, nil
+}
+
+// queryColumns returns every column of a table
+func queryColumns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("show full columns from %s", table))
+	if err != nil {
+		return nil, fmt.Errorf("analyzer.queryColumns: exeuting query: %w", err)
 	}
 	defer rows.Close()
 
 	cols, err := rows.Columns()
 	if err != nil {
-		return nil, fmt.Errorf("analyzer.queryStringColumns: reading columns: %w", err)
+		return nil, fmt.Errorf("analyzer.queryColumns: reading columns: %w", err)
 	}
 	values := make([]interface{}, len(cols))
 	valuePtrs := make([]interface{}, len(cols))
@@ -45,43 +70,46 @@ func queryStringColumns(db *sql.DB, table string) ([]Column, error) {
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("analyzer.queryStringColumns: scanning rows: %w", err)
+			return nil, fmt.Errorf("analyzer.queryColumns: scanning rows: %w", err)
 		}
 
 		var column Column
 		name, err := platform.ConvertString(values[0])
 		if err != nil {
-			return nil, fmt.Errorf("analyzer.queryStringColumns: parsing name: %w", err)
+			return nil, fmt.Errorf("analyzer.queryColumns: parsing name: %w", err)
 		}
 		column.name = name
 
 		dataType, err := platform.ConvertString(values[1])
 		if err != nil {
-			return nil, fmt.Errorf("analyzer.queryStringColumns: parsing dataType: %w", err)
+			return nil, fmt.Errorf("analyzer.queryColumns: parsing dataType: %w", err)
 		}
 		column.dataType = dataType
 
-		key, err := platform.ConvertString(values[3])
-		if err != nil {
-			return nil, fmt.Errorf("analyzer.queryStringColumns: parsing key: %w", err)
+		// Collation is NULL for non-string columns
+		if values[2] != nil {
+			collation, err := platform.ConvertString(values[2])
+			if err != nil {
+				return nil, fmt.Errorf("analyzer.queryColumns: parsing collation: %w", err)
+			}
+			column.charset = charsetOf(collation)
 		}
-		column.key = key
 
-		if strings.Contains(column.dataType, "varchar") {
-			columns = append(columns, column)
-			continue
+		key, err := platform.ConvertString(values[4])
+		if err != nil {
+			return nil, fmt.Errorf("analyzer.queryColumns: parsing key: %w", err)
 		}
+		column.key = key
 
-		if slices.Contains([]string{"tinytext", "mediumtext", "longtext"}, column.dataType) {
-			columns = append(columns, column)
-			continue
-		}
+		columns = append(columns, column)
 	}
 	return columns, nil
 }
 
-func queryIndexes(db *sql.DB, table string) ([]Index, error) {
-	rows, err := db.Query(fmt.Sprintf("show index from %s", table))
+func queryIndexes(ctx context.Context, db *sql.DB, table string) ([]Index, error) {
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("show index from %s", table))
 	if err != nil {
 		return nil, fmt.Errorf("analyzer.queryIndexes: exeuting query: %w", err)
 	}
@@ -111,11 +139,20 @@ func queryIndexes(db *sql.DB, table string) ([]Index, error) {
 		}
 		idx.keyName = key
 
-		col, err := platform.ConvertString(values[4])
-		if err != nil {
-			return nil, fmt.Errorf("analyzer.queryIndexes: parsing col: %w", err)
+		// Column_name is NULL for functional key parts, the Expression column has the expression instead
+		if values[4] != nil {
+			col, err := platform.ConvertString(values[4])
+			if err != nil {
+				return nil, fmt.Errorf("analyzer.queryIndexes: parsing col: %w", err)
+			}
+			idx.column = col
+		} else if exprIdx := slices.Index(cols, "Expression"); exprIdx != -1 && values[exprIdx] != nil {
+			expr, err := platform.ConvertString(values[exprIdx])
+			if err != nil {
+				return nil, fmt.Errorf("analyzer.queryIndexes: parsing expression: %w", err)
+			}
+			idx.expression = expr
 		}
-		idx.column = col
 
 		idxType, err := platform.ConvertString(values[10])
 		if err != nil {
@@ -129,6 +166,21 @@ func queryIndexes(db *sql.DB, table string) ([]Index, error) {
 		}
 		idx.seq = seq
 
+		nonUnique, ok := values[1].(int64)
+		if !ok {
+			return nil, fmt.Errorf("analyzer.queryIndexes: parsing non_unique: %v", values[1])
+		}
+		idx.unique = nonUnique == 0
+
+		// Sub_part is NULL if the entire column is indexed
+		if values[7] != nil {
+			subPart, ok := values[7].(int64)
+			if !ok {
+				return nil, fmt.Errorf("analyzer.queryIndexes: parsing sub_part: %v", values[7])
+			}
+			idx.subPart = subPart
+		}
+
 		card, ok := values[6].(int64)
 		if !ok {
 			return nil, fmt.Errorf("analyzer.queryIndexes: parsing cardinality: %w", err)
@@ -140,8 +192,8 @@ func queryIndexes(db *sql.DB, table string) ([]Index, error) {
 	return indexes, nil
 }
 
-func queryTooLongTextColumns(db *sql.DB, table string) ([]TooLongTextColumn, error) {
-	stringCols, err := queryStringColumns(db, table)
+func queryTooLongTextColumns(ctx context.Context, db *sql.DB, table string) ([]TooLongTextColumn, error) {
+	stringCols, err := queryStringColumns(ctx, db, table)
 	if err != nil {
 		return nil, fmt.Errorf("abalyzer.queryTooLongTextColumns: querying columns: %w", err)
 	}
@@ -155,7 +207,7 @@ func queryTooLongTextColumns(db *sql.DB, table string) ([]TooLongTextColumn, err
 
 	res := make([]TooLongTextColumn, 0)
 	for _, c := range longTextCols {
-		maxLen, err := queryMaxLen(db, table, c)
+		maxLen, err := queryMaxLen(ctx, db, table, c)
 		if err != nil {
 			if errors.Is(err, errEmptyResults) {
 				continue
@@ -170,8 +222,10 @@ func queryTooLongTextColumns(db *sql.DB, table string) ([]TooLongTextColumn, err
 	return res, nil
 }
 
-func queryMaxLen(db *sql.DB, table string, col Column) (int, error) {
-	rows, err := db.Query(fmt.Sprintf("select max(length(%s)) from %s", col.name, table))
+func queryMaxLen(ctx context.Context, db *sql.DB, table string, col Column) (int, error) {
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("select max(length(%s)) from %s", col.name, table))
 	if err != nil {
 		return -1, fmt.Errorf("analyzer.queryIndexes: exeuting query: %w", err)
 	}
@@ -187,3 +241,35 @@ func queryMaxLen(db *sql.DB, table string, col Column) (int, error) {
 	}
 	return length, nil
 }
+
+// queryTableSize returns the estimated row count and the size of a table from information_schema
+func queryTableSize(ctx context.Context, db *sql.DB, table string) (TableSize, error) {
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "select coalesce(table_rows, 0), coalesce(data_length, 0), coalesce(index_length, 0) from information_schema.tables where table_schema = database() and table_name = ?", table)
+	if err != nil {
+		return TableSize{}, fmt.Errorf("analyzer.queryTableSize: exeuting query: %w", err)
+	}
+	defer rows.Close()
+
+	var size TableSize
+	if rows.Next() {
+		if err := rows.Scan(&size.rows, &size.dataLength, &size.indexLength); err != nil {
+			return TableSize{}, fmt.Errorf("analyzer.queryTableSize: scanning size: %w", err)
+		}
+	} else {
+		return TableSize{}, errEmptyResults
+	}
+	return size, nil
+}
+
+// queryVersion returns the version of the MySQL server
+func queryVersion(ctx context.Context, db *sql.DB) (mysqlVersion, error) {
+	var version string
+	ctx, cancel := platform.QueryContext(ctx)
+	defer cancel()
+	if err := db.QueryRowContext(ctx, "select version()").Scan(&version); err != nil {
+		return mysqlVersion{}, fmt.Errorf("analyzer.queryVersion: %w", err)
+	}
+	return parseVersion(version), nil
+}
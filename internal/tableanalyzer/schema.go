@@ -0,0 +1,159 @@
+package tableanalyzer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mmartinjoo/explainer/internal/platform/grade"
+)
+
+// longStringIndexBytes is the key length above which a full-length string index is considered wasteful
+// It is the index key prefix limit of the COMPACT row format
+const longStringIndexBytes = 767
+
+// checkSchema runs the checks that only need the table definition and neither the data nor the index statistics
+// The checks see the table as it's written, without the indexes InnoDB adds for foreign keys.
+func checkSchema(t Table) Result {
+	t = t.asWritten()
+	res := newResult()
+	res.checkStringIndexLengths(t)
+	res.checkLargeTextColumns(t)
+	res.checkRedundantIndexes(t)
+	res.checkForeignKeyIndexes(t)
+	return res
+}
+
+// checkStringIndexLengths checks if string columns are indexed on their full length
+//
+// For example:
+//   - email is a varchar(255) utf8mb4 column
+//   - It is part of an index without a prefix length
+//   - The index stores up to 1020 bytes per row for this column so it will be marked as a warning
+func (r *Result) checkStringIndexLengths(t Table) {
+	for _, idx := range t.indexes {
+		// A prefix would change the meaning of a unique index
+		if idx.indexType == "FULLTEXT" || idx.indexType == "SPATIAL" || idx.unique || idx.subPart > 0 {
+			continue
+		}
+		col, ok := t.column(idx.column)
+		if !ok || !col.isString() {
+			continue
+		}
+
+		if col.isText() {
+			r.stringIndexLengthWarnings = append(r.stringIndexLengthWarnings, fmt.Sprintf("'%s' indexes the %s column %s without a prefix length. MySQL only accepts TEXT columns in an index with a prefix length such as %s(32)\n", idx.keyName, col.dataType, col.name, col.name))
+			continue
+		}
+
+		keyLen := col.length() * col.bytesPerChar()
+		if keyLen > longStringIndexBytes {
+			r.stringIndexLengthWarnings = append(r.stringIndexLengthWarnings, fmt.Sprintf("'%s' indexes all %d characters of %s which is up to %d bytes per row. A prefix index is usually just as selective and much smaller. Run 'myexplainer table %s' against real data to get a recommended prefix length\n", idx.keyName, col.length(), col.name, keyLen, t.name))
+		}
+	}
+
+	if len(r.stringIndexLengthWarnings) != 0 {
+		r.grade = grade.Dec(r.grade, 0.5)
+	}
+}
+
+// checkLargeTextColumns checks for mediumtext and longtext columns that are rarely needed
+func (r *Result) checkLargeTextColumns(t Table) {
+	for _, c := range t.columns {
+		if c.dataType != "mediumtext" && c.dataType != "longtext" {
+			continue
+		}
+		r.largeTextColumnWarnings = append(r.largeTextColumnWarnings, fmt.Sprintf("Column: %s is %s which can store up to %s per row. Make sure the data needs it. Run 'myexplainer table %s' against real data to compare it with the longest value\n", c.name, c.dataType, formatBytes(c.length()), t.name))
+	}
+
+	if len(r.largeTextColumnWarnings) != 0 {
+		r.grade = grade.Dec(r.grade, 0.25)
+	}
+}
+
+// checkRedundantIndexes checks for indexes whose columns are the leftmost columns of another index
+//
+// For example:
+//   - idx1 is (user_id)
+//   - idx2 is (user_id, created_at)
+//   - Every query that can use idx1 can use idx2 as well so idx1 only slows down writes
+func (r *Result) checkRedundantIndexes(t Table) {
+	indexes := t.groupedIndexes()
+	for i, idx := range indexes {
+		// Unique indexes enforce a constraint so they are not redundant
+		if idx[0].unique || idx[0].indexType != "BTREE" {
+			continue
+		}
+		for j, other := range indexes {
+			if i == j || other[0].indexType != "BTREE" || len(other) < len(idx) {
+				continue
+			}
+			// Report identical indexes only once
+			if len(other) == len(idx) && j > i && !other[0].unique {
+				continue
+			}
+			if !startsWith(other, idx) {
+				continue
+			}
+			r.redundantIndexWarnings = append(r.redundantIndexWarnings, fmt.Sprintf("'%s' %v is redundant because '%s' %v starts with the same columns. It slows down every write without speeding up any query\n", idx[0].keyName, indexColumns(idx), other[0].keyName, indexColumns(other)))
+			break
+		}
+	}
+
+	if len(r.redundantIndexWarnings) != 0 {
+		r.grade = grade.Dec(r.grade, 0.5)
+	}
+}
+
+// checkForeignKeyIndexes checks for foreign key columns that are not the leftmost column of any index
+//
+// A column counts as a foreign key if it has a FOREIGN KEY constraint or if it's an integer column named like user_id.
+// InnoDB adds an index for a FOREIGN KEY that no index starts with, so those are reported as missing from the definition.
+func (r *Result) checkForeignKeyIndexes(t Table) {
+	fkColumns := make([]string, 0)
+	for _, fk := range t.foreignKeys {
+		if len(fk.columns) != 0 {
+			fkColumns = append(fkColumns, fk.columns[0])
+		}
+	}
+	for _, c := range t.columns {
+		if strings.HasSuffix(strings.ToLower(c.name), "_id") && strings.Contains(c.dataType, "int") {
+			fkColumns = append(fkColumns, c.name)
+		}
+	}
+	slices.Sort(fkColumns)
+	fkColumns = slices.Compact(fkColumns)
+
+	for _, c := range fkColumns {
+		if t.hasIndexStartingWith([]string{c}) {
+			continue
+		}
+		if i := slices.IndexFunc(t.foreignKeys, func(fk ForeignKey) bool { return len(fk.columns) != 0 && fk.columns[0] == c }); i != -1 {
+			r.foreignKeyIndexWarnings = append(r.foreignKeyIndexWarnings, fmt.Sprintf("The foreign key %s on %s has no index in the table definition. InnoDB creates one implicitly, declare it so the definition shows every index and you choose its name and columns\n", t.foreignKeys[i].name, c))
+			continue
+		}
+		r.foreignKeyIndexWarnings = append(r.foreignKeyIndexWarnings, fmt.Sprintf("%s looks like a foreign key but no index starts with it. Every JOIN or lookup on it will scan the whole table\n", c))
+	}
+
+	if len(r.foreignKeyIndexWarnings) != 0 {
+		r.grade = grade.Dec(r.grade, 1)
+	}
+}
+
+// startsWith reports whether the columns of prefix are the leftmost columns of idx with the same prefix lengths
+func startsWith(idx, prefix CompositeIndex) bool {
+	for i, v := range prefix {
+		if idx[i].column != v.column || idx[i].expression != v.expression || idx[i].subPart != v.subPart {
+			return false
+		}
+	}
+	return true
+}
+
+func indexColumns(idx CompositeIndex) []string {
+	cols := make([]string, 0)
+	for _, v := range idx {
+		cols = append(cols, v.part())
+	}
+	return cols
+}
@@ -0,0 +1,108 @@
+package tableanalyzer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCheckStringIndexLengths(t *testing.T) {
+	table := Table{
+		name: "users",
+		columns: []Column{
+			{name: "email", dataType: "varchar(255)", charset: "utf8mb4"},
+			{name: "slug", dataType: "varchar(64)", charset: "utf8mb4"},
+			{name: "bio", dataType: "text", charset: "utf8mb4"},
+		},
+		indexes: []Index{
+			{keyName: "users_email_index", indexType: "BTREE", seq: 1, column: "email"},
+			{keyName: "users_slug_index", indexType: "BTREE", seq: 1, column: "slug"},
+			{keyName: "users_bio_index", indexType: "BTREE", seq: 1, column: "bio"},
+		},
+	}
+	res := newResult()
+	res.checkStringIndexLengths(table)
+
+	assert.Equal(t, float32(4.5), res.grade)
+	assert.Len(t, res.stringIndexLengthWarnings, 2)
+	assert.Contains(t, res.stringIndexLengthWarnings[0], "users_email_index")
+	assert.Contains(t, res.stringIndexLengthWarnings[1], "users_bio_index")
+}
+
+func TestCheckLargeTextColumns(t *testing.T) {
+	table := Table{
+		columns: []Column{
+			{name: "body", dataType: "longtext"},
+			{name: "summary", dataType: "text"},
+		},
+	}
+	res := newResult()
+	res.checkLargeTextColumns(table)
+
+	assert.Equal(t, float32(4.75), res.grade)
+	assert.Len(t, res.largeTextColumnWarnings, 1)
+	assert.Contains(t, res.largeTextColumnWarnings[0], "body")
+}
+
+func TestCheckRedundantIndexes(t *testing.T) {
+	table := Table{
+		indexes: []Index{
+			{keyName: "PRIMARY", indexType: "BTREE", seq: 1, column: "id", unique: true},
+			{keyName: "idx_user", indexType: "BTREE", seq: 1, column: "user_id"},
+			{keyName: "idx_user_created", indexType: "BTREE", seq: 1, column: "user_id"},
+			{keyName: "idx_user_created", indexType: "BTREE", seq: 2, column: "created_at"},
+			{keyName: "idx_status", indexType: "BTREE", seq: 1, column: "status"},
+			{keyName: "idx_status_2", indexType: "BTREE", seq: 1, column: "status"},
+		},
+	}
+	res := newResult()
+	res.checkRedundantIndexes(table)
+
+	assert.Equal(t, float32(4.5), res.grade)
+	assert.Len(t, res.redundantIndexWarnings, 2)
+	assert.Contains(t, res.redundantIndexWarnings[0], "'idx_user'")
+	assert.Contains(t, res.redundantIndexWarnings[1], "'idx_status_2'")
+}
+
+func TestCheckForeignKeyIndexes(t *testing.T) {
+	table := Table{
+		columns: []Column{
+			{name: "id", dataType: "bigint unsigned"},
+			{name: "user_id", dataType: "bigint unsigned"},
+			{name: "team_id", dataType: "bigint unsigned"},
+			{name: "external_id", dataType: "varchar(64)"},
+		},
+		indexes: []Index{
+			{keyName: "PRIMARY", indexType: "BTREE", seq: 1, column: "id", unique: true},
+			{keyName: "idx_user", indexType: "BTREE", seq: 1, column: "user_id"},
+		},
+	}
+	res := newResult()
+	res.checkForeignKeyIndexes(table)
+
+	assert.Equal(t, float32(4), res.grade)
+	assert.Len(t, res.foreignKeyIndexWarnings, 1)
+	assert.Contains(t, res.foreignKeyIndexWarnings[0], "team_id")
+}
+
+func TestCheckForeignKeyIndexes_DDL(t *testing.T) {
+	tables, err := parseDDL(strings.NewReader(`CREATE TABLE orders (
+		id bigint unsigned NOT NULL,
+		user_id bigint unsigned NOT NULL,
+		team_id bigint unsigned NOT NULL,
+		PRIMARY KEY (id),
+		KEY idx_team_created (team_id, id),
+		CONSTRAINT orders_user_id_foreign FOREIGN KEY (user_id) REFERENCES users (id),
+		CONSTRAINT orders_team_id_foreign FOREIGN KEY (team_id) REFERENCES teams (id)
+	)`))
+	assert.Nil(t, err)
+
+	// The parsed table has the index InnoDB creates for user_id but not for team_id which idx_team_created covers
+	assert.True(t, tables[0].hasIndexStartingWith([]string{"user_id"}))
+	assert.Len(t, tables[0].indexes, 4)
+
+	res := checkSchema(tables[0])
+	assert.Len(t, res.foreignKeyIndexWarnings, 1)
+	assert.Contains(t, res.foreignKeyIndexWarnings[0], "The foreign key orders_user_id_foreign on user_id has no index")
+	assert.Equal(t, float32(4), res.grade)
+}
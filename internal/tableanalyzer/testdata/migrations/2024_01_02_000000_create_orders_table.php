@@ -0,0 +1,29 @@
+<?php
+
+use App\Models\User;
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::create('orders', function (Blueprint $table) {
+            $table->id();
+            $table->foreignIdFor(User::class)->constrained()->cascadeOnDelete();
+            $table->foreignId('coupon_id');
+            $table->string('status', length: 32);
+            $table->decimal('total', 10, 2);
+            $table->timestamps();
+
+            $table->index(['status']);
+            $table->index(['status', 'created_at'], 'orders_status_created_index');
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::drop('orders');
+    }
+};
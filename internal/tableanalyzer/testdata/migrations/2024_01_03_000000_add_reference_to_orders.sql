@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN reference varchar(255) NOT NULL;
+CREATE INDEX orders_reference_index ON orders (reference);
@@ -0,0 +1,19 @@
+<?php
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('users', function (Blueprint $t) {
+            $t->dropColumn(['bio']);
+            $t->dropIndex(['country']);
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('users', function (Blueprint $t) {
+            $t->longText('bio');
+        });
+    }
+};